@@ -0,0 +1,170 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/entra-exporter/collector"
+	"github.com/your-username/entra-exporter/config"
+)
+
+// Storage stores snapshot objects
+type Storage interface {
+	// Upload stores the object under the name
+	Upload(ctx context.Context, name string, body []byte) error
+
+	// DeleteBefore deletes the objects below the prefix which were stored
+	// before the cutoff and returns their number
+	DeleteBefore(ctx context.Context, prefix string, cutoff time.Time) (int, error)
+}
+
+// NewStorage creates the storage of the configured type
+func NewStorage(ctx context.Context, cfg config.ArchiveConfig) (Storage, error) {
+	switch cfg.Type {
+	case config.ArchiveAzureBlob:
+		return newAzureBlobStorage(cfg.AzureBlob)
+	case config.ArchiveS3:
+		return newS3Storage(ctx, cfg.S3)
+	default:
+		return nil, fmt.Errorf("unknown archive storage type %q", cfg.Type)
+	}
+}
+
+// Snapshot is the document uploaded per collection cycle
+type Snapshot struct {
+	Collector string              `json:"collector"`
+	Time      time.Time           `json:"time"`
+	Objects   []map[string]string `json:"objects"`
+}
+
+// Archive uploads the inventory of every collection cycle as gzipped JSON
+type Archive struct {
+	logger    *logrus.Entry
+	config    config.ArchiveConfig
+	storage   Storage
+	exporters map[string]collector.InventoryExporter
+
+	// Names of the collectors with a completed collection cycle
+	pending chan string
+}
+
+// NewArchive creates an archive of the configured collectors
+func NewArchive(ctx context.Context, cfg config.ArchiveConfig, collectors []collector.Collector, logger *logrus.Entry) (*Archive, error) {
+	storage, err := NewStorage(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Archive{
+		logger:    logger,
+		config:    cfg,
+		storage:   storage,
+		exporters: map[string]collector.InventoryExporter{},
+		pending:   make(chan string, len(collectors)),
+	}
+	for _, c := range collectors {
+		exporter, ok := c.(collector.InventoryExporter)
+		if !ok {
+			continue
+		}
+		if len(cfg.Collectors) == 0 || slices.Contains(cfg.Collectors, exporter.Name()) {
+			a.exporters[exporter.Name()] = exporter
+		}
+	}
+
+	return a, nil
+}
+
+// CollectionCompleted queues the upload of the collector's snapshot, it is
+// registered as collection hook and doesn't block the collection
+func (a *Archive) CollectionCompleted(name string) {
+	if _, exists := a.exporters[name]; !exists {
+		return
+	}
+
+	select {
+	case a.pending <- name:
+	default:
+		a.logger.Warnf("Skipping %s snapshot upload, previous snapshots are still being uploaded", name)
+	}
+}
+
+// Run uploads the queued snapshots until the context is cancelled
+func (a *Archive) Run(ctx context.Context) {
+	a.logger.Infof("Uploading inventory snapshots to %s storage", a.config.Type)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case name := <-a.pending:
+			start := time.Now()
+			objectName, objects, err := a.upload(ctx, a.exporters[name], start.UTC())
+			if err != nil {
+				a.logger.Errorf("Failed to upload %s snapshot: %v", name, err)
+				continue
+			}
+			a.logger.Debugf("Uploaded %s snapshot with %d objects to %s in %s", name, objects, objectName, time.Since(start))
+
+			if a.config.Retention > 0 {
+				deleted, err := a.storage.DeleteBefore(ctx, a.collectorPrefix(name), start.Add(-a.config.Retention))
+				if err != nil {
+					a.logger.Errorf("Failed to delete expired %s snapshots: %v", name, err)
+				} else if deleted > 0 {
+					a.logger.Debugf("Deleted %d expired %s snapshots", deleted, name)
+				}
+			}
+		}
+	}
+}
+
+// collectorPrefix returns the prefix of all snapshots of the collector
+func (a *Archive) collectorPrefix(name string) string {
+	return a.config.Prefix + name + "/"
+}
+
+// upload stores the current inventory of the collector as
+// <prefix><collector>/<yyyy>/<mm>/<dd>/<collector>-<time>.json.gz and
+// returns the object name and number of objects
+func (a *Archive) upload(ctx context.Context, exporter collector.InventoryExporter, snapshotTime time.Time) (string, int, error) {
+	header, rows := exporter.Inventory()
+
+	snapshot := Snapshot{
+		Collector: exporter.Name(),
+		Time:      snapshotTime,
+		Objects:   []map[string]string{},
+	}
+	for row := range rows {
+		object := make(map[string]string, len(header))
+		for i, column := range header {
+			object[column] = row[i]
+		}
+		snapshot.Objects = append(snapshot.Objects, object)
+	}
+
+	var body bytes.Buffer
+	gz := gzip.NewWriter(&body)
+	if err := json.NewEncoder(gz).Encode(snapshot); err != nil {
+		return "", 0, err
+	}
+	if err := gz.Close(); err != nil {
+		return "", 0, err
+	}
+
+	name := path.Join(
+		a.collectorPrefix(exporter.Name()),
+		snapshotTime.Format("2006/01/02"),
+		fmt.Sprintf("%s-%s.json.gz", exporter.Name(), snapshotTime.Format("20060102T150405Z")),
+	)
+	if err := a.storage.Upload(ctx, name, body.Bytes()); err != nil {
+		return "", 0, err
+	}
+	return name, len(snapshot.Objects), nil
+}
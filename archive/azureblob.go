@@ -0,0 +1,70 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/your-username/entra-exporter/config"
+)
+
+// snapshotContentType is the content type of the uploaded snapshots, they are
+// stored compressed and not decoded by clients on download
+const snapshotContentType = "application/gzip"
+
+// azureBlobStorage stores the snapshots as blobs of a container
+type azureBlobStorage struct {
+	client    *azblob.Client
+	container string
+}
+
+func newAzureBlobStorage(cfg config.ArchiveAzureBlobConfig) (*azureBlobStorage, error) {
+	credential, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure credential: %w", err)
+	}
+
+	client, err := azblob.NewClient(cfg.URL, credential, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Blob Storage client: %w", err)
+	}
+	return &azureBlobStorage{client: client, container: cfg.Container}, nil
+}
+
+// Upload implements Storage
+func (s *azureBlobStorage) Upload(ctx context.Context, name string, body []byte) error {
+	contentType := snapshotContentType
+	_, err := s.client.UploadBuffer(ctx, s.container, name, body, &azblob.UploadBufferOptions{
+		HTTPHeaders: &blob.HTTPHeaders{BlobContentType: &contentType},
+	})
+	return err
+}
+
+// DeleteBefore implements Storage
+func (s *azureBlobStorage) DeleteBefore(ctx context.Context, prefix string, cutoff time.Time) (int, error) {
+	deleted := 0
+	pager := s.client.NewListBlobsFlatPager(s.container, &azblob.ListBlobsFlatOptions{Prefix: &prefix})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return deleted, err
+		}
+
+		for _, item := range page.Segment.BlobItems {
+			if item.Name == nil || item.Properties == nil || item.Properties.LastModified == nil {
+				continue
+			}
+			if !item.Properties.LastModified.Before(cutoff) {
+				continue
+			}
+			if _, err := s.client.DeleteBlob(ctx, s.container, *item.Name, nil); err != nil {
+				return deleted, fmt.Errorf("failed to delete %s: %w", *item.Name, err)
+			}
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,92 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/your-username/entra-exporter/config"
+)
+
+// s3DeleteBatchSize is the maximum number of keys of a DeleteObjects request
+const s3DeleteBatchSize = 1000
+
+// s3Storage stores the snapshots as objects of a bucket
+type s3Storage struct {
+	client *s3.Client
+	bucket string
+}
+
+func newS3Storage(ctx context.Context, cfg config.ArchiveS3Config) (*s3Storage, error) {
+	var options []func(*awsconfig.LoadOptions) error
+	if cfg.Region != "" {
+		options = append(options, awsconfig.WithRegion(cfg.Region))
+	}
+
+	awsConfig, err := awsconfig.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+
+	client := s3.NewFromConfig(awsConfig, func(o *s3.Options) {
+		if cfg.Endpoint != "" {
+			// S3 compatible storages usually don't support virtual hosted buckets
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+			o.UsePathStyle = true
+		}
+	})
+	return &s3Storage{client: client, bucket: cfg.Bucket}, nil
+}
+
+// Upload implements Storage
+func (s *s3Storage) Upload(ctx context.Context, name string, body []byte) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(name),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String(snapshotContentType),
+	})
+	return err
+}
+
+// DeleteBefore implements Storage
+func (s *s3Storage) DeleteBefore(ctx context.Context, prefix string, cutoff time.Time) (int, error) {
+	var expired []types.ObjectIdentifier
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return 0, err
+		}
+		for _, object := range page.Contents {
+			if object.LastModified != nil && object.LastModified.Before(cutoff) {
+				expired = append(expired, types.ObjectIdentifier{Key: object.Key})
+			}
+		}
+	}
+
+	deleted := 0
+	for batch := range slices.Chunk(expired, s3DeleteBatchSize) {
+		result, err := s.client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(s.bucket),
+			Delete: &types.Delete{Objects: batch, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return deleted, err
+		}
+		deleted += len(batch) - len(result.Errors)
+		if len(result.Errors) > 0 {
+			return deleted, fmt.Errorf("failed to delete %s: %s", aws.ToString(result.Errors[0].Key), aws.ToString(result.Errors[0].Message))
+		}
+	}
+	return deleted, nil
+}
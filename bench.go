@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/your-username/entra-exporter/config"
+)
+
+// benchCommand runs every enabled collector once and reports how expensive it was
+type benchCommand struct{}
+
+// runBench runs each enabled collector once against the configured tenants
+// and prints duration, pages, objects and peak heap usage
+func runBench(cfg *config.Config) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg.Oneshot = true
+	collectors := setupCollectors(ctx, cfg)
+	if len(collectors) == 0 {
+		logger.Fatal("No collectors enabled in config")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "COLLECTOR\tTENANT\tDURATION\tPAGES\tOBJECTS\tPEAK HEAP")
+
+	for _, c := range collectors {
+		logger.Infof("Benchmarking collector %s", c.Name())
+
+		runtime.GC()
+		peakHeap := trackPeakHeap(func() {
+			c.RunOnce()
+		})
+
+		for _, stats := range c.LastStats() {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%.1f MiB\n",
+				c.Name(),
+				stats.TenantID,
+				stats.Duration.Round(time.Millisecond),
+				stats.Pages,
+				stats.Objects,
+				float64(peakHeap)/1024/1024,
+			)
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	w.Flush()
+}
+
+// trackPeakHeap runs fn and returns the highest heap usage sampled meanwhile
+func trackPeakHeap(fn func()) uint64 {
+	var memStats runtime.MemStats
+	var peak uint64
+
+	sample := func() {
+		runtime.ReadMemStats(&memStats)
+		if memStats.HeapAlloc > peak {
+			peak = memStats.HeapAlloc
+		}
+	}
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			sample()
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	fn()
+	close(done)
+	<-finished
+	sample()
+
+	return peak
+}
@@ -0,0 +1,342 @@
+package changes
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	mgraph "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/entra-exporter/collector"
+	"github.com/your-username/entra-exporter/config"
+)
+
+const (
+	// subscriptionLifetime stays far below the maximum lifetime of user
+	// subscriptions (29 days), so subscriptions left behind by a crash
+	// expire soon
+	subscriptionLifetime = 48 * time.Hour
+
+	// renewBefore is the remaining lifetime below which subscriptions are renewed
+	renewBefore = 24 * time.Hour
+
+	// startDelay gives the HTTP server time to start, Graph validates the
+	// notification URL while the subscription is created
+	startDelay = 10 * time.Second
+)
+
+// resources maps the collectors to the Graph resources they collect, only
+// those Graph supports change notifications for
+var resources = map[string]string{
+	"users": "users",
+}
+
+// graphCollector is implemented by the collectors through their BaseCollector
+type graphCollector interface {
+	collector.Collector
+	GetGraphClient(ctx context.Context, tenantID string) (*mgraph.GraphServiceClient, error)
+	GetTenants() []string
+}
+
+// subscription is a change notification subscription of a tenant
+type subscription struct {
+	id         string
+	tenantID   string
+	collector  graphCollector
+	expiration time.Time
+}
+
+// notification is a Graph change notification
+type notification struct {
+	SubscriptionID string `json:"subscriptionId"`
+	ClientState    string `json:"clientState"`
+	ChangeType     string `json:"changeType"`
+	Resource       string `json:"resource"`
+	TenantID       string `json:"tenantId"`
+}
+
+// Manager subscribes to Graph change notifications for the collected
+// resources and refreshes the collectors when they change
+type Manager struct {
+	logger     *logrus.Entry
+	config     config.ChangeNotificationsConfig
+	collectors []graphCollector
+
+	subscriptions     map[string]*subscription
+	subscriptionsLock sync.Mutex
+
+	// Number of subscriptions being created, Graph validates the
+	// notification URL meanwhile
+	creating atomic.Int32
+
+	// Pending refreshes per collector, to batch bursts of notifications
+	refreshes     map[string]*time.Timer
+	refreshesLock sync.Mutex
+}
+
+// NewManager creates a manager for the configured collectors which support
+// change notifications
+func NewManager(cfg config.ChangeNotificationsConfig, collectors []collector.Collector, logger *logrus.Entry) (*Manager, error) {
+	m := &Manager{
+		logger:        logger,
+		config:        cfg,
+		subscriptions: map[string]*subscription{},
+		refreshes:     map[string]*time.Timer{},
+	}
+
+	for _, c := range collectors {
+		if _, supported := resources[c.Name()]; !supported {
+			continue
+		}
+		if len(cfg.Collectors) > 0 && !slices.Contains(cfg.Collectors, c.Name()) {
+			continue
+		}
+		if gc, ok := c.(graphCollector); ok {
+			m.collectors = append(m.collectors, gc)
+		}
+	}
+	if len(m.collectors) == 0 {
+		return nil, fmt.Errorf("no enabled collector supports change notifications")
+	}
+
+	return m, nil
+}
+
+// Path returns the path of the notification endpoint
+func (m *Manager) Path() string {
+	notificationURL, err := url.Parse(m.config.NotificationURL)
+	if err != nil || notificationURL.Path == "" {
+		return "/"
+	}
+	return notificationURL.Path
+}
+
+// Run creates and renews the subscriptions until the context is cancelled,
+// then deletes them. Subscriptions which couldn't be created, e.g. because
+// the notification URL wasn't reachable yet, are retried on every tick.
+func (m *Manager) Run(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(startDelay):
+	}
+
+	m.subscribeMissing(ctx)
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			m.unsubscribeAll()
+			return
+		case <-ticker.C:
+			m.renew(ctx)
+			m.subscribeMissing(ctx)
+		}
+	}
+}
+
+// subscribeMissing subscribes every collector and tenant without a subscription
+func (m *Manager) subscribeMissing(ctx context.Context) {
+	m.subscriptionsLock.Lock()
+	subscribed := map[string]map[string]bool{}
+	for _, sub := range m.subscriptions {
+		if subscribed[sub.collector.Name()] == nil {
+			subscribed[sub.collector.Name()] = map[string]bool{}
+		}
+		subscribed[sub.collector.Name()][sub.tenantID] = true
+	}
+	m.subscriptionsLock.Unlock()
+
+	for _, c := range m.collectors {
+		for _, tenantID := range c.GetTenants() {
+			if ctx.Err() != nil {
+				return
+			}
+			if subscribed[c.Name()][tenantID] {
+				continue
+			}
+			if err := m.subscribe(ctx, c, tenantID); err != nil {
+				m.logger.Errorf("Failed to subscribe to %s changes of tenant %s, retrying in an hour: %v", c.Name(), tenantID, err)
+			}
+		}
+	}
+}
+
+// subscribe creates a subscription for the resource of the collector
+func (m *Manager) subscribe(ctx context.Context, c graphCollector, tenantID string) error {
+	client, err := c.GetGraphClient(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+
+	expiration := time.Now().Add(subscriptionLifetime)
+	body := models.NewSubscription()
+	body.SetChangeType(stringPtr("created,updated,deleted"))
+	body.SetNotificationUrl(stringPtr(m.config.NotificationURL))
+	body.SetResource(stringPtr(resources[c.Name()]))
+	body.SetClientState(stringPtr(m.config.ClientState))
+	body.SetExpirationDateTime(&expiration)
+
+	m.creating.Add(1)
+	created, err := client.Subscriptions().Post(ctx, body, nil)
+	m.creating.Add(-1)
+	if err != nil {
+		return err
+	}
+
+	m.subscriptionsLock.Lock()
+	m.subscriptions[*created.GetId()] = &subscription{
+		id:         *created.GetId(),
+		tenantID:   tenantID,
+		collector:  c,
+		expiration: expiration,
+	}
+	m.subscriptionsLock.Unlock()
+
+	m.logger.Infof("Subscribed to %s changes of tenant %s until %s", c.Name(), tenantID, expiration.Format(time.RFC3339))
+	return nil
+}
+
+// renew extends the subscriptions expiring soon, subscriptions which can't
+// be renewed are created again
+func (m *Manager) renew(ctx context.Context) {
+	m.subscriptionsLock.Lock()
+	var expiring []*subscription
+	for _, sub := range m.subscriptions {
+		if time.Until(sub.expiration) < renewBefore {
+			expiring = append(expiring, sub)
+		}
+	}
+	m.subscriptionsLock.Unlock()
+
+	for _, sub := range expiring {
+		client, err := sub.collector.GetGraphClient(ctx, sub.tenantID)
+		if err != nil {
+			m.logger.Errorf("Failed to renew subscription %s: %v", sub.id, err)
+			continue
+		}
+
+		expiration := time.Now().Add(subscriptionLifetime)
+		body := models.NewSubscription()
+		body.SetExpirationDateTime(&expiration)
+
+		if _, err := client.Subscriptions().BySubscriptionId(sub.id).Patch(ctx, body, nil); err != nil {
+			m.logger.Warnf("Failed to renew subscription %s, creating a new one: %v", sub.id, err)
+
+			// Created again by subscribeMissing
+			m.subscriptionsLock.Lock()
+			delete(m.subscriptions, sub.id)
+			m.subscriptionsLock.Unlock()
+			continue
+		}
+
+		m.subscriptionsLock.Lock()
+		sub.expiration = expiration
+		m.subscriptionsLock.Unlock()
+	}
+}
+
+// unsubscribeAll deletes all subscriptions on shutdown
+func (m *Manager) unsubscribeAll() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	m.subscriptionsLock.Lock()
+	defer m.subscriptionsLock.Unlock()
+
+	for id, sub := range m.subscriptions {
+		client, err := sub.collector.GetGraphClient(ctx, sub.tenantID)
+		if err == nil {
+			err = client.Subscriptions().BySubscriptionId(id).Delete(ctx, nil)
+		}
+		if err != nil {
+			m.logger.Warnf("Failed to delete subscription %s: %v", id, err)
+		}
+		delete(m.subscriptions, id)
+	}
+}
+
+// ServeHTTP handles the validation requests and notifications of Graph
+func (m *Manager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Graph validates the endpoint by expecting the token echoed back, which
+	// is only answered while a subscription is being created
+	if token := r.URL.Query().Get("validationToken"); token != "" {
+		if m.creating.Load() == 0 {
+			http.Error(w, "No subscription is being created", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Write([]byte(token))
+		return
+	}
+
+	var payload struct {
+		Value []notification `json:"value"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&payload); err != nil {
+		http.Error(w, "Invalid notification", http.StatusBadRequest)
+		return
+	}
+
+	// Acknowledge quickly, Graph retries and eventually drops slow endpoints
+	w.WriteHeader(http.StatusAccepted)
+
+	for _, n := range payload.Value {
+		if subtle.ConstantTimeCompare([]byte(n.ClientState), []byte(m.config.ClientState)) != 1 {
+			m.logger.Warnf("Ignoring notification for subscription %s with invalid client state", n.SubscriptionID)
+			continue
+		}
+
+		m.subscriptionsLock.Lock()
+		sub, exists := m.subscriptions[n.SubscriptionID]
+		m.subscriptionsLock.Unlock()
+		if !exists {
+			m.logger.Debugf("Ignoring notification for unknown subscription %s", n.SubscriptionID)
+			continue
+		}
+
+		m.logger.Debugf("Change notification for tenant %s: %s %s", n.TenantID, n.ChangeType, n.Resource)
+		m.scheduleRefresh(sub.collector)
+	}
+}
+
+// scheduleRefresh refreshes the collector after the debounce time, further
+// notifications meanwhile are covered by the same refresh
+func (m *Manager) scheduleRefresh(c graphCollector) {
+	m.refreshesLock.Lock()
+	defer m.refreshesLock.Unlock()
+
+	if _, pending := m.refreshes[c.Name()]; pending {
+		return
+	}
+
+	m.refreshes[c.Name()] = time.AfterFunc(m.config.GetDebounce(), func() {
+		m.refreshesLock.Lock()
+		delete(m.refreshes, c.Name())
+		m.refreshesLock.Unlock()
+
+		m.logger.Infof("Refreshing %s after change notifications", c.Name())
+		c.Refresh()
+	})
+}
+
+func stringPtr(value string) *string {
+	return &value
+}
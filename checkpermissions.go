@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/your-username/entra-exporter/collector"
+	"github.com/your-username/entra-exporter/config"
+)
+
+// checkPermissionsCommand checks the Graph permissions of the enabled collectors
+type checkPermissionsCommand struct{}
+
+// graphEndpoint holds the permissions the collectors need on a Graph endpoint
+type graphEndpoint struct {
+	// Configuration of one of the collectors, for the token scope and the
+	// probe requests
+	collectorConfig *config.CollectorConfig
+
+	// The collectors needing each permission
+	needed map[string][]string
+}
+
+// permissions returns the needed permissions, sorted
+func (e *graphEndpoint) permissions() []string {
+	permissions := make([]string, 0, len(e.needed))
+	for permission := range e.needed {
+		permissions = append(permissions, permission)
+	}
+	slices.Sort(permissions)
+	return permissions
+}
+
+// runCheckPermissions checks the permissions the enabled collectors need for
+// every configured tenant and prints a pass/fail table, it exits with status
+// 1 when a permission is missing
+func runCheckPermissions(cfg *config.Config) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Collectors may request different Graph endpoints, e.g. of national
+	// clouds, whose tokens and probes are checked separately
+	endpoints := map[string]*graphEndpoint{}
+	for _, name := range collector.KnownCollectors() {
+		collectorConfig := cfg.GetCollector(name)
+		if !collectorConfig.IsEnabled() {
+			continue
+		}
+		baseURL := collectorConfig.GetGraphBaseURL()
+		endpoint, exists := endpoints[baseURL]
+		if !exists {
+			endpoint = &graphEndpoint{collectorConfig: collectorConfig, needed: map[string][]string{}}
+			endpoints[baseURL] = endpoint
+		}
+		for _, permission := range collector.RequiredPermissions(name, cfg) {
+			if !slices.Contains(endpoint.needed[permission], name) {
+				endpoint.needed[permission] = append(endpoint.needed[permission], name)
+			}
+		}
+	}
+	if len(endpoints) == 0 {
+		logger.Fatal("No collectors enabled in config")
+	}
+
+	baseURLs := make([]string, 0, len(endpoints))
+	for baseURL := range endpoints {
+		baseURLs = append(baseURLs, baseURL)
+	}
+	slices.Sort(baseURLs)
+
+	// Same fallback to the environment as the collectors
+	tenants := cfg.Azure.Tenants
+	if len(tenants) == 0 {
+		tenants = []string{os.Getenv("AZURE_TENANT_ID")}
+	}
+	tenants = cfg.FilterTenants(tenants)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TENANT\tGRAPH\tPERMISSION\tIN TOKEN\tPROBE\tRESULT\tCOLLECTORS")
+
+	failed := false
+	for _, tenantID := range tenants {
+		logger.Infof("Checking permissions for tenant %s", tenantID)
+
+		for _, baseURL := range baseURLs {
+			endpoint := endpoints[baseURL]
+			checks, err := collector.CheckPermissions(ctx, tenantID, endpoint.collectorConfig, endpoint.permissions())
+			if err != nil {
+				logger.Errorf("Failed to authenticate for tenant %s at %s: %v", tenantID, baseURL, err)
+				failed = true
+				continue
+			}
+
+			for _, check := range checks {
+				result := "pass"
+				if !check.Passed {
+					result = "fail"
+					failed = true
+				}
+				probe := check.Probe
+				if probe == "" {
+					probe = "-"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%s\t%s\t%s\n",
+					tenantID,
+					baseURL,
+					check.Permission,
+					check.InToken,
+					probe,
+					result,
+					strings.Join(endpoint.needed[check.Permission], ", "),
+				)
+			}
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	w.Flush()
+
+	if failed {
+		os.Exit(1)
+	}
+}
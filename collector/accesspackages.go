@@ -0,0 +1,301 @@
+package collector
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/identitygovernance"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/entra-exporter/config"
+)
+
+// accessPackageStats holds the assignments and pending approvals of an access package
+type accessPackageStats struct {
+	name        string
+	assignments int
+
+	// Expiry of the assignments with an end date
+	expirations []time.Time
+
+	pendingApprovals int
+	oldestPending    time.Time
+}
+
+// AccessPackagesCollector collects entitlement management access package
+// assignment and approval backlog metrics
+type AccessPackagesCollector struct {
+	*BaseCollector
+
+	// Access packages by ID per tenant
+	packagesLock sync.RWMutex
+	packages     map[string]map[string]*accessPackageStats
+
+	// Metrics
+	assignmentsTotal      *gaugeVec
+	expiringTotal         *gaugeVec
+	pendingApprovalsTotal *gaugeVec
+	oldestPendingAge      *gaugeVec
+}
+
+// NewAccessPackagesCollector creates a new AccessPackagesCollector
+func NewAccessPackagesCollector(ctx context.Context, config *config.Config, logger *logrus.Entry) *AccessPackagesCollector {
+	labels := []string{"tenant_id", "access_package_id", "access_package"}
+
+	c := &AccessPackagesCollector{
+		BaseCollector: NewBaseCollector("accessPackages", config.Collector.AccessPackages.CollectorConfig, config, logger),
+		packages:      map[string]map[string]*accessPackageStats{},
+		assignmentsTotal: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_access_package_assignments_total",
+				Help: "Number of delivered assignments of the access package",
+			},
+			labels,
+		),
+		expiringTotal: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_access_package_assignments_expiring_total",
+				Help: "Number of assignments of the access package expiring within the collector's expiringWithin duration",
+			},
+			labels,
+		),
+		pendingApprovalsTotal: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_access_package_pending_approvals_total",
+				Help: "Number of assignment requests of the access package pending approval",
+			},
+			labels,
+		),
+		oldestPendingAge: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_access_package_oldest_pending_approval_age_seconds",
+				Help: "Age of the oldest assignment request of the access package pending approval",
+			},
+			labels,
+		),
+	}
+
+	// Start background collection
+	c.StartCacheInvalidator(ctx, c.collect)
+
+	return c
+}
+
+// Describe implements prometheus.Collector
+func (c *AccessPackagesCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.BaseCollector.Describe(ch)
+	c.assignmentsTotal.Describe(ch)
+	c.expiringTotal.Describe(ch)
+	c.pendingApprovalsTotal.Describe(ch)
+	c.oldestPendingAge.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *AccessPackagesCollector) Collect(ch chan<- prometheus.Metric) {
+	c.BaseCollector.Collect(ch)
+
+	c.packagesLock.RLock()
+	defer c.packagesLock.RUnlock()
+
+	// Rebuild the metrics from the cache so removed access packages disappear
+	assignmentsTotal := c.assignmentsTotal.vec()
+	expiringTotal := c.expiringTotal.vec()
+	pendingApprovalsTotal := c.pendingApprovalsTotal.vec()
+	oldestPendingAge := c.oldestPendingAge.vec()
+
+	// Expiry and age are relative to the scrape so they move between collections
+	now := time.Now()
+	expiringBefore := now.Add(c.config.Collector.AccessPackages.GetExpiringWithin())
+
+	for tenantID, packages := range c.packages {
+		if c.isCacheExpired(tenantID) {
+			continue
+		}
+
+		for packageID, stats := range packages {
+			expiring := 0
+			for _, expiration := range stats.expirations {
+				if expiration.After(now) && expiration.Before(expiringBefore) {
+					expiring++
+				}
+			}
+
+			assignmentsTotal.WithLabelValues(tenantID, packageID, stats.name).Set(float64(stats.assignments))
+			expiringTotal.WithLabelValues(tenantID, packageID, stats.name).Set(float64(expiring))
+			pendingApprovalsTotal.WithLabelValues(tenantID, packageID, stats.name).Set(float64(stats.pendingApprovals))
+			if !stats.oldestPending.IsZero() {
+				oldestPendingAge.WithLabelValues(tenantID, packageID, stats.name).Set(now.Sub(stats.oldestPending).Seconds())
+			}
+		}
+	}
+
+	c.collectCached(ch, assignmentsTotal, expiringTotal, pendingApprovalsTotal, oldestPendingAge)
+}
+
+// collect gets the access packages with their assignments and pending requests
+func (c *AccessPackagesCollector) collect(ctx context.Context) {
+	for _, tenantID := range c.GetTenants() {
+		if ctx.Err() != nil {
+			c.logger.Debugf("Stopping access packages collection: %v", ctx.Err())
+			return
+		}
+
+		if !c.tenantAllowed(tenantID) {
+			continue
+		}
+
+		start := time.Now()
+		c.sampledDebugf("Collecting access packages for tenant %s", tenantID)
+
+		client, err := c.GetGraphClient(ctx, tenantID)
+		if err != nil {
+			c.logger.Errorf("Failed to get Graph client for tenant %s: %v", tenantID, err)
+			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			c.tenantFailed(tenantID, err)
+			continue
+		}
+
+		packages := map[string]*accessPackageStats{}
+		objects := 0
+
+		packageQuery := identitygovernance.EntitlementManagementAccessPackagesRequestBuilderGetQueryParameters{
+			Select: []string{"id", "displayName"},
+		}
+		if filter := c.collectorConfig.Filter; filter != "" {
+			c.logger.Debugf("Using filter %q for access packages collection", filter)
+			packageQuery.Filter = &filter
+		}
+		packageConfig := identitygovernance.EntitlementManagementAccessPackagesRequestBuilderGetRequestConfiguration{
+			QueryParameters: &packageQuery,
+		}
+		pages, err := readPages(ctx, c.BaseCollector, tenantID, "accessPackages", func(ctx context.Context, nextLink string) ([]models.AccessPackageable, *string, error) {
+			builder := client.IdentityGovernance().EntitlementManagement().AccessPackages()
+			requestConfig := &packageConfig
+			if nextLink != "" {
+				builder = builder.WithUrl(nextLink)
+				requestConfig = nil
+			}
+
+			result, err := builder.Get(ctx, requestConfig)
+			if err != nil {
+				return nil, nil, err
+			}
+			return result.GetValue(), result.GetOdataNextLink(), nil
+		}, func(accessPackage models.AccessPackageable) {
+			objects++
+			if accessPackage.GetId() != nil {
+				packages[*accessPackage.GetId()] = &accessPackageStats{name: stringValue(accessPackage.GetDisplayName(), "")}
+			}
+		})
+
+		// Assignments and requests of packages outside the filter are left out
+		packageOf := func(accessPackage models.AccessPackageable) *accessPackageStats {
+			if accessPackage == nil || accessPackage.GetId() == nil {
+				return nil
+			}
+			return packages[*accessPackage.GetId()]
+		}
+
+		if err == nil {
+			assignmentFilter := "state eq 'delivered'"
+			assignmentConfig := identitygovernance.EntitlementManagementAssignmentsRequestBuilderGetRequestConfiguration{
+				QueryParameters: &identitygovernance.EntitlementManagementAssignmentsRequestBuilderGetQueryParameters{
+					Filter: &assignmentFilter,
+					Select: []string{"id", "state", "schedule"},
+					Expand: []string{"accessPackage($select=id)"},
+				},
+			}
+			var assignmentPages int
+			assignmentPages, err = readPages(ctx, c.BaseCollector, tenantID, "assignments", func(ctx context.Context, nextLink string) ([]models.AccessPackageAssignmentable, *string, error) {
+				builder := client.IdentityGovernance().EntitlementManagement().Assignments()
+				requestConfig := &assignmentConfig
+				if nextLink != "" {
+					builder = builder.WithUrl(nextLink)
+					requestConfig = nil
+				}
+
+				result, err := builder.Get(ctx, requestConfig)
+				if err != nil {
+					return nil, nil, err
+				}
+				return result.GetValue(), result.GetOdataNextLink(), nil
+			}, func(assignment models.AccessPackageAssignmentable) {
+				objects++
+				stats := packageOf(assignment.GetAccessPackage())
+				if stats == nil {
+					return
+				}
+				stats.assignments++
+				if schedule := assignment.GetSchedule(); schedule != nil && schedule.GetExpiration() != nil && schedule.GetExpiration().GetEndDateTime() != nil {
+					stats.expirations = append(stats.expirations, *schedule.GetExpiration().GetEndDateTime())
+				}
+			})
+			pages += assignmentPages
+		}
+
+		if err == nil {
+			requestFilter := "state eq 'pendingApproval'"
+			pendingConfig := identitygovernance.EntitlementManagementAssignmentRequestsRequestBuilderGetRequestConfiguration{
+				QueryParameters: &identitygovernance.EntitlementManagementAssignmentRequestsRequestBuilderGetQueryParameters{
+					Filter: &requestFilter,
+					Select: []string{"id", "state", "createdDateTime"},
+					Expand: []string{"accessPackage($select=id)"},
+				},
+			}
+			var requestPages int
+			requestPages, err = readPages(ctx, c.BaseCollector, tenantID, "assignmentRequests", func(ctx context.Context, nextLink string) ([]models.AccessPackageAssignmentRequestable, *string, error) {
+				builder := client.IdentityGovernance().EntitlementManagement().AssignmentRequests()
+				requestConfig := &pendingConfig
+				if nextLink != "" {
+					builder = builder.WithUrl(nextLink)
+					requestConfig = nil
+				}
+
+				result, err := builder.Get(ctx, requestConfig)
+				if err != nil {
+					return nil, nil, err
+				}
+				return result.GetValue(), result.GetOdataNextLink(), nil
+			}, func(request models.AccessPackageAssignmentRequestable) {
+				objects++
+				stats := packageOf(request.GetAccessPackage())
+				if stats == nil {
+					return
+				}
+				stats.pendingApprovals++
+				if created := request.GetCreatedDateTime(); created != nil && (stats.oldestPending.IsZero() || created.Before(stats.oldestPending)) {
+					stats.oldestPending = *created
+				}
+			})
+			pages += requestPages
+		}
+
+		if ctx.Err() != nil {
+			c.logger.Debugf("Access packages collection for tenant %s cancelled", tenantID)
+			return
+		}
+
+		// Partial counts would look like removed assignments, keep the previous ones
+		if err != nil {
+			c.logger.WithFields(graphErrorFields(err)).Errorf("Failed to get access packages for tenant %s: %v", tenantID, graphErrorMessage(err))
+			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			c.tenantFailed(tenantID, err)
+			continue
+		}
+
+		c.packagesLock.Lock()
+		c.packages[tenantID] = packages
+		c.packagesLock.Unlock()
+		c.cacheUpdated(tenantID, objects)
+		c.recordStats(tenantID, start, pages, objects)
+		c.tenantSucceeded(tenantID)
+
+		// Update scrape metrics
+		duration := time.Since(start).Seconds()
+		c.observeDuration(tenantID, duration)
+		c.lastScrapeTime.WithLabelValues(tenantID).Set(float64(time.Now().Unix()))
+		c.sampledDebugf("Completed access packages collection for tenant %s in %.2f seconds: %d access packages", tenantID, duration, len(packages))
+	}
+}
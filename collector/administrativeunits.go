@@ -0,0 +1,260 @@
+package collector
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/directory"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/microsoftgraph/msgraph-sdk-go/rolemanagement"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/entra-exporter/config"
+)
+
+// administrativeUnitScopePrefix prefixes the directory scope of role
+// assignments scoped to an administrative unit
+const administrativeUnitScopePrefix = "/administrativeUnits/"
+
+// scopedRoleKey groups the role assignments of an administrative unit by role
+type scopedRoleKey struct {
+	unitID           string
+	roleDefinitionID string
+}
+
+// administrativeUnitStats holds the administrative units of a tenant and
+// their scoped role assignments
+type administrativeUnitStats struct {
+	// Display names by administrative unit ID
+	units       map[string]string
+	assignments map[scopedRoleKey]int
+}
+
+// AdministrativeUnitsCollector collects the role assignments scoped to
+// administrative units
+type AdministrativeUnitsCollector struct {
+	*BaseCollector
+
+	// Administrative units cache, role names by role definition ID per tenant
+	statsLock sync.RWMutex
+	stats     map[string]administrativeUnitStats
+	roleNames map[string]map[string]string
+
+	// Metrics
+	unitsTotal        *gaugeVec
+	assignmentsTotal  *countVec
+	assignmentsByRole *gaugeVec
+}
+
+// NewAdministrativeUnitsCollector creates a new AdministrativeUnitsCollector
+func NewAdministrativeUnitsCollector(ctx context.Context, config *config.Config, logger *logrus.Entry) *AdministrativeUnitsCollector {
+	c := &AdministrativeUnitsCollector{
+		BaseCollector: NewBaseCollector("administrativeUnits", config.Collector.AdministrativeUnits, config, logger),
+		stats:         map[string]administrativeUnitStats{},
+		roleNames:     map[string]map[string]string{},
+		unitsTotal: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_administrative_units_total",
+				Help: "Total number of administrative units",
+			},
+			[]string{"tenant_id"},
+		),
+		assignmentsTotal: newCountVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_administrative_unit_role_assignments_total",
+				Help: "Number of role assignments scoped to the administrative unit",
+			},
+			[]string{"tenant_id", "administrative_unit_id", "administrative_unit"},
+		),
+		assignmentsByRole: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_administrative_unit_role_assignments_by_role_total",
+				Help: "Number of role assignments scoped to the administrative unit by role",
+			},
+			[]string{"tenant_id", "administrative_unit_id", "administrative_unit", "role_definition_id", "role"},
+		),
+	}
+
+	// Start background collection
+	c.StartCacheInvalidator(ctx, c.collect)
+
+	return c
+}
+
+// Describe implements prometheus.Collector
+func (c *AdministrativeUnitsCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.BaseCollector.Describe(ch)
+	c.unitsTotal.Describe(ch)
+	c.assignmentsTotal.Describe(ch)
+	c.assignmentsByRole.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *AdministrativeUnitsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.BaseCollector.Collect(ch)
+
+	c.statsLock.RLock()
+	defer c.statsLock.RUnlock()
+
+	// Rebuild the metrics from the cache so removed units and assignments disappear
+	unitsTotal := c.unitsTotal.vec()
+	assignmentsByRole := c.assignmentsByRole.vec()
+
+	// Assignments are summed per unit per Collect, so concurrent Gathers don't
+	// add to the same series
+	assignmentsTotal := c.assignmentsTotal.counts()
+
+	for tenantID, stats := range c.stats {
+		if c.isCacheExpired(tenantID) {
+			continue
+		}
+
+		unitsTotal.WithLabelValues(tenantID).Set(float64(len(stats.units)))
+
+		// Units without scoped roles are exported too, so sprawl is visible relative to them
+		for unitID, unit := range stats.units {
+			assignmentsTotal.Add(0, tenantID, unitID, unit)
+		}
+		for key, assignments := range stats.assignments {
+			unit := stats.units[key.unitID]
+			role := "unknown"
+			if name, exists := c.roleNames[tenantID][key.roleDefinitionID]; exists {
+				role = name
+			}
+			assignmentsTotal.Add(float64(assignments), tenantID, key.unitID, unit)
+			assignmentsByRole.WithLabelValues(tenantID, key.unitID, unit, key.roleDefinitionID, role).Set(float64(assignments))
+		}
+	}
+
+	c.collectCached(ch, unitsTotal, assignmentsTotal, assignmentsByRole)
+}
+
+// collect gets the administrative units and the role assignments scoped to them
+func (c *AdministrativeUnitsCollector) collect(ctx context.Context) {
+	for _, tenantID := range c.GetTenants() {
+		if ctx.Err() != nil {
+			c.logger.Debugf("Stopping administrative units collection: %v", ctx.Err())
+			return
+		}
+
+		if !c.tenantAllowed(tenantID) {
+			continue
+		}
+
+		start := time.Now()
+		c.sampledDebugf("Collecting administrative units for tenant %s", tenantID)
+
+		client, err := c.GetGraphClient(ctx, tenantID)
+		if err != nil {
+			c.logger.Errorf("Failed to get Graph client for tenant %s: %v", tenantID, err)
+			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			c.tenantFailed(tenantID, err)
+			continue
+		}
+
+		roleNames := c.fetchRoleNames(ctx, client, tenantID)
+
+		stats := administrativeUnitStats{
+			units:       map[string]string{},
+			assignments: map[scopedRoleKey]int{},
+		}
+
+		unitQuery := directory.AdministrativeUnitsRequestBuilderGetQueryParameters{
+			Select: []string{"id", "displayName"},
+		}
+		if filter := c.collectorConfig.Filter; filter != "" {
+			c.logger.Debugf("Using filter %q for administrative units collection", filter)
+			unitQuery.Filter = &filter
+		}
+		unitConfig := directory.AdministrativeUnitsRequestBuilderGetRequestConfiguration{
+			QueryParameters: &unitQuery,
+		}
+		unitPages, err := readPages(ctx, c.BaseCollector, tenantID, "administrativeUnits", func(ctx context.Context, nextLink string) ([]models.AdministrativeUnitable, *string, error) {
+			builder := client.Directory().AdministrativeUnits()
+			requestConfig := &unitConfig
+			if nextLink != "" {
+				builder = builder.WithUrl(nextLink)
+				requestConfig = nil
+			}
+
+			result, err := builder.Get(ctx, requestConfig)
+			if err != nil {
+				return nil, nil, err
+			}
+			return result.GetValue(), result.GetOdataNextLink(), nil
+		}, func(unit models.AdministrativeUnitable) {
+			if unit.GetId() != nil {
+				stats.units[*unit.GetId()] = stringValue(unit.GetDisplayName(), "")
+			}
+		})
+
+		// Graph only filters role assignments by exact scope, the scoped ones
+		// are picked while paging
+		assignmentPages := 0
+		assignments := 0
+		if err == nil {
+			assignmentConfig := rolemanagement.DirectoryRoleAssignmentsRequestBuilderGetRequestConfiguration{
+				QueryParameters: &rolemanagement.DirectoryRoleAssignmentsRequestBuilderGetQueryParameters{
+					Select: []string{"id", "roleDefinitionId", "directoryScopeId"},
+				},
+			}
+			assignmentPages, err = readPages(ctx, c.BaseCollector, tenantID, "roleAssignments", func(ctx context.Context, nextLink string) ([]models.UnifiedRoleAssignmentable, *string, error) {
+				builder := client.RoleManagement().Directory().RoleAssignments()
+				requestConfig := &assignmentConfig
+				if nextLink != "" {
+					builder = builder.WithUrl(nextLink)
+					requestConfig = nil
+				}
+
+				result, err := builder.Get(ctx, requestConfig)
+				if err != nil {
+					return nil, nil, err
+				}
+				return result.GetValue(), result.GetOdataNextLink(), nil
+			}, func(assignment models.UnifiedRoleAssignmentable) {
+				unitID, scoped := strings.CutPrefix(stringValue(assignment.GetDirectoryScopeId(), ""), administrativeUnitScopePrefix)
+				if !scoped {
+					return
+				}
+				// Assignments of units outside the filter are left out
+				if _, exists := stats.units[unitID]; !exists {
+					return
+				}
+				assignments++
+				stats.assignments[scopedRoleKey{unitID: unitID, roleDefinitionID: stringValue(assignment.GetRoleDefinitionId(), "unknown")}]++
+			})
+		}
+
+		if ctx.Err() != nil {
+			c.logger.Debugf("Administrative units collection for tenant %s cancelled", tenantID)
+			return
+		}
+
+		// Partial assignments would look like removed delegations, keep the previous ones
+		if err != nil {
+			c.logger.WithFields(graphErrorFields(err)).Errorf("Failed to get administrative units or role assignments for tenant %s: %v", tenantID, graphErrorMessage(err))
+			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			c.tenantFailed(tenantID, err)
+			continue
+		}
+
+		objects := len(stats.units) + assignments
+		c.statsLock.Lock()
+		c.stats[tenantID] = stats
+		if roleNames != nil {
+			c.roleNames[tenantID] = roleNames
+		}
+		c.statsLock.Unlock()
+		c.cacheUpdated(tenantID, objects)
+		c.recordStats(tenantID, start, unitPages+assignmentPages, objects)
+		c.tenantSucceeded(tenantID)
+
+		// Update scrape metrics
+		duration := time.Since(start).Seconds()
+		c.observeDuration(tenantID, duration)
+		c.lastScrapeTime.WithLabelValues(tenantID).Set(float64(time.Now().Unix()))
+		c.sampledDebugf("Completed administrative units collection for tenant %s in %.2f seconds: %d units, %d scoped role assignments", tenantID, duration, len(stats.units), assignments)
+	}
+}
@@ -0,0 +1,88 @@
+package collector
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// graphEndpointLastUsed shows which Graph endpoints and permissions the
+// collectors actually use, to trim the app registration to least privilege
+var graphEndpointLastUsed = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "entraid_exporter_graph_endpoint_last_used_timestamp_seconds",
+		Help: "Time a collector last requested a Graph endpoint, with the permission the endpoint needs if known",
+	},
+	[]string{"collector", "endpoint", "permission"},
+)
+
+// endpointUsed records a Graph request of the current collection cycle
+func (c *BaseCollector) endpointUsed(endpoint string) {
+	c.usedEndpointsLock.Lock()
+	if c.usedEndpoints == nil {
+		c.usedEndpoints = map[string]bool{}
+	}
+	first := !c.usedEndpoints[endpoint]
+	c.usedEndpoints[endpoint] = true
+	c.usedEndpointsLock.Unlock()
+
+	if first {
+		graphEndpointLastUsed.WithLabelValues(c.name, endpoint, endpointPermission(endpoint)).Set(float64(time.Now().Unix()))
+	}
+}
+
+// auditEndpoints logs the Graph endpoints the finished collection cycle
+// requested, the permissions they need and the collector's required
+// permissions none of them needed, then starts a new cycle
+func (c *BaseCollector) auditEndpoints() {
+	c.usedEndpointsLock.Lock()
+	used := c.usedEndpoints
+	c.usedEndpoints = nil
+	c.usedEndpointsLock.Unlock()
+
+	if !c.config.Graph.AuditLog || len(used) == 0 {
+		return
+	}
+
+	endpoints := make([]string, 0, len(used))
+	var permissions []string
+	for endpoint := range used {
+		endpoints = append(endpoints, endpoint)
+		if permission := endpointPermission(endpoint); permission != "" && !slices.Contains(permissions, permission) {
+			permissions = append(permissions, permission)
+		}
+	}
+	slices.Sort(endpoints)
+	slices.Sort(permissions)
+
+	var unused []string
+	for _, permission := range RequiredPermissions(c.name, c.config) {
+		if !slices.Contains(permissions, permission) && !slices.Contains(unused, permission) {
+			unused = append(unused, permission)
+		}
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"endpoints":          strings.Join(endpoints, ","),
+		"permissions":        strings.Join(permissions, ","),
+		"unused_permissions": strings.Join(unused, ","),
+	}).Infof("Graph usage of %s collection cycle", c.name)
+}
+
+// endpointPermission returns the permission the endpoint needs, from the
+// longest permission probe path the endpoint starts with, or empty if unknown.
+// Properties needing additional permissions, like the sign-in activity of
+// users, aren't visible in the endpoint.
+func endpointPermission(endpoint string) string {
+	permission, longest := "", 0
+	for name, probe := range permissionProbes {
+		path, _, _ := strings.Cut(probe, "?")
+		if len(path) > longest && (endpoint == path || strings.HasPrefix(endpoint, path+"/")) {
+			permission, longest = name, len(path)
+		}
+	}
+	return permission
+}
@@ -0,0 +1,191 @@
+package collector
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/microsoftgraph/msgraph-sdk-go/reports"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/entra-exporter/config"
+)
+
+// AuthMethodsCollector collects authentication method registration metrics
+// from the user registration details report
+type AuthMethodsCollector struct {
+	*BaseCollector
+
+	// Registered users by method per tenant
+	registrationsLock sync.RWMutex
+	registrations     map[string]map[string]int
+
+	// Metrics
+	methodRegistered *gaugeVec
+}
+
+// NewAuthMethodsCollector creates a new AuthMethodsCollector
+func NewAuthMethodsCollector(ctx context.Context, config *config.Config, logger *logrus.Entry) *AuthMethodsCollector {
+	c := &AuthMethodsCollector{
+		BaseCollector: NewBaseCollector("authMethods", config.Collector.AuthMethods, config, logger),
+		registrations: map[string]map[string]int{},
+		methodRegistered: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_users_auth_method_registered_total",
+				Help: "Number of users who registered an authentication method in Entra ID",
+			},
+			[]string{"tenant_id", "method"},
+		),
+	}
+
+	// Start background collection
+	c.StartCacheInvalidator(ctx, c.collect)
+
+	return c
+}
+
+// Describe implements prometheus.Collector
+func (c *AuthMethodsCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.BaseCollector.Describe(ch)
+	c.methodRegistered.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *AuthMethodsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.BaseCollector.Collect(ch)
+
+	c.registrationsLock.RLock()
+	defer c.registrationsLock.RUnlock()
+
+	// Rebuild the metrics from the cache so methods nobody uses disappear
+	methodRegistered := c.methodRegistered.vec()
+
+	for tenantID, registrations := range c.registrations {
+		if c.isCacheExpired(tenantID) {
+			continue
+		}
+
+		for method, users := range registrations {
+			methodRegistered.WithLabelValues(tenantID, method).Set(float64(users))
+		}
+	}
+
+	c.collectCached(ch, methodRegistered)
+}
+
+// collect counts the registered methods of all users
+func (c *AuthMethodsCollector) collect(ctx context.Context) {
+	for _, tenantID := range c.GetTenants() {
+		if ctx.Err() != nil {
+			c.logger.Debugf("Stopping authentication methods collection: %v", ctx.Err())
+			return
+		}
+
+		if !c.tenantAllowed(tenantID) {
+			continue
+		}
+
+		start := time.Now()
+		c.sampledDebugf("Collecting authentication method registrations for tenant %s", tenantID)
+
+		client, err := c.GetGraphClient(ctx, tenantID)
+		if err != nil {
+			c.logger.Errorf("Failed to get Graph client for tenant %s: %v", tenantID, err)
+			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			c.tenantFailed(tenantID, err)
+			continue
+		}
+
+		query := reports.AuthenticationMethodsUserRegistrationDetailsRequestBuilderGetQueryParameters{
+			Select: []string{"id", "methodsRegistered"},
+		}
+		if filter := c.collectorConfig.Filter; filter != "" {
+			c.logger.Debugf("Using filter %q for authentication methods collection", filter)
+			query.Filter = &filter
+		}
+		reqConfig := reports.AuthenticationMethodsUserRegistrationDetailsRequestBuilderGetRequestConfiguration{
+			QueryParameters: &query,
+		}
+
+		pageCtx, cancelPages := context.WithCancel(ctx)
+		pages := prefetchPages(pageCtx, func(ctx context.Context, nextLink string) ([]models.UserRegistrationDetailsable, *string, error) {
+			builder := client.Reports().AuthenticationMethods().UserRegistrationDetails()
+			requestConfig := &reqConfig
+			if nextLink != "" {
+				builder = builder.WithUrl(nextLink)
+				requestConfig = nil
+			}
+
+			result, err := builder.Get(ctx, requestConfig)
+			if err != nil {
+				return nil, nil, err
+			}
+			return result.GetValue(), result.GetOdataNextLink(), nil
+		})
+
+		registrations := map[string]int{}
+		users := 0
+		var pageErr error
+		tooManyObjects := false
+		pageCount := 0
+		for page := range pages {
+			pageCount = page.number
+			c.checkSlowPage(tenantID, "userRegistrationDetails", page.number, page.duration)
+			if page.err != nil {
+				if ctx.Err() != nil {
+					cancelPages()
+					c.logger.Debugf("Authentication methods collection for tenant %s cancelled on page %d", tenantID, page.number)
+					return
+				}
+				c.logger.WithFields(graphErrorFields(page.err)).Errorf("Failed to get page %d of registration details for tenant %s: %v", page.number, tenantID, graphErrorMessage(page.err))
+				c.scrapeErrors.WithLabelValues(tenantID).Inc()
+				pageErr = page.err
+				break
+			}
+
+			for _, details := range page.items {
+				users++
+				for _, method := range details.GetMethodsRegistered() {
+					registrations[method]++
+				}
+			}
+			c.sampledDebugf("Retrieved %d registration details in page %d for tenant %s", len(page.items), page.number, tenantID)
+
+			if c.exceedsMaxObjects(tenantID, users) {
+				tooManyObjects = true
+				break
+			}
+		}
+		cancelPages()
+
+		if ctx.Err() != nil {
+			c.logger.Debugf("Authentication methods collection for tenant %s cancelled", tenantID)
+			return
+		}
+
+		if tooManyObjects {
+			c.tenantAborted(tenantID)
+			continue
+		}
+
+		// Partial counts would look like a drop in registrations, keep the previous ones
+		if pageErr != nil {
+			c.tenantFailed(tenantID, pageErr)
+			continue
+		}
+
+		c.registrationsLock.Lock()
+		c.registrations[tenantID] = registrations
+		c.registrationsLock.Unlock()
+		c.cacheUpdated(tenantID, users)
+		c.recordStats(tenantID, start, pageCount, users)
+		c.tenantSucceeded(tenantID)
+
+		// Update scrape metrics
+		duration := time.Since(start).Seconds()
+		c.observeDuration(tenantID, duration)
+		c.lastScrapeTime.WithLabelValues(tenantID).Set(float64(time.Now().Unix()))
+		c.sampledDebugf("Completed authentication methods collection for tenant %s in %.2f seconds: %d users", tenantID, duration, users)
+	}
+}
@@ -0,0 +1,227 @@
+package collector
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/devicemanagement"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/entra-exporter/config"
+)
+
+// autopilotStats counts the Autopilot devices of a tenant
+type autopilotStats struct {
+	total             int
+	byGroupTag        map[string]int
+	byEnrollmentState map[string]int
+}
+
+// AutopilotCollector collects Windows Autopilot device registration metrics
+type AutopilotCollector struct {
+	*BaseCollector
+
+	// Device counts per tenant
+	statsLock sync.RWMutex
+	stats     map[string]autopilotStats
+
+	// Metrics
+	devicesTotal             *gaugeVec
+	devicesByGroupTag        *gaugeVec
+	devicesByEnrollmentState *gaugeVec
+}
+
+// NewAutopilotCollector creates a new AutopilotCollector
+func NewAutopilotCollector(ctx context.Context, config *config.Config, logger *logrus.Entry) *AutopilotCollector {
+	c := &AutopilotCollector{
+		BaseCollector: NewBaseCollector("autopilot", config.Collector.Autopilot, config, logger),
+		stats:         map[string]autopilotStats{},
+		devicesTotal: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_autopilot_devices_total",
+				Help: "Number of devices registered with Windows Autopilot",
+			},
+			[]string{"tenant_id"},
+		),
+		devicesByGroupTag: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_autopilot_devices_by_group_tag_total",
+				Help: "Number of Windows Autopilot devices by group tag",
+			},
+			[]string{"tenant_id", "group_tag"},
+		),
+		devicesByEnrollmentState: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_autopilot_devices_by_enrollment_state_total",
+				Help: "Number of Windows Autopilot devices by Intune enrollment state",
+			},
+			[]string{"tenant_id", "enrollment_state"},
+		),
+	}
+
+	// Start background collection
+	c.StartCacheInvalidator(ctx, c.collect)
+
+	return c
+}
+
+// Describe implements prometheus.Collector
+func (c *AutopilotCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.BaseCollector.Describe(ch)
+	c.devicesTotal.Describe(ch)
+	c.devicesByGroupTag.Describe(ch)
+	c.devicesByEnrollmentState.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *AutopilotCollector) Collect(ch chan<- prometheus.Metric) {
+	c.BaseCollector.Collect(ch)
+
+	c.statsLock.RLock()
+	defer c.statsLock.RUnlock()
+
+	// Rebuild the metrics from the cache so removed group tags disappear
+	devicesTotal := c.devicesTotal.vec()
+	devicesByGroupTag := c.devicesByGroupTag.vec()
+	devicesByEnrollmentState := c.devicesByEnrollmentState.vec()
+
+	for tenantID, stats := range c.stats {
+		if c.isCacheExpired(tenantID) {
+			continue
+		}
+
+		devicesTotal.WithLabelValues(tenantID).Set(float64(stats.total))
+		for groupTag, devices := range stats.byGroupTag {
+			devicesByGroupTag.WithLabelValues(tenantID, groupTag).Set(float64(devices))
+		}
+		for state, devices := range stats.byEnrollmentState {
+			devicesByEnrollmentState.WithLabelValues(tenantID, state).Set(float64(devices))
+		}
+	}
+
+	c.collectCached(ch, devicesTotal, devicesByGroupTag, devicesByEnrollmentState)
+}
+
+// collect counts the Autopilot devices of all tenants
+func (c *AutopilotCollector) collect(ctx context.Context) {
+	for _, tenantID := range c.GetTenants() {
+		if ctx.Err() != nil {
+			c.logger.Debugf("Stopping Autopilot collection: %v", ctx.Err())
+			return
+		}
+
+		if !c.tenantAllowed(tenantID) {
+			continue
+		}
+
+		start := time.Now()
+		c.sampledDebugf("Collecting Autopilot devices for tenant %s", tenantID)
+
+		client, err := c.GetGraphClient(ctx, tenantID)
+		if err != nil {
+			c.logger.Errorf("Failed to get Graph client for tenant %s: %v", tenantID, err)
+			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			c.tenantFailed(tenantID, err)
+			continue
+		}
+
+		// Intune doesn't reliably support $select here, the full objects are requested
+		var reqConfig *devicemanagement.WindowsAutopilotDeviceIdentitiesRequestBuilderGetRequestConfiguration
+		if filter := c.collectorConfig.Filter; filter != "" {
+			c.logger.Debugf("Using filter %q for Autopilot collection", filter)
+			reqConfig = &devicemanagement.WindowsAutopilotDeviceIdentitiesRequestBuilderGetRequestConfiguration{
+				QueryParameters: &devicemanagement.WindowsAutopilotDeviceIdentitiesRequestBuilderGetQueryParameters{
+					Filter: &filter,
+				},
+			}
+		}
+
+		pageCtx, cancelPages := context.WithCancel(ctx)
+		pages := prefetchPages(pageCtx, func(ctx context.Context, nextLink string) ([]models.WindowsAutopilotDeviceIdentityable, *string, error) {
+			builder := client.DeviceManagement().WindowsAutopilotDeviceIdentities()
+			requestConfig := reqConfig
+			if nextLink != "" {
+				builder = builder.WithUrl(nextLink)
+				requestConfig = nil
+			}
+
+			result, err := builder.Get(ctx, requestConfig)
+			if err != nil {
+				return nil, nil, err
+			}
+			return result.GetValue(), result.GetOdataNextLink(), nil
+		})
+
+		stats := autopilotStats{
+			byGroupTag:        map[string]int{},
+			byEnrollmentState: map[string]int{},
+		}
+		var pageErr error
+		tooManyObjects := false
+		pageCount := 0
+		for page := range pages {
+			pageCount = page.number
+			c.checkSlowPage(tenantID, "windowsAutopilotDeviceIdentities", page.number, page.duration)
+			if page.err != nil {
+				if ctx.Err() != nil {
+					cancelPages()
+					c.logger.Debugf("Autopilot collection for tenant %s cancelled on page %d", tenantID, page.number)
+					return
+				}
+				c.logger.WithFields(graphErrorFields(page.err)).Errorf("Failed to get page %d of Autopilot devices for tenant %s: %v", page.number, tenantID, graphErrorMessage(page.err))
+				c.scrapeErrors.WithLabelValues(tenantID).Inc()
+				pageErr = page.err
+				break
+			}
+
+			for _, device := range page.items {
+				stats.total++
+				// Devices without group tag keep an empty label
+				stats.byGroupTag[stringValue(device.GetGroupTag(), "")]++
+				state := "unknown"
+				if device.GetEnrollmentState() != nil {
+					state = device.GetEnrollmentState().String()
+				}
+				stats.byEnrollmentState[state]++
+			}
+			c.sampledDebugf("Retrieved %d Autopilot devices in page %d for tenant %s", len(page.items), page.number, tenantID)
+
+			if c.exceedsMaxObjects(tenantID, stats.total) {
+				tooManyObjects = true
+				break
+			}
+		}
+		cancelPages()
+
+		if ctx.Err() != nil {
+			c.logger.Debugf("Autopilot collection for tenant %s cancelled", tenantID)
+			return
+		}
+
+		if tooManyObjects {
+			c.tenantAborted(tenantID)
+			continue
+		}
+
+		// Partial counts would look like removed devices, keep the previous ones
+		if pageErr != nil {
+			c.tenantFailed(tenantID, pageErr)
+			continue
+		}
+
+		c.statsLock.Lock()
+		c.stats[tenantID] = stats
+		c.statsLock.Unlock()
+		c.cacheUpdated(tenantID, stats.total)
+		c.recordStats(tenantID, start, pageCount, stats.total)
+		c.tenantSucceeded(tenantID)
+
+		// Update scrape metrics
+		duration := time.Since(start).Seconds()
+		c.observeDuration(tenantID, duration)
+		c.lastScrapeTime.WithLabelValues(tenantID).Set(float64(time.Now().Unix()))
+		c.sampledDebugf("Completed Autopilot collection for tenant %s in %.2f seconds: %d devices", tenantID, duration, stats.total)
+	}
+}
@@ -0,0 +1,110 @@
+package collector
+
+import (
+	"sync"
+	"time"
+
+	"github.com/your-username/entra-exporter/config"
+)
+
+// tenantCircuits is shared by all collectors so a failing tenant is paused
+// for every collector at once
+var tenantCircuits = newCircuitBreaker()
+
+// circuitState is the breaker state of a single tenant
+type circuitState struct {
+	failures  int
+	open      bool
+	probing   bool
+	backoff   time.Duration
+	openUntil time.Time
+}
+
+// circuitBreaker stops collections for tenants whose collections keep
+// failing and lets single recovery probes through with exponential backoff
+type circuitBreaker struct {
+	lock    sync.Mutex
+	tenants map[string]*circuitState
+}
+
+// newCircuitBreaker creates a new circuit breaker
+func newCircuitBreaker() *circuitBreaker {
+	return &circuitBreaker{
+		tenants: map[string]*circuitState{},
+	}
+}
+
+// state returns the state of a tenant, the lock must be held
+func (b *circuitBreaker) state(tenantID string) *circuitState {
+	state, exists := b.tenants[tenantID]
+	if !exists {
+		state = &circuitState{}
+		b.tenants[tenantID] = state
+	}
+	return state
+}
+
+// allow returns if a collection for the tenant may run. Once the backoff of
+// an open circuit has passed a single probe is allowed through.
+func (b *circuitBreaker) allow(tenantID string, cfg config.CircuitBreakerConfig) bool {
+	if cfg.Disabled {
+		return true
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	state := b.state(tenantID)
+	if !state.open {
+		return true
+	}
+
+	if state.probing || time.Now().Before(state.openUntil) {
+		return false
+	}
+
+	state.probing = true
+	return true
+}
+
+// success closes the circuit of a tenant
+func (b *circuitBreaker) success(tenantID string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.tenants[tenantID] = &circuitState{}
+	tenantCircuitOpen.WithLabelValues(tenantID).Set(0)
+}
+
+// failure records a failed collection and returns true if the circuit of
+// the tenant is open afterwards
+func (b *circuitBreaker) failure(tenantID string, cfg config.CircuitBreakerConfig) bool {
+	if cfg.Disabled {
+		return false
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	state := b.state(tenantID)
+	state.failures++
+
+	switch {
+	case state.probing:
+		// Recovery probe failed, back off further
+		state.probing = false
+		state.backoff *= 2
+		if state.backoff > cfg.GetMaxBackoff() {
+			state.backoff = cfg.GetMaxBackoff()
+		}
+	case !state.open && state.failures >= cfg.GetFailureThreshold():
+		state.open = true
+		state.backoff = cfg.GetInitialBackoff()
+	default:
+		return state.open
+	}
+
+	state.openUntil = time.Now().Add(state.backoff)
+	tenantCircuitOpen.WithLabelValues(tenantID).Set(1)
+	return true
+}
@@ -3,8 +3,11 @@ package collector
 import (
 	"context"
 	"fmt"
+	"math/rand/v2"
 	"os"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
@@ -12,37 +15,248 @@ import (
 	mgraph "github.com/microsoftgraph/msgraph-sdk-go"
 	graphauth "github.com/microsoft/kiota-authentication-azure-go"
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/sirupsen/logrus"
 	"github.com/your-username/entra-exporter/config"
+	"golang.org/x/time/rate"
 )
 
+// Collector is implemented by all Entra ID collectors
+type Collector interface {
+	prometheus.Collector
+
+	// Name returns the name of the collector
+	Name() string
+
+	// RunOnce runs a single collection cycle synchronously
+	RunOnce()
+
+	// LastStats returns the statistics of the last collection of every tenant
+	LastStats() []CollectionStats
+
+	// Status returns the runtime status of the collector
+	Status() CollectorStatus
+
+	// Refresh invalidates the cached results and collects again outside
+	// the schedule
+	Refresh()
+}
+
+// CollectionStats describes the last collection of a tenant
+type CollectionStats struct {
+	TenantID string
+	Started  time.Time
+	Duration time.Duration
+	Pages    int
+	Objects  int
+}
+
+// CollectionError is the last error of a tenant's collection
+type CollectionError struct {
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// TenantStatus is the runtime status of a collector for a tenant
+type TenantStatus struct {
+	TenantID        string           `json:"tenantId"`
+	LastRun         *time.Time       `json:"lastRun,omitempty"`
+	DurationSeconds float64          `json:"durationSeconds"`
+	Pages           int              `json:"pages"`
+	Objects         int              `json:"objects"`
+	CacheUpdated    *time.Time       `json:"cacheUpdated,omitempty"`
+	CacheExpired    bool             `json:"cacheExpired"`
+	LastError       *CollectionError `json:"lastError,omitempty"`
+}
+
+// CollectorConfigStatus is the configuration of a collector as reported in its status
+type CollectorConfigStatus struct {
+	ScrapeTime string `json:"scrapeTime"`
+	Mode       string `json:"mode"`
+	Filter     string `json:"filter,omitempty"`
+	CacheTTL   string `json:"cacheTTL,omitempty"`
+	MaxObjects int    `json:"maxObjects,omitempty"`
+	GraphURL   string `json:"graphUrl"`
+}
+
+// CollectorStatus is the runtime status of a collector
+type CollectorStatus struct {
+	Name    string                `json:"name"`
+	Config  CollectorConfigStatus `json:"config"`
+	Running bool                  `json:"running"`
+	Tenants []TenantStatus        `json:"tenants"`
+}
+
+// cacheInfo describes the cache of a tenant
+type cacheInfo struct {
+	updated time.Time
+	objects int
+}
+
+var (
+	// baseCollectors holds all created collectors for exporter wide metrics
+	baseCollectors     []*BaseCollector
+	baseCollectorsLock sync.RWMutex
+)
+
+// registeredCollectors returns all created collectors
+func registeredCollectors() []*BaseCollector {
+	baseCollectorsLock.RLock()
+	defer baseCollectorsLock.RUnlock()
+
+	return append([]*BaseCollector(nil), baseCollectors...)
+}
+
+// CollectionHook is called with the collector name after every completed
+// collection cycle, in the collection goroutine
+type CollectionHook func(collector string)
+
+var (
+	collectionHooks     []CollectionHook
+	collectionHooksLock sync.RWMutex
+)
+
+// AddCollectionHook registers a hook called after every completed collection cycle
+func AddCollectionHook(hook CollectionHook) {
+	collectionHooksLock.Lock()
+	defer collectionHooksLock.Unlock()
+	collectionHooks = append(collectionHooks, hook)
+}
+
+// collectionCompleted calls the collection hooks unless the collection was cancelled
+func (c *BaseCollector) collectionCompleted(ctx context.Context) {
+	c.auditEndpoints()
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	collectionHooksLock.RLock()
+	hooks := append([]CollectionHook(nil), collectionHooks...)
+	collectionHooksLock.RUnlock()
+
+	for _, hook := range hooks {
+		hook(c.name)
+	}
+}
+
+// standby pauses the collections of all collectors while another replica is
+// the elected leader, the cached results are still served
+var standby atomic.Bool
+
+// SetStandby pauses or resumes the collections of all collectors
+func SetStandby(paused bool) {
+	standby.Store(paused)
+}
+
+// paused suspends the collections of all collectors on request, e.g. during
+// tenant migrations or Graph incidents. Independent of the standby state so
+// resuming doesn't start collecting on a standby replica.
+var paused atomic.Bool
+
+// SetPaused suspends or resumes the collections of all collectors, the
+// cached results are served without expiring meanwhile
+func SetPaused(pause bool) {
+	paused.Store(pause)
+}
+
+// Paused returns if the collections are suspended
+func Paused() bool {
+	return paused.Load()
+}
+
+// inFlight tracks the running collection cycles of all collectors
+var inFlight sync.WaitGroup
+
+// WaitCollections waits for the running collection cycles to return after
+// the collectors were stopped. Returns false if the context is done first.
+func WaitCollections(ctx context.Context) bool {
+	done := make(chan struct{})
+	go func() {
+		inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
 // BaseCollector is a base collector for all Microsoft Graph collectors
 type BaseCollector struct {
 	sync.Mutex
 
-	name       string
-	logger     *logrus.Entry
-	config     *config.Config
-	scrapeTime time.Duration
+	name            string
+	logger          *logrus.Entry
+	config          *config.Config
+	collectorConfig config.CollectorConfig
+	scrapeTime      time.Duration
+
+	// Collection function and context, set by StartCacheInvalidator
+	collectCtx  context.Context
+	collectFunc func(ctx context.Context)
+	lastCollect time.Time
+	running     atomic.Bool
+
+	// Consecutive panicked collection cycles and until when further cycles
+	// are skipped, guarded by the collector lock
+	panics       int
+	panicBackoff time.Time
+
+	// Sampling of high volume debug logs, nil if unlimited
+	debugLimiter    *rate.Limiter
+	debugSuppressed atomic.Int64
+
+	// Statistics of the last collection per tenant
+	runStats     map[string]CollectionStats
+	runStatsLock sync.RWMutex
+
+	// Last collection error and consecutive auth failures per tenant
+	lastErrors     map[string]CollectionError
+	authFailures   map[string]int
+	lastErrorsLock sync.RWMutex
+
+	// State of the cache per tenant and the tenants whose last collection
+	// failed or was aborted, their cache may be partial or stale
+	caches     map[string]cacheInfo
+	incomplete map[string]bool
+	cachesLock sync.RWMutex
 
 	graphClients      map[string]*mgraph.GraphServiceClient
 	graphClientsLock  sync.RWMutex
 
+	// Graph endpoints requested during the current collection cycle
+	usedEndpoints     map[string]bool
+	usedEndpointsLock sync.Mutex
+
 	// Common metrics
 	scrapeErrors *prometheus.CounterVec
 	scrapeDuration *prometheus.SummaryVec
+	scrapeDurationHistogram *prometheus.HistogramVec
 	lastScrapeTime *prometheus.GaugeVec
+	skippedCycles prometheus.Counter
+	cacheExpired *gaugeVec
+	maxObjectsExceeded *prometheus.CounterVec
 }
 
 // NewBaseCollector creates a new base collector
-func NewBaseCollector(name string, scrapeTime time.Duration, config *config.Config, logger *logrus.Entry) *BaseCollector {
+func NewBaseCollector(name string, collectorConfig config.CollectorConfig, config *config.Config, logger *logrus.Entry) *BaseCollector {
 	c := &BaseCollector{
 		name:              name,
 		logger:            logger,
 		config:            config,
-		scrapeTime:        scrapeTime,
+		collectorConfig:   collectorConfig,
+		scrapeTime:        collectorConfig.ScrapeTime,
 		graphClients:      map[string]*mgraph.GraphServiceClient{},
 		graphClientsLock:  sync.RWMutex{},
+		caches:            map[string]cacheInfo{},
+		incomplete:        map[string]bool{},
+		runStats:          map[string]CollectionStats{},
+		lastErrors:        map[string]CollectionError{},
+		authFailures:      map[string]int{},
 		
 		scrapeErrors: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
@@ -65,13 +279,52 @@ func NewBaseCollector(name string, scrapeTime time.Duration, config *config.Conf
 			},
 			[]string{"tenant_id"},
 		),
+		skippedCycles: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: fmt.Sprintf("entraid_%s_skipped_cycles_total", name),
+				Help: fmt.Sprintf("Total number of Entra ID %s collection cycles skipped because the previous one was still running", name),
+			},
+		),
+		cacheExpired: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: fmt.Sprintf("entraid_%s_cache_expired", name),
+				Help: fmt.Sprintf("Whether the cached Entra ID %s data is older than the cache TTL and no longer exported (1 = expired)", name),
+			},
+			[]string{"tenant_id"},
+		),
+		maxObjectsExceeded: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: fmt.Sprintf("entraid_%s_max_objects_exceeded_total", name),
+				Help: fmt.Sprintf("Total number of Entra ID %s collections aborted because they returned more than maxObjects objects", name),
+			},
+			[]string{"tenant_id"},
+		),
+	}
+
+	if collectorConfig.DebugLogRate > 0 {
+		c.debugLimiter = rate.NewLimiter(rate.Limit(collectorConfig.DebugLogRate), max(1, int(collectorConfig.DebugLogRate)))
 	}
 
+	if config.Metrics.DurationHistogram {
+		c.scrapeDurationHistogram = prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    fmt.Sprintf("entraid_%s_scrape_duration_histogram_seconds", name),
+				Help:    fmt.Sprintf("Histogram of the Entra ID %s scrape duration in seconds", name),
+				Buckets: config.GetDurationBuckets(),
+			},
+			[]string{"tenant_id"},
+		)
+	}
+
+	baseCollectorsLock.Lock()
+	baseCollectors = append(baseCollectors, c)
+	baseCollectorsLock.Unlock()
+
 	return c
 }
 
 // GetGraphClient returns a Microsoft Graph client for a tenant
-func (c *BaseCollector) GetGraphClient(tenantID string) (*mgraph.GraphServiceClient, error) {
+func (c *BaseCollector) GetGraphClient(ctx context.Context, tenantID string) (*mgraph.GraphServiceClient, error) {
 	c.graphClientsLock.RLock()
 	if client, exists := c.graphClients[tenantID]; exists {
 		c.graphClientsLock.RUnlock()
@@ -108,13 +361,7 @@ func (c *BaseCollector) GetGraphClient(tenantID string) (*mgraph.GraphServiceCli
 		c.logger.Debugf("Using default Azure credential chain (managed identity or other method)")
 	}
 
-	// Create a credential using the default Azure credential chain
-	credOptions := &azidentity.DefaultAzureCredentialOptions{}
-	if tenantID != "" {
-		credOptions.TenantID = tenantID
-	}
-	
-	cred, err := azidentity.NewDefaultAzureCredential(credOptions)
+	cred, err := newCredential(tenantID)
 	if err != nil {
 		c.logger.Errorf("Failed to create Azure credential: %v", err)
 		c.logger.Debug("Authentication error details: Check if AZURE_TENANT_ID, AZURE_CLIENT_ID, and AZURE_CLIENT_SECRET environment variables are set correctly")
@@ -123,15 +370,13 @@ func (c *BaseCollector) GetGraphClient(tenantID string) (*mgraph.GraphServiceCli
 
 	// Try to validate the credential by getting a token
 	c.logger.Debug("Validating Azure credential by requesting a token")
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	tokenCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 	
-	// The Microsoft Graph scope
-	scopes := []string{"https://graph.microsoft.com/.default"}
 	tokenRequestOptions := policy.TokenRequestOptions{
-		Scopes: scopes,
+		Scopes: []string{c.collectorConfig.GetGraphScope()},
 	}
-	_, err = cred.GetToken(ctx, tokenRequestOptions)
+	_, err = cred.GetToken(tokenCtx, tokenRequestOptions)
 	if err != nil {
 		c.logger.Errorf("Failed to validate Azure credential: %v", err)
 		c.logger.Debug("Token acquisition failed: This usually indicates incorrect credentials or insufficient permissions")
@@ -139,19 +384,21 @@ func (c *BaseCollector) GetGraphClient(tenantID string) (*mgraph.GraphServiceCli
 	}
 	c.logger.Debug("Successfully acquired authentication token")
 
-	// Create an auth provider using the credential
-	authProvider, err := graphauth.NewAzureIdentityAuthenticationProvider(cred)
+	// Create an auth provider using the credential, tracking the token expiry for state dumps.
+	// The scope matches the configured Graph host, e.g. of a national cloud.
+	authProvider, err := graphauth.NewAzureIdentityAuthenticationProviderWithScopes(&expiryTrackingCredential{TokenCredential: cred, tenantID: tenantID}, []string{c.collectorConfig.GetGraphScope()})
 	if err != nil {
 		c.logger.Errorf("Failed to create auth provider: %v", err)
 		return nil, fmt.Errorf("failed to create auth provider: %v", err)
 	}
 
 	// Create a request adapter
-	adapter, err := mgraph.NewGraphRequestAdapter(authProvider)
+	adapter, err := mgraph.NewGraphRequestAdapterWithParseNodeFactoryAndSerializationWriterFactoryAndHttpClient(authProvider, nil, nil, c.newGraphHTTPClient(tenantID))
 	if err != nil {
 		c.logger.Errorf("Failed to create adapter: %v", err)
 		return nil, fmt.Errorf("failed to create adapter: %v", err)
 	}
+	adapter.SetBaseUrl(c.collectorConfig.GetGraphBaseURL())
 
 	c.logger.Debugf("Successfully created Graph client for tenant: %s", tenantID)
 
@@ -162,6 +409,46 @@ func (c *BaseCollector) GetGraphClient(tenantID string) (*mgraph.GraphServiceCli
 	return client, nil
 }
 
+// newCredential creates a credential for the tenant using the default Azure
+// credential chain
+func newCredential(tenantID string) (*azidentity.DefaultAzureCredential, error) {
+	credOptions := &azidentity.DefaultAzureCredentialOptions{}
+	if tenantID != "" {
+		credOptions.TenantID = tenantID
+	}
+	return azidentity.NewDefaultAzureCredential(credOptions)
+}
+
+// stringValue dereferences an optional Graph string, falling back to def
+func stringValue(value *string, def string) string {
+	if value == nil {
+		return def
+	}
+	return *value
+}
+
+// boolValue dereferences an optional Graph bool, treating nil as false
+func boolValue(value *bool) bool {
+	return value != nil && *value
+}
+
+// boolFloat returns 1 for true and 0 for false as metric value
+func boolFloat(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+// syncSource returns the sync source label of an object by its
+// onPremisesSyncEnabled property
+func syncSource(onPremisesSyncEnabled bool) string {
+	if onPremisesSyncEnabled {
+		return "onPremises"
+	}
+	return "cloud"
+}
+
 // GetTenants returns a list of tenants from the config
 func (c *BaseCollector) GetTenants() []string {
 	tenants := c.config.Azure.Tenants
@@ -179,59 +466,635 @@ func (c *BaseCollector) GetTenants() []string {
 		}
 	}
 	
+	tenants = c.config.FilterTenants(tenants)
+	if len(tenants) == 0 {
+		c.logger.Warn("No tenants left after the tenant include and exclude filters")
+	}
 	c.logger.Debugf("Using tenants: %v", tenants)
 	return tenants
 }
 
+// tenantAllowed returns if the circuit breaker lets a collection for the tenant run
+func (c *BaseCollector) tenantAllowed(tenantID string) bool {
+	if tenantCircuits.allow(tenantID, c.config.Graph.CircuitBreaker) {
+		return true
+	}
+	c.logger.Debugf("Circuit for tenant %s is open, skipping %s collection", tenantID, c.name)
+	return false
+}
+
+// tenantFailed records a failed collection for the tenant with the circuit breaker
+// and keeps the error for status reporting
+func (c *BaseCollector) tenantFailed(tenantID string, err error) {
+	collectorSuccess.WithLabelValues(c.name, tenantID).Set(0)
+
+	c.lastErrorsLock.Lock()
+	c.lastErrors[tenantID] = CollectionError{
+		Message: graphErrorMessage(err),
+		Time:    time.Now(),
+	}
+	c.lastErrorsLock.Unlock()
+
+	c.checkAuthFailure(tenantID, err)
+	c.setIncomplete(tenantID, true)
+
+	if tenantCircuits.failure(tenantID, c.config.Graph.CircuitBreaker) {
+		c.logger.Warnf("Circuit for tenant %s is open after repeated failures, pausing collections", tenantID)
+	}
+}
+
+// tenantSucceeded records a successful collection for the tenant with the circuit breaker
+func (c *BaseCollector) tenantSucceeded(tenantID string) {
+	collectorSuccess.WithLabelValues(c.name, tenantID).Set(1)
+	collectorLastSuccess.WithLabelValues(c.name, tenantID).Set(float64(time.Now().Unix()))
+
+	c.lastErrorsLock.Lock()
+	delete(c.authFailures, tenantID)
+	c.lastErrorsLock.Unlock()
+	c.setIncomplete(tenantID, false)
+
+	tenantCircuits.success(tenantID)
+}
+
+// tenantAborted records a collection for the tenant which was aborted by the
+// exporter although Graph responded fine
+func (c *BaseCollector) tenantAborted(tenantID string) {
+	collectorSuccess.WithLabelValues(c.name, tenantID).Set(0)
+	c.setIncomplete(tenantID, true)
+
+	tenantCircuits.success(tenantID)
+}
+
+// observeDuration records the duration of a tenant's collection in seconds
+func (c *BaseCollector) observeDuration(tenantID string, duration float64) {
+	c.scrapeDuration.WithLabelValues(tenantID).Observe(duration)
+	if c.scrapeDurationHistogram != nil {
+		c.scrapeDurationHistogram.WithLabelValues(tenantID).Observe(duration)
+	}
+}
+
+// sampledDebugf logs high volume debug messages, e.g. per page or per tenant,
+// limited to the configured debug log rate of the collector
+func (c *BaseCollector) sampledDebugf(format string, args ...interface{}) {
+	if !c.logger.Logger.IsLevelEnabled(logrus.DebugLevel) {
+		return
+	}
+
+	if c.debugLimiter != nil {
+		if !c.debugLimiter.Allow() {
+			c.debugSuppressed.Add(1)
+			return
+		}
+		if suppressed := c.debugSuppressed.Swap(0); suppressed > 0 {
+			format += fmt.Sprintf(" (%d similar debug messages suppressed)", suppressed)
+		}
+	}
+
+	c.logger.Debugf(format, args...)
+}
+
+// checkSlowPage logs a warning if fetching a page of the endpoint took
+// longer than the configured slow request threshold
+func (c *BaseCollector) checkSlowPage(tenantID, endpoint string, page int, duration time.Duration) {
+	threshold := c.config.Graph.SlowRequestThreshold
+	if threshold <= 0 || duration <= threshold {
+		return
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"tenant_id": tenantID,
+		"endpoint":  endpoint,
+		"page":      page,
+		"duration":  duration.Round(time.Millisecond).String(),
+	}).Warnf("Slow Graph request: page %d of %s for tenant %s took %s (threshold %s)", page, endpoint, tenantID, duration.Round(time.Millisecond), threshold)
+}
+
+// cacheUpdated records that the cache of the tenant was refreshed with the
+// given number of objects
+func (c *BaseCollector) cacheUpdated(tenantID string, objects int) {
+	c.cachesLock.Lock()
+	defer c.cachesLock.Unlock()
+
+	c.caches[tenantID] = cacheInfo{
+		updated: time.Now(),
+		objects: objects,
+	}
+}
+
+// setIncomplete records if the last collection of the tenant failed or was aborted
+func (c *BaseCollector) setIncomplete(tenantID string, incomplete bool) {
+	c.cachesLock.Lock()
+	defer c.cachesLock.Unlock()
+
+	if incomplete {
+		c.incomplete[tenantID] = true
+	} else {
+		delete(c.incomplete, tenantID)
+	}
+}
+
+// InventoryComplete returns if the cached inventory of the tenant is complete,
+// its last collection neither failed nor was aborted and it hasn't expired
+func (c *BaseCollector) InventoryComplete(tenantID string) bool {
+	c.cachesLock.RLock()
+	incomplete := c.incomplete[tenantID]
+	c.cachesLock.RUnlock()
+
+	return !incomplete && !c.isCacheExpired(tenantID)
+}
+
+// cacheInfos returns the cache state of all tenants
+func (c *BaseCollector) cacheInfos() map[string]cacheInfo {
+	c.cachesLock.RLock()
+	defer c.cachesLock.RUnlock()
+
+	infos := make(map[string]cacheInfo, len(c.caches))
+	for tenantID, info := range c.caches {
+		infos[tenantID] = info
+	}
+	return infos
+}
+
+// collectCached sends the metrics built from the tenant caches. With
+// collection timestamps enabled, samples carry the time the cache of their
+// tenant was updated instead of the scrape time.
+func (c *BaseCollector) collectCached(ch chan<- prometheus.Metric, collectors ...prometheus.Collector) {
+	if !c.config.Metrics.CollectionTimestamps {
+		for _, collector := range collectors {
+			collector.Collect(ch)
+		}
+		return
+	}
+
+	caches := c.cacheInfos()
+	metrics := make(chan prometheus.Metric)
+	go func() {
+		defer close(metrics)
+		for _, collector := range collectors {
+			collector.Collect(metrics)
+		}
+	}()
+
+	var written dto.Metric
+	for metric := range metrics {
+		written.Reset()
+		if err := metric.Write(&written); err == nil {
+			for _, label := range written.GetLabel() {
+				if label.GetName() != "tenant_id" {
+					continue
+				}
+				if info, exists := caches[label.GetValue()]; exists {
+					metric = prometheus.NewMetricWithTimestamp(info.updated, metric)
+				}
+				break
+			}
+		}
+		ch <- metric
+	}
+}
+
+// isCacheExpired returns if the cache of the tenant is older than the cache TTL
+func (c *BaseCollector) isCacheExpired(tenantID string) bool {
+	if c.collectorConfig.CacheTTL <= 0 || paused.Load() {
+		return false
+	}
+
+	c.cachesLock.RLock()
+	defer c.cachesLock.RUnlock()
+
+	info, exists := c.caches[tenantID]
+	return exists && time.Since(info.updated) > c.collectorConfig.CacheTTL
+}
+
+// exceedsMaxObjects returns if a collection of the tenant returned more
+// objects than allowed by maxObjects and must be aborted
+func (c *BaseCollector) exceedsMaxObjects(tenantID string, count int) bool {
+	maxObjects := c.collectorConfig.MaxObjects
+	if maxObjects <= 0 || count <= maxObjects {
+		return false
+	}
+
+	c.logger.Errorf("Aborting %s collection for tenant %s: more than %d objects returned, keeping previous cache", c.name, tenantID, maxObjects)
+	c.maxObjectsExceeded.WithLabelValues(tenantID).Inc()
+	return true
+}
+
 // Describe implements prometheus.Collector
 func (c *BaseCollector) Describe(ch chan<- *prometheus.Desc) {
 	c.scrapeErrors.Describe(ch)
 	c.scrapeDuration.Describe(ch)
+	if c.scrapeDurationHistogram != nil {
+		c.scrapeDurationHistogram.Describe(ch)
+	}
 	c.lastScrapeTime.Describe(ch)
+	c.skippedCycles.Describe(ch)
+	c.cacheExpired.Describe(ch)
+	c.maxObjectsExceeded.Describe(ch)
 }
 
-// Collect implements prometheus.Collector
+// Collect implements prometheus.Collector. In ondemand mode it refreshes the
+// cache first, so collectors must call it before reading their caches.
 func (c *BaseCollector) Collect(ch chan<- prometheus.Metric) {
+	if c.collectFunc != nil && c.collectorConfig.IsOnDemand() {
+		c.collectOnDemand()
+	}
+
 	c.scrapeErrors.Collect(ch)
 	c.scrapeDuration.Collect(ch)
+	if c.scrapeDurationHistogram != nil {
+		c.scrapeDurationHistogram.Collect(ch)
+	}
 	c.lastScrapeTime.Collect(ch)
+	c.skippedCycles.Collect(ch)
+	c.maxObjectsExceeded.Collect(ch)
+
+	cacheExpired := c.cacheExpired.vec()
+	for tenantID := range c.cacheInfos() {
+		expired := 0.0
+		if c.isCacheExpired(tenantID) {
+			expired = 1
+		}
+		cacheExpired.WithLabelValues(tenantID).Set(expired)
+	}
+	cacheExpired.Collect(ch)
 }
 
 // StartCacheInvalidator starts background cache invalidation based on scrape time
-func (c *BaseCollector) StartCacheInvalidator(collect func()) {
+// plus jitter until the context is cancelled. Cycles that would overlap a still running
+// collection are skipped. Collectors in ondemand mode collect during Collect
+// instead, in oneshot mode only RunOnce collects.
+func (c *BaseCollector) StartCacheInvalidator(ctx context.Context, collect func(ctx context.Context)) {
+	c.collectCtx = ctx
+	c.collectFunc = collect
+
+	if c.config.Oneshot {
+		c.logger.Debugf("%s collector runs once, skipping cache invalidator", c.name)
+		return
+	}
+
+	if c.collectorConfig.IsOnDemand() {
+		c.logger.Infof("%s collector runs on demand, results are cached for %s", c.name, c.collectorConfig.GetOnDemandCacheTime())
+		return
+	}
+
+	go c.superviseCacheInvalidator(ctx, collect)
+}
+
+// Backoff of cache invalidator restarts after panics
+const (
+	minRestartBackoff = 5 * time.Second
+	maxRestartBackoff = 5 * time.Minute
+)
+
+// maxPanicBackoff limits how long collection cycles are skipped after
+// consecutive panics, unless the scrape time is longer
+const maxPanicBackoff = 6 * time.Hour
+
+// collectionPanicked records a panicked collection cycle. The following
+// cycles are skipped for the scrape time, doubled with every consecutive
+// panic, so a crash-looping collection doesn't hit Graph every cycle. Must
+// be called with the collector locked.
+func (c *BaseCollector) collectionPanicked() {
+	c.panics++
+	collectorRestarts.WithLabelValues(c.name).Inc()
+
+	limit := max(maxPanicBackoff, c.scrapeTime)
+	backoff := max(c.scrapeTime, minRestartBackoff)
+	for i := 1; i < c.panics && backoff < limit; i++ {
+		backoff *= 2
+	}
+	backoff = min(backoff, limit)
+
+	c.panicBackoff = time.Now().Add(backoff)
+	c.logger.Warnf("Skipping %s collection cycles for %s after %d consecutive panics", c.name, backoff, c.panics)
+}
+
+// collectionSucceeded resets the panic backoff after a collection cycle
+// returned. Must be called with the collector locked.
+func (c *BaseCollector) collectionSucceeded() {
+	c.panics = 0
+	c.panicBackoff = time.Time{}
+}
+
+// inPanicBackoff returns if collection cycles are skipped after panics.
+// Must be called with the collector locked.
+func (c *BaseCollector) inPanicBackoff() bool {
+	if time.Now().Before(c.panicBackoff) {
+		c.logger.Debugf("Skipping %s collection cycle until %s after %d consecutive panics", c.name, c.panicBackoff.Format(time.RFC3339), c.panics)
+		return true
+	}
+	return false
+}
+
+// superviseCacheInvalidator runs the cache invalidator and restarts it after
+// a panic. The delay before a restart doubles with every panic up to
+// maxRestartBackoff and is reset once the invalidator ran that long.
+func (c *BaseCollector) superviseCacheInvalidator(ctx context.Context, collect func(ctx context.Context)) {
+	c.logger.Infof("Starting cache invalidator for %s collector", c.name)
+
+	// Stagger the first run so collectors don't all hit Graph at startup
+	wait := randomDuration(c.collectorConfig.GetStartDelay())
+	c.logger.Debugf("Delaying first %s collection cycle by %s", c.name, wait)
+
+	backoff := minRestartBackoff
+	for {
+		start := time.Now()
+		if !c.runCacheInvalidator(ctx, collect, wait) {
+			return
+		}
+
+		collectorRestarts.WithLabelValues(c.name).Inc()
+		if time.Since(start) > maxRestartBackoff {
+			backoff = minRestartBackoff
+		}
+		c.logger.Infof("Restarting cache invalidator for %s collector after panic in %s", c.name, backoff)
+
+		// The collection runs right after the backoff
+		wait = 0
+		select {
+		case <-ctx.Done():
+			c.logger.Infof("Stopping cache invalidator for %s collector", c.name)
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxRestartBackoff)
+	}
+}
+
+// runCacheInvalidator runs a collection cycle after wait and then every
+// scrape time until the context is done. It returns true if it panicked.
+func (c *BaseCollector) runCacheInvalidator(ctx context.Context, collect func(ctx context.Context), wait time.Duration) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.logger.Errorf("PANIC in %s collector: %v", c.name, r)
+			reportPanic(c.name, r)
+			panicked = true
+		}
+	}()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.logger.Infof("Stopping cache invalidator for %s collector", c.name)
+			return false
+		case <-timer.C:
+		}
+
+		c.runCollection(ctx, collect)
+
+		// Wait for next scrape
+		wait = c.scrapeTime + randomDuration(c.collectorConfig.ScrapeJitter)
+		c.logger.Debugf("Waiting %s for next %s collection cycle", wait, c.name)
+		timer.Reset(wait)
+	}
+}
+
+// randomDuration returns a random duration in [0, max)
+func randomDuration(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(max)))
+}
+
+// Refresh starts a collection cycle now in background mode, in on demand
+// mode the next scrape collects
+func (c *BaseCollector) Refresh() {
+	if c.collectFunc == nil || c.config.Oneshot {
+		return
+	}
+
+	if c.collectorConfig.IsOnDemand() {
+		c.Lock()
+		c.lastCollect = time.Time{}
+		c.Unlock()
+		return
+	}
+
+	c.logger.Debugf("Refreshing %s collection outside the schedule", c.name)
+	c.runCollection(c.collectCtx, c.collectFunc)
+}
+
+// runCollection starts a collection cycle in the background unless the
+// previous cycle is still running, in which case the cycle is skipped
+func (c *BaseCollector) runCollection(ctx context.Context, collect func(ctx context.Context)) {
+	if standby.Load() {
+		c.logger.Debugf("Skipping %s collection cycle on standby", c.name)
+		return
+	}
+	if paused.Load() {
+		c.logger.Debugf("Skipping %s collection cycle while paused", c.name)
+		return
+	}
+
+	if !c.TryLock() {
+		c.logger.Warnf("Previous %s collection cycle is still running, skipping this cycle", c.name)
+		c.skippedCycles.Inc()
+		return
+	}
+
+	if c.inPanicBackoff() {
+		c.Unlock()
+		return
+	}
+
+	inFlight.Add(1)
 	go func() {
-		c.logger.Infof("Starting cache invalidator for %s collector", c.name)
-		
-		// Recover from panics to keep the application running
+		defer inFlight.Done()
+		defer c.Unlock()
+
+		// Recover from panics during the collection cycle, the following
+		// cycles back off
 		defer func() {
 			if r := recover(); r != nil {
-				c.logger.Errorf("PANIC in %s collector: %v", c.name, r)
-				// Restart the goroutine after a short delay
-				time.Sleep(5 * time.Second)
-				c.logger.Infof("Restarting cache invalidator for %s collector after panic", c.name)
-				// Restart the cache invalidator
-				c.StartCacheInvalidator(collect)
+				c.logger.Errorf("PANIC during %s collection: %v", c.name, r)
+				reportPanic(c.name, r)
+				c.collectionPanicked()
 			}
 		}()
 
-		for {
-			// Wrap collection in another recover to prevent panics during each collection cycle
-			func() {
-				defer func() {
-					if r := recover(); r != nil {
-						c.logger.Errorf("PANIC during %s collection: %v", c.name, r)
-					}
-				}()
-				
-				// Run the collection
-				c.logger.Debugf("Starting collection cycle for %s", c.name)
-				collect()
-				c.logger.Debugf("Completed collection cycle for %s", c.name)
-			}()
-
-			// Wait for next scrape
-			c.logger.Debugf("Waiting %s for next %s collection cycle", c.scrapeTime, c.name)
-			time.Sleep(c.scrapeTime)
+		c.logger.Debugf("Starting collection cycle for %s", c.name)
+		c.running.Store(true)
+		defer c.running.Store(false)
+		collect(ctx)
+		c.collectionSucceeded()
+		c.logger.Debugf("Completed collection cycle for %s", c.name)
+		c.collectionCompleted(ctx)
+	}()
+}
+
+// collectOnDemand runs a collection cycle during the scrape unless the
+// previous result is younger than the on demand cache time
+func (c *BaseCollector) collectOnDemand() {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.collectCtx.Err() != nil || standby.Load() || paused.Load() {
+		return
+	}
+
+	if age := time.Since(c.lastCollect); age < c.collectorConfig.GetOnDemandCacheTime() {
+		c.logger.Debugf("Serving cached %s results (age %s)", c.name, age)
+		return
+	}
+
+	if c.inPanicBackoff() {
+		return
+	}
+
+	inFlight.Add(1)
+	defer inFlight.Done()
+
+	// Recover from panics so a failed collection doesn't break the scrape,
+	// the following scrapes serve the cached results during the backoff
+	defer func() {
+		if r := recover(); r != nil {
+			c.logger.Errorf("PANIC during %s collection: %v", c.name, r)
+			reportPanic(c.name, r)
+			c.collectionPanicked()
 		}
 	}()
+
+	c.logger.Debugf("Starting on demand collection for %s", c.name)
+	c.running.Store(true)
+	defer c.running.Store(false)
+	c.collectFunc(c.collectCtx)
+	c.collectionSucceeded()
+	c.lastCollect = time.Now()
+	c.logger.Debugf("Completed on demand collection for %s", c.name)
+	c.collectionCompleted(c.collectCtx)
+}
+
+// RunOnce runs a single collection cycle synchronously
+func (c *BaseCollector) RunOnce() {
+	c.Lock()
+	defer c.Unlock()
+
+	// Recover from panics so one collector doesn't stop the others
+	defer func() {
+		if r := recover(); r != nil {
+			c.logger.Errorf("PANIC during %s collection: %v", c.name, r)
+			reportPanic(c.name, r)
+		}
+	}()
+
+	c.logger.Debugf("Starting single collection cycle for %s", c.name)
+	c.running.Store(true)
+	defer c.running.Store(false)
+	c.collectFunc(c.collectCtx)
+	c.lastCollect = time.Now()
+	c.logger.Debugf("Completed single collection cycle for %s", c.name)
+	c.collectionCompleted(c.collectCtx)
+}
+
+// recordStats stores the statistics of a completed collection of the tenant
+func (c *BaseCollector) recordStats(tenantID string, start time.Time, pages, objects int) {
+	c.runStatsLock.Lock()
+	defer c.runStatsLock.Unlock()
+
+	c.runStats[tenantID] = CollectionStats{
+		TenantID: tenantID,
+		Started:  start,
+		Duration: time.Since(start),
+		Pages:    pages,
+		Objects:  objects,
+	}
+}
+
+// Name returns the name of the collector
+func (c *BaseCollector) Name() string {
+	return c.name
+}
+
+// LastStats returns the statistics of the last collection of every tenant
+func (c *BaseCollector) LastStats() []CollectionStats {
+	c.runStatsLock.RLock()
+	defer c.runStatsLock.RUnlock()
+
+	stats := make([]CollectionStats, 0, len(c.runStats))
+	for _, tenantStats := range c.runStats {
+		stats = append(stats, tenantStats)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].TenantID < stats[j].TenantID
+	})
+	return stats
+}
+
+// lastErrorsByTenant returns the last collection error of every tenant which had one
+func (c *BaseCollector) lastErrorsByTenant() map[string]CollectionError {
+	c.lastErrorsLock.RLock()
+	defer c.lastErrorsLock.RUnlock()
+
+	lastErrors := make(map[string]CollectionError, len(c.lastErrors))
+	for tenantID, lastError := range c.lastErrors {
+		lastErrors[tenantID] = lastError
+	}
+	return lastErrors
+}
+
+// Status returns the runtime status of the collector
+func (c *BaseCollector) Status() CollectorStatus {
+	mode := c.collectorConfig.Mode
+	if mode == "" {
+		mode = config.ModeBackground
+	}
+
+	status := CollectorStatus{
+		Name: c.name,
+		Config: CollectorConfigStatus{
+			ScrapeTime: c.scrapeTime.String(),
+			Mode:       mode,
+			Filter:     c.collectorConfig.Filter,
+			MaxObjects: c.collectorConfig.MaxObjects,
+			GraphURL:   c.collectorConfig.GetGraphBaseURL(),
+		},
+		Running: c.running.Load(),
+		Tenants: []TenantStatus{},
+	}
+	if c.collectorConfig.CacheTTL > 0 {
+		status.Config.CacheTTL = c.collectorConfig.CacheTTL.String()
+	}
+
+	// Merge everything known per tenant
+	tenants := map[string]*TenantStatus{}
+	tenantStatus := func(tenantID string) *TenantStatus {
+		if _, exists := tenants[tenantID]; !exists {
+			tenants[tenantID] = &TenantStatus{TenantID: tenantID}
+		}
+		return tenants[tenantID]
+	}
+
+	for _, stats := range c.LastStats() {
+		tenant := tenantStatus(stats.TenantID)
+		started := stats.Started
+		tenant.LastRun = &started
+		tenant.DurationSeconds = stats.Duration.Seconds()
+		tenant.Pages = stats.Pages
+		tenant.Objects = stats.Objects
+	}
+
+	for tenantID, info := range c.cacheInfos() {
+		tenant := tenantStatus(tenantID)
+		updated := info.updated
+		tenant.CacheUpdated = &updated
+		tenant.CacheExpired = c.isCacheExpired(tenantID)
+	}
+
+	for tenantID, lastError := range c.lastErrorsByTenant() {
+		lastError := lastError
+		tenantStatus(tenantID).LastError = &lastError
+	}
+
+	for _, tenant := range tenants {
+		status.Tenants = append(status.Tenants, *tenant)
+	}
+	sort.Slice(status.Tenants, func(i, j int) bool {
+		return status.Tenants[i].TenantID < status.Tenants[j].TenantID
+	})
+
+	return status
 }
@@ -0,0 +1,288 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	mgraph "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/microsoftgraph/msgraph-sdk-go/oauth2permissiongrants"
+	"github.com/microsoftgraph/msgraph-sdk-go/serviceprincipals"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/entra-exporter/config"
+)
+
+// microsoftGraphAppID is the application ID of the Microsoft Graph resource
+const microsoftGraphAppID = "00000003-0000-0000-c000-000000000000"
+
+// consentKey groups the service principals holding a permission by how it was granted
+type consentKey struct {
+	permission     string
+	permissionType string
+}
+
+// consentStats counts the service principals holding high privilege permissions
+type consentStats struct {
+	servicePrincipals int
+	byPermission      map[consentKey]int
+}
+
+// ConsentsCollector collects metrics about service principals holding high
+// privilege Microsoft Graph permissions
+type ConsentsCollector struct {
+	*BaseCollector
+
+	// Configured permissions by lower case name, Graph compares them case insensitively
+	permissions map[string]string
+
+	// Consent counts per tenant
+	statsLock sync.RWMutex
+	stats     map[string]consentStats
+
+	// Metrics
+	servicePrincipalsTotal *gaugeVec
+	byPermission           *gaugeVec
+}
+
+// NewConsentsCollector creates a new ConsentsCollector
+func NewConsentsCollector(ctx context.Context, config *config.Config, logger *logrus.Entry) *ConsentsCollector {
+	permissions := map[string]string{}
+	for _, permission := range config.Collector.Consents.GetPermissions() {
+		permissions[strings.ToLower(permission)] = permission
+	}
+
+	c := &ConsentsCollector{
+		BaseCollector: NewBaseCollector("consents", config.Collector.Consents.CollectorConfig, config, logger),
+		permissions:   permissions,
+		stats:         map[string]consentStats{},
+		servicePrincipalsTotal: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_serviceprincipals_high_privilege_total",
+				Help: "Number of service principals granted at least one high privilege Microsoft Graph permission",
+			},
+			[]string{"tenant_id"},
+		),
+		byPermission: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_serviceprincipals_by_high_privilege_permission_total",
+				Help: "Number of service principals granted a high privilege Microsoft Graph permission by permission type",
+			},
+			[]string{"tenant_id", "permission", "permission_type"},
+		),
+	}
+
+	// Start background collection
+	c.StartCacheInvalidator(ctx, c.collect)
+
+	return c
+}
+
+// Describe implements prometheus.Collector
+func (c *ConsentsCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.BaseCollector.Describe(ch)
+	c.servicePrincipalsTotal.Describe(ch)
+	c.byPermission.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *ConsentsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.BaseCollector.Collect(ch)
+
+	c.statsLock.RLock()
+	defer c.statsLock.RUnlock()
+
+	// Rebuild the metrics from the cache so revoked permissions disappear
+	servicePrincipalsTotal := c.servicePrincipalsTotal.vec()
+	byPermission := c.byPermission.vec()
+
+	for tenantID, stats := range c.stats {
+		if c.isCacheExpired(tenantID) {
+			continue
+		}
+
+		servicePrincipalsTotal.WithLabelValues(tenantID).Set(float64(stats.servicePrincipals))
+		for key, servicePrincipals := range stats.byPermission {
+			byPermission.WithLabelValues(tenantID, key.permission, key.permissionType).Set(float64(servicePrincipals))
+		}
+	}
+
+	c.collectCached(ch, servicePrincipalsTotal, byPermission)
+}
+
+// collect cross-references the delegated and application permissions granted
+// on Microsoft Graph with the configured permissions
+func (c *ConsentsCollector) collect(ctx context.Context) {
+	for _, tenantID := range c.GetTenants() {
+		if ctx.Err() != nil {
+			c.logger.Debugf("Stopping consents collection: %v", ctx.Err())
+			return
+		}
+
+		if !c.tenantAllowed(tenantID) {
+			continue
+		}
+
+		start := time.Now()
+		c.sampledDebugf("Collecting high privilege consents for tenant %s", tenantID)
+
+		client, err := c.GetGraphClient(ctx, tenantID)
+		if err != nil {
+			c.logger.Errorf("Failed to get Graph client for tenant %s: %v", tenantID, err)
+			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			c.tenantFailed(tenantID, err)
+			continue
+		}
+
+		// Service principal IDs by granted permission
+		holders := map[consentKey]map[string]struct{}{}
+		grant := func(permission, permissionType, servicePrincipalID string) {
+			name, exists := c.permissions[strings.ToLower(permission)]
+			if !exists {
+				return
+			}
+			key := consentKey{permission: name, permissionType: permissionType}
+			if holders[key] == nil {
+				holders[key] = map[string]struct{}{}
+			}
+			holders[key][servicePrincipalID] = struct{}{}
+		}
+
+		pages, objects, err := c.collectGrants(ctx, client, tenantID, grant)
+		if ctx.Err() != nil {
+			c.logger.Debugf("Consents collection for tenant %s cancelled", tenantID)
+			return
+		}
+		// Partial grants would look like revoked permissions, keep the previous counts
+		if err != nil {
+			c.logger.WithFields(graphErrorFields(err)).Errorf("Failed to get permission grants for tenant %s: %v", tenantID, graphErrorMessage(err))
+			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			c.tenantFailed(tenantID, err)
+			continue
+		}
+
+		stats := consentStats{byPermission: map[consentKey]int{}}
+		servicePrincipals := map[string]struct{}{}
+		for key, ids := range holders {
+			stats.byPermission[key] = len(ids)
+			for id := range ids {
+				servicePrincipals[id] = struct{}{}
+			}
+		}
+		stats.servicePrincipals = len(servicePrincipals)
+
+		c.statsLock.Lock()
+		c.stats[tenantID] = stats
+		c.statsLock.Unlock()
+		c.cacheUpdated(tenantID, objects)
+		c.recordStats(tenantID, start, pages, objects)
+		c.tenantSucceeded(tenantID)
+
+		// Update scrape metrics
+		duration := time.Since(start).Seconds()
+		c.observeDuration(tenantID, duration)
+		c.lastScrapeTime.WithLabelValues(tenantID).Set(float64(time.Now().Unix()))
+		c.sampledDebugf("Completed consents collection for tenant %s in %.2f seconds: %d service principals with high privilege permissions", tenantID, duration, stats.servicePrincipals)
+	}
+}
+
+// collectGrants passes every permission granted on Microsoft Graph to grant
+// and returns the number of pages and grants read
+func (c *ConsentsCollector) collectGrants(ctx context.Context, client *mgraph.GraphServiceClient, tenantID string, grant func(permission, permissionType, servicePrincipalID string)) (int, int, error) {
+	// The application permissions are app roles of the Microsoft Graph
+	// service principal, their assignments only carry the role ID
+	filter := fmt.Sprintf("appId eq '%s'", microsoftGraphAppID)
+	result, err := client.ServicePrincipals().Get(ctx, &serviceprincipals.ServicePrincipalsRequestBuilderGetRequestConfiguration{
+		QueryParameters: &serviceprincipals.ServicePrincipalsRequestBuilderGetQueryParameters{
+			Filter: &filter,
+			Select: []string{"id", "appRoles"},
+		},
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(result.GetValue()) == 0 || result.GetValue()[0].GetId() == nil {
+		return 0, 0, fmt.Errorf("no Microsoft Graph service principal in tenant %s", tenantID)
+	}
+	graphSP := result.GetValue()[0]
+	graphSPID := *graphSP.GetId()
+
+	appRoles := map[string]string{}
+	for _, role := range graphSP.GetAppRoles() {
+		if role.GetId() != nil && role.GetValue() != nil {
+			appRoles[role.GetId().String()] = *role.GetValue()
+		}
+	}
+
+	pages := 1
+	objects := 0
+
+	// Application permissions
+	assignmentConfig := serviceprincipals.ItemAppRoleAssignedToRequestBuilderGetRequestConfiguration{
+		QueryParameters: &serviceprincipals.ItemAppRoleAssignedToRequestBuilderGetQueryParameters{
+			Select: []string{"id", "appRoleId", "principalId", "principalType"},
+		},
+	}
+	assignmentPages, err := readPages(ctx, c.BaseCollector, tenantID, "appRoleAssignedTo", func(ctx context.Context, nextLink string) ([]models.AppRoleAssignmentable, *string, error) {
+		builder := client.ServicePrincipals().ByServicePrincipalId(graphSPID).AppRoleAssignedTo()
+		requestConfig := &assignmentConfig
+		if nextLink != "" {
+			builder = builder.WithUrl(nextLink)
+			requestConfig = nil
+		}
+
+		result, err := builder.Get(ctx, requestConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		return result.GetValue(), result.GetOdataNextLink(), nil
+	}, func(assignment models.AppRoleAssignmentable) {
+		objects++
+		if stringValue(assignment.GetPrincipalType(), "") != "ServicePrincipal" || assignment.GetAppRoleId() == nil || assignment.GetPrincipalId() == nil {
+			return
+		}
+		if permission, exists := appRoles[assignment.GetAppRoleId().String()]; exists {
+			grant(permission, "application", assignment.GetPrincipalId().String())
+		}
+	})
+	pages += assignmentPages
+	if err != nil {
+		return pages, objects, err
+	}
+
+	// Delegated permissions, granted for all users or single users alike
+	grantFilter := fmt.Sprintf("resourceId eq '%s'", graphSPID)
+	grantConfig := oauth2permissiongrants.Oauth2PermissionGrantsRequestBuilderGetRequestConfiguration{
+		QueryParameters: &oauth2permissiongrants.Oauth2PermissionGrantsRequestBuilderGetQueryParameters{
+			Filter: &grantFilter,
+			Select: []string{"id", "clientId", "scope"},
+		},
+	}
+	grantPages, err := readPages(ctx, c.BaseCollector, tenantID, "oauth2PermissionGrants", func(ctx context.Context, nextLink string) ([]models.OAuth2PermissionGrantable, *string, error) {
+		builder := client.Oauth2PermissionGrants()
+		requestConfig := &grantConfig
+		if nextLink != "" {
+			builder = builder.WithUrl(nextLink)
+			requestConfig = nil
+		}
+
+		result, err := builder.Get(ctx, requestConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		return result.GetValue(), result.GetOdataNextLink(), nil
+	}, func(permissionGrant models.OAuth2PermissionGrantable) {
+		objects++
+		if permissionGrant.GetClientId() == nil {
+			return
+		}
+		for _, scope := range strings.Fields(stringValue(permissionGrant.GetScope(), "")) {
+			grant(scope, "delegated", *permissionGrant.GetClientId())
+		}
+	})
+	pages += grantPages
+	return pages, objects, err
+}
@@ -0,0 +1,346 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmespath/go-jmespath"
+	abstractions "github.com/microsoft/kiota-abstractions-go"
+	mgraph "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/models/odataerrors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/entra-exporter/config"
+)
+
+// customMetric is a configured metric with its compiled expressions
+type customMetric struct {
+	config.CustomMetricConfig
+	labelNames  []string
+	labelValues []*jmespath.JMESPath
+	value       *jmespath.JMESPath
+	gauge       *gaugeVec
+}
+
+// customQuery is a configured query with its metrics
+type customQuery struct {
+	config.CustomQueryConfig
+	metrics []*customMetric
+}
+
+// customSample is the value of a metric for a set of label values
+type customSample struct {
+	labels []string
+	value  float64
+}
+
+// customPage is a page of a Graph collection read as plain JSON
+type customPage struct {
+	Value    []any  `json:"value"`
+	NextLink string `json:"@odata.nextLink"`
+}
+
+// CustomCollector turns configured Graph GET queries into metrics
+type CustomCollector struct {
+	*BaseCollector
+
+	queries []*customQuery
+
+	// Samples by metric name and label values per tenant
+	samplesLock sync.RWMutex
+	samples     map[string]map[string]map[string]customSample
+}
+
+// NewCustomCollector creates a new CustomCollector
+func NewCustomCollector(ctx context.Context, config *config.Config, logger *logrus.Entry) *CustomCollector {
+	c := &CustomCollector{
+		BaseCollector: NewBaseCollector("custom", config.Collector.Custom.CollectorConfig, config, logger),
+		samples:       map[string]map[string]map[string]customSample{},
+	}
+
+	// Expressions were validated with the config
+	for _, queryConfig := range config.Collector.Custom.Queries {
+		query := &customQuery{CustomQueryConfig: queryConfig}
+		for _, metricConfig := range queryConfig.Metrics {
+			metric := &customMetric{CustomMetricConfig: metricConfig}
+			for label := range metricConfig.Labels {
+				metric.labelNames = append(metric.labelNames, label)
+			}
+			sort.Strings(metric.labelNames)
+			for _, label := range metric.labelNames {
+				metric.labelValues = append(metric.labelValues, jmespath.MustCompile(metricConfig.Labels[label]))
+			}
+			if metricConfig.Value != "" {
+				metric.value = jmespath.MustCompile(metricConfig.Value)
+			}
+
+			help := metricConfig.Help
+			if help == "" {
+				help = fmt.Sprintf("Custom metric of the Graph query %s", queryConfig.Path)
+			}
+			metric.gauge = newGaugeVec(
+				prometheus.GaugeOpts{
+					Name: metricConfig.Name,
+					Help: help,
+				},
+				append([]string{"tenant_id"}, metric.labelNames...),
+			)
+			query.metrics = append(query.metrics, metric)
+		}
+		c.queries = append(c.queries, query)
+	}
+
+	// Start background collection
+	c.StartCacheInvalidator(ctx, c.collect)
+
+	return c
+}
+
+// Describe implements prometheus.Collector
+func (c *CustomCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.BaseCollector.Describe(ch)
+	for _, query := range c.queries {
+		for _, metric := range query.metrics {
+			metric.gauge.Describe(ch)
+		}
+	}
+}
+
+// Collect implements prometheus.Collector
+func (c *CustomCollector) Collect(ch chan<- prometheus.Metric) {
+	c.BaseCollector.Collect(ch)
+
+	c.samplesLock.RLock()
+	defer c.samplesLock.RUnlock()
+
+	var gauges []prometheus.Collector
+	for _, query := range c.queries {
+		for _, metric := range query.metrics {
+			// Rebuild the metrics from the cache so removed objects disappear
+			gauge := metric.gauge.vec()
+			gauges = append(gauges, gauge)
+
+			for tenantID, metrics := range c.samples {
+				if c.isCacheExpired(tenantID) {
+					continue
+				}
+				for _, sample := range metrics[metric.Name] {
+					gauge.WithLabelValues(append([]string{tenantID}, sample.labels...)...).Set(sample.value)
+				}
+			}
+		}
+	}
+
+	c.collectCached(ch, gauges...)
+}
+
+// collect runs the queries
+func (c *CustomCollector) collect(ctx context.Context) {
+	for _, tenantID := range c.GetTenants() {
+		if ctx.Err() != nil {
+			c.logger.Debugf("Stopping custom collection: %v", ctx.Err())
+			return
+		}
+
+		if !c.tenantAllowed(tenantID) {
+			continue
+		}
+
+		start := time.Now()
+		c.sampledDebugf("Collecting custom queries for tenant %s", tenantID)
+
+		client, err := c.GetGraphClient(ctx, tenantID)
+		if err != nil {
+			c.logger.Errorf("Failed to get Graph client for tenant %s: %v", tenantID, err)
+			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			c.tenantFailed(tenantID, err)
+			continue
+		}
+
+		// A failing query only drops its own series
+		samples := map[string]map[string]customSample{}
+		var collectErr error
+		pages := 0
+		objects := 0
+		for _, query := range c.queries {
+			querySamples := map[string]map[string]customSample{}
+			for _, metric := range query.metrics {
+				querySamples[metric.Name] = map[string]customSample{}
+			}
+
+			queryPages, err := readPages(ctx, c.BaseCollector, tenantID, query.Path, c.fetchPage(client, query), func(object any) {
+				objects++
+				for _, metric := range query.metrics {
+					metric.observe(querySamples[metric.Name], object)
+				}
+			})
+			pages += queryPages
+			if err != nil {
+				if ctx.Err() != nil {
+					c.logger.Debugf("Custom collection for tenant %s cancelled", tenantID)
+					return
+				}
+				c.logger.WithFields(graphErrorFields(err)).Errorf("Failed to run custom query %s for tenant %s: %v", query.Path, tenantID, graphErrorMessage(err))
+				c.scrapeErrors.WithLabelValues(tenantID).Inc()
+				collectErr = err
+				continue
+			}
+
+			for name, metricSamples := range querySamples {
+				samples[name] = metricSamples
+			}
+		}
+
+		c.samplesLock.Lock()
+		c.samples[tenantID] = samples
+		c.samplesLock.Unlock()
+		c.cacheUpdated(tenantID, objects)
+		c.recordStats(tenantID, start, pages, objects)
+
+		if collectErr != nil {
+			c.tenantFailed(tenantID, collectErr)
+		} else {
+			c.tenantSucceeded(tenantID)
+		}
+
+		// Update scrape metrics
+		duration := time.Since(start).Seconds()
+		c.observeDuration(tenantID, duration)
+		c.lastScrapeTime.WithLabelValues(tenantID).Set(float64(time.Now().Unix()))
+		c.sampledDebugf("Completed custom collection for tenant %s in %.2f seconds: %d objects", tenantID, duration, objects)
+	}
+}
+
+// fetchPage returns the page function of a query. The response is read as
+// plain JSON since the queried resource type is not known; a response
+// without a value collection is a single object.
+func (c *CustomCollector) fetchPage(client *mgraph.GraphServiceClient, query *customQuery) fetchPageFunc[any] {
+	return func(ctx context.Context, nextLink string) ([]any, *string, error) {
+		rawURL := nextLink
+		if rawURL == "" {
+			params := url.Values{}
+			if query.Filter != "" {
+				params.Set("$filter", query.Filter)
+			}
+			if len(query.Select) > 0 {
+				params.Set("$select", strings.Join(query.Select, ","))
+			}
+			rawURL = client.GetAdapter().GetBaseUrl() + query.Path
+			if len(params) > 0 {
+				rawURL += "?" + params.Encode()
+			}
+		}
+		uri, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		requestInfo := abstractions.NewRequestInformation()
+		requestInfo.Method = abstractions.GET
+		requestInfo.SetUri(*uri)
+		requestInfo.Headers.TryAdd("Accept", "application/json")
+
+		response, err := client.GetAdapter().SendPrimitive(ctx, requestInfo, "[]byte", abstractions.ErrorMappings{
+			"XXX": odataerrors.CreateODataErrorFromDiscriminatorValue,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		body, _ := response.([]byte)
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return nil, nil, fmt.Errorf("invalid response: %w", err)
+		}
+		if _, exists := fields["value"]; !exists {
+			var object any
+			if err := json.Unmarshal(body, &object); err != nil {
+				return nil, nil, fmt.Errorf("invalid response: %w", err)
+			}
+			return []any{object}, nil, nil
+		}
+
+		var page customPage
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, nil, fmt.Errorf("invalid response: %w", err)
+		}
+		return page.Value, &page.NextLink, nil
+	}
+}
+
+// observe adds an object to the samples of the metric. Objects without a
+// numeric value are skipped.
+func (m *customMetric) observe(samples map[string]customSample, object any) {
+	value := 1.0
+	if m.value != nil {
+		result, err := m.value.Search(object)
+		if err != nil {
+			return
+		}
+		var ok bool
+		if value, ok = customValue(result); !ok {
+			return
+		}
+	}
+
+	labels := make([]string, len(m.labelValues))
+	for i, expression := range m.labelValues {
+		result, err := expression.Search(object)
+		if err == nil {
+			labels[i] = customLabel(result)
+		}
+	}
+
+	key := strings.Join(labels, "\xff")
+	if m.Aggregate == "sum" {
+		value += samples[key].value
+	}
+	samples[key] = customSample{labels: labels, value: value}
+}
+
+// customValue converts an expression result to a sample value
+func customValue(result any) (float64, bool) {
+	switch v := result.(type) {
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		if value, err := strconv.ParseFloat(v, 64); err == nil {
+			return value, true
+		}
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return float64(t.Unix()), true
+		}
+	case []any:
+		return float64(len(v)), true
+	}
+	return 0, false
+}
+
+// customLabel converts an expression result to a label value
+func customLabel(result any) string {
+	switch v := result.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+}
@@ -0,0 +1,219 @@
+package collector
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/directory"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/entra-exporter/config"
+)
+
+// deletedItemsRetention is how long deleted users and groups can be restored
+// before Entra ID purges them
+const deletedItemsRetention = 30 * 24 * time.Hour
+
+// DeletedItemsCollector collects metrics about the deleted users and groups
+// in the recycle bin relative to their purge
+type DeletedItemsCollector struct {
+	*BaseCollector
+
+	// Deletion times by object type per tenant
+	deletionsLock sync.RWMutex
+	deletions     map[string]map[string][]time.Time
+
+	// Metrics
+	deletedTotal *gaugeVec
+	purgingTotal *gaugeVec
+}
+
+// NewDeletedItemsCollector creates a new DeletedItemsCollector
+func NewDeletedItemsCollector(ctx context.Context, config *config.Config, logger *logrus.Entry) *DeletedItemsCollector {
+	c := &DeletedItemsCollector{
+		BaseCollector: NewBaseCollector("deletedItems", config.Collector.DeletedItems.CollectorConfig, config, logger),
+		deletions:     map[string]map[string][]time.Time{},
+		deletedTotal: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_deleted_objects_total",
+				Help: "Number of deleted objects which can still be restored",
+			},
+			[]string{"tenant_id", "object_type"},
+		),
+		purgingTotal: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_deleted_objects_purging_total",
+				Help: "Number of deleted objects which are permanently purged within the duration",
+			},
+			[]string{"tenant_id", "object_type", "within"},
+		),
+	}
+
+	// Start background collection
+	c.StartCacheInvalidator(ctx, c.collect)
+
+	return c
+}
+
+// Describe implements prometheus.Collector
+func (c *DeletedItemsCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.BaseCollector.Describe(ch)
+	c.deletedTotal.Describe(ch)
+	c.purgingTotal.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *DeletedItemsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.BaseCollector.Collect(ch)
+
+	c.deletionsLock.RLock()
+	defer c.deletionsLock.RUnlock()
+
+	// Rebuild the metrics from the cache
+	deletedTotal := c.deletedTotal.vec()
+	purgingTotal := c.purgingTotal.vec()
+
+	// The purge is relative to the scrape, so the counts move between collections
+	now := time.Now()
+	purgeWithin := c.config.Collector.DeletedItems.GetPurgeWithin()
+
+	for tenantID, deletions := range c.deletions {
+		if c.isCacheExpired(tenantID) {
+			continue
+		}
+
+		for objectType, deletedTimes := range deletions {
+			purging := make([]int, len(purgeWithin))
+			restorable := 0
+			for _, deleted := range deletedTimes {
+				remaining := deleted.Add(deletedItemsRetention).Sub(now)
+				// Already purged since the collection
+				if remaining <= 0 {
+					continue
+				}
+				restorable++
+				for i, within := range purgeWithin {
+					if remaining <= within {
+						purging[i]++
+					}
+				}
+			}
+
+			deletedTotal.WithLabelValues(tenantID, objectType).Set(float64(restorable))
+			for i, within := range purgeWithin {
+				purgingTotal.WithLabelValues(tenantID, objectType, model.Duration(within).String()).Set(float64(purging[i]))
+			}
+		}
+	}
+
+	c.collectCached(ch, deletedTotal, purgingTotal)
+}
+
+// collect gets the deletion times of the deleted users and groups
+func (c *DeletedItemsCollector) collect(ctx context.Context) {
+	for _, tenantID := range c.GetTenants() {
+		if ctx.Err() != nil {
+			c.logger.Debugf("Stopping deleted items collection: %v", ctx.Err())
+			return
+		}
+
+		if !c.tenantAllowed(tenantID) {
+			continue
+		}
+
+		start := time.Now()
+		c.sampledDebugf("Collecting deleted items for tenant %s", tenantID)
+
+		client, err := c.GetGraphClient(ctx, tenantID)
+		if err != nil {
+			c.logger.Errorf("Failed to get Graph client for tenant %s: %v", tenantID, err)
+			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			c.tenantFailed(tenantID, err)
+			continue
+		}
+
+		deletions := map[string][]time.Time{"user": nil, "group": nil}
+		addDeletion := func(objectType string, deleted *time.Time) {
+			if deleted != nil {
+				deletions[objectType] = append(deletions[objectType], *deleted)
+			}
+		}
+
+		userConfig := directory.DeletedItemsGraphUserRequestBuilderGetRequestConfiguration{
+			QueryParameters: &directory.DeletedItemsGraphUserRequestBuilderGetQueryParameters{
+				Select: []string{"id", "deletedDateTime"},
+			},
+		}
+		userPages, err := readPages(ctx, c.BaseCollector, tenantID, "deletedItems/user", func(ctx context.Context, nextLink string) ([]models.Userable, *string, error) {
+			builder := client.Directory().DeletedItems().GraphUser()
+			requestConfig := &userConfig
+			if nextLink != "" {
+				builder = builder.WithUrl(nextLink)
+				requestConfig = nil
+			}
+
+			result, err := builder.Get(ctx, requestConfig)
+			if err != nil {
+				return nil, nil, err
+			}
+			return result.GetValue(), result.GetOdataNextLink(), nil
+		}, func(user models.Userable) {
+			addDeletion("user", user.GetDeletedDateTime())
+		})
+
+		groupPages := 0
+		if err == nil {
+			groupConfig := directory.DeletedItemsGraphGroupRequestBuilderGetRequestConfiguration{
+				QueryParameters: &directory.DeletedItemsGraphGroupRequestBuilderGetQueryParameters{
+					Select: []string{"id", "deletedDateTime"},
+				},
+			}
+			groupPages, err = readPages(ctx, c.BaseCollector, tenantID, "deletedItems/group", func(ctx context.Context, nextLink string) ([]models.Groupable, *string, error) {
+				builder := client.Directory().DeletedItems().GraphGroup()
+				requestConfig := &groupConfig
+				if nextLink != "" {
+					builder = builder.WithUrl(nextLink)
+					requestConfig = nil
+				}
+
+				result, err := builder.Get(ctx, requestConfig)
+				if err != nil {
+					return nil, nil, err
+				}
+				return result.GetValue(), result.GetOdataNextLink(), nil
+			}, func(group models.Groupable) {
+				addDeletion("group", group.GetDeletedDateTime())
+			})
+		}
+
+		if ctx.Err() != nil {
+			c.logger.Debugf("Deleted items collection for tenant %s cancelled", tenantID)
+			return
+		}
+
+		// Partial counts would look like restored objects, keep the previous ones
+		if err != nil {
+			c.logger.WithFields(graphErrorFields(err)).Errorf("Failed to get deleted items for tenant %s: %v", tenantID, graphErrorMessage(err))
+			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			c.tenantFailed(tenantID, err)
+			continue
+		}
+
+		objects := len(deletions["user"]) + len(deletions["group"])
+		c.deletionsLock.Lock()
+		c.deletions[tenantID] = deletions
+		c.deletionsLock.Unlock()
+		c.cacheUpdated(tenantID, objects)
+		c.recordStats(tenantID, start, userPages+groupPages, objects)
+		c.tenantSucceeded(tenantID)
+
+		// Update scrape metrics
+		duration := time.Since(start).Seconds()
+		c.observeDuration(tenantID, duration)
+		c.lastScrapeTime.WithLabelValues(tenantID).Set(float64(time.Now().Unix()))
+		c.sampledDebugf("Completed deleted items collection for tenant %s in %.2f seconds: %d deleted objects", tenantID, duration, objects)
+	}
+}
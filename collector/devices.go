@@ -2,6 +2,8 @@ package collector
 
 import (
 	"context"
+	"iter"
+	"strconv"
 	"sync"
 	"time"
 
@@ -12,34 +14,143 @@ import (
 	"github.com/your-username/entra-exporter/config"
 )
 
+// cachedDevice holds the device properties used for metric labels
+type cachedDevice struct {
+	id                     string
+	displayName            string
+	deviceCategory         string
+	operatingSystem        string
+	operatingSystemVersion string
+	trustType              string
+	enrollmentType         string
+	accountEnabled         bool
+	managementType         string
+	registrationDateTime   string
+	onPremisesSynced       bool
+	ownership              string
+	profileType            string
+	isManaged              bool
+	isCompliant            bool
+	isRooted               bool
+}
+
+// newCachedDevice maps a Graph device into the slim cache representation
+func newCachedDevice(device models.Deviceable) cachedDevice {
+	registrationDateTime := "unknown"
+	if device.GetRegistrationDateTime() != nil {
+		registrationDateTime = device.GetRegistrationDateTime().Format(time.RFC3339)
+	}
+
+	return cachedDevice{
+		id:                     stringValue(device.GetId(), ""),
+		displayName:            stringValue(device.GetDisplayName(), ""),
+		deviceCategory:         stringValue(device.GetDeviceCategory(), "unknown"),
+		operatingSystem:        stringValue(device.GetOperatingSystem(), "unknown"),
+		operatingSystemVersion: stringValue(device.GetOperatingSystemVersion(), "unknown"),
+		trustType:              stringValue(device.GetTrustType(), "unknown"),
+		enrollmentType:         stringValue(device.GetEnrollmentType(), "unknown"),
+		accountEnabled:         boolValue(device.GetAccountEnabled()),
+		managementType:         stringValue(device.GetManagementType(), "unknown"),
+		registrationDateTime:   registrationDateTime,
+		onPremisesSynced:       boolValue(device.GetOnPremisesSyncEnabled()),
+		ownership:              stringValue(device.GetDeviceOwnership(), "unknown"),
+		profileType:            stringValue(device.GetProfileType(), "unknown"),
+		isManaged:              boolValue(device.GetIsManaged()),
+		isCompliant:            boolValue(device.GetIsCompliant()),
+		isRooted:               boolValue(device.GetIsRooted()),
+	}
+}
+
 // DevicesCollector collects Entra ID device metrics
 type DevicesCollector struct {
 	*BaseCollector
 
 	// Devices cache
 	devicesLock sync.RWMutex
-	devicesList map[string][]models.Deviceable
+	devicesList map[string][]cachedDevice
 
 	// Metrics
-	devicesTotal *prometheus.GaugeVec
-	devicesInfo  *prometheus.GaugeVec
+	devicesTotal            *gaugeVec
+	devicesByOSTotal        *countVec
+	devicesByTrustTypeTotal *countVec
+	devicesBySyncSource     *countVec
+	devicesByOwnership      *countVec
+	devicesByProfileType    *countVec
+	devicesCompliantTotal   *gaugeVec
+	devicesManagedTotal     *gaugeVec
+	devicesRootedTotal      *gaugeVec
+	devicesInfo             *infoVec
 }
 
 // NewDevicesCollector creates a new DevicesCollector
-func NewDevicesCollector(config *config.Config, logger *logrus.Entry) *DevicesCollector {
-	scrapeTime := config.Collector.Devices.ScrapeTime
-
+func NewDevicesCollector(ctx context.Context, config *config.Config, logger *logrus.Entry) *DevicesCollector {
 	c := &DevicesCollector{
-		BaseCollector: NewBaseCollector("devices", scrapeTime, config, logger),
-		devicesList:     map[string][]models.Deviceable{},
-		devicesTotal: prometheus.NewGaugeVec(
+		BaseCollector: NewBaseCollector("devices", config.Collector.Devices, config, logger),
+		devicesList:     map[string][]cachedDevice{},
+		devicesTotal: newGaugeVec(
 			prometheus.GaugeOpts{
 				Name: "entraid_devices_total",
 				Help: "Total number of devices in Entra ID",
 			},
 			[]string{"tenant_id"},
 		),
-		devicesInfo: prometheus.NewGaugeVec(
+		devicesByOSTotal: newCountVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_devices_by_os_total",
+				Help: "Number of devices in Entra ID by operating system",
+			},
+			[]string{"tenant_id", "operating_system"},
+		),
+		devicesByTrustTypeTotal: newCountVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_devices_by_trust_type_total",
+				Help: "Number of devices in Entra ID by trust type",
+			},
+			[]string{"tenant_id", "trust_type"},
+		),
+		devicesBySyncSource: newCountVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_devices_by_sync_source_total",
+				Help: "Number of devices in Entra ID by source, cloud only or synchronized from on-premises",
+			},
+			[]string{"tenant_id", "source"},
+		),
+		devicesByOwnership: newCountVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_devices_by_ownership_total",
+				Help: "Number of devices in Entra ID by ownership, Company or Personal",
+			},
+			[]string{"tenant_id", "ownership"},
+		),
+		devicesByProfileType: newCountVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_devices_by_profile_type_total",
+				Help: "Number of devices in Entra ID by profile type",
+			},
+			[]string{"tenant_id", "profile_type"},
+		),
+		devicesCompliantTotal: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_devices_compliant_total",
+				Help: "Number of devices in Entra ID marked as compliant by the device management",
+			},
+			[]string{"tenant_id"},
+		),
+		devicesManagedTotal: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_devices_managed_total",
+				Help: "Number of devices in Entra ID managed by a device management app such as Intune",
+			},
+			[]string{"tenant_id"},
+		),
+		devicesRootedTotal: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_devices_rooted_total",
+				Help: "Number of devices in Entra ID reported as rooted or jailbroken",
+			},
+			[]string{"tenant_id"},
+		),
+		devicesInfo: newInfoVec(
 			prometheus.GaugeOpts{
 				Name: "entraid_devices_info",
 				Help: "Information about devices in Entra ID",
@@ -56,13 +167,17 @@ func NewDevicesCollector(config *config.Config, logger *logrus.Entry) *DevicesCo
 				"account_enabled",
 				"management_type",
 				"ownership",
+				"profile_type",
+				"is_managed",
+				"is_compliant",
 				"registration_datetime",
 			},
+			&config.Collector.Devices,
 		),
 	}
 
 	// Start background collection
-	c.StartCacheInvalidator(c.collect)
+	c.StartCacheInvalidator(ctx, c.collect)
 
 	return c
 }
@@ -71,6 +186,14 @@ func NewDevicesCollector(config *config.Config, logger *logrus.Entry) *DevicesCo
 func (c *DevicesCollector) Describe(ch chan<- *prometheus.Desc) {
 	c.BaseCollector.Describe(ch)
 	c.devicesTotal.Describe(ch)
+	c.devicesByOSTotal.Describe(ch)
+	c.devicesByTrustTypeTotal.Describe(ch)
+	c.devicesBySyncSource.Describe(ch)
+	c.devicesByOwnership.Describe(ch)
+	c.devicesByProfileType.Describe(ch)
+	c.devicesCompliantTotal.Describe(ch)
+	c.devicesManagedTotal.Describe(ch)
+	c.devicesRootedTotal.Describe(ch)
 	c.devicesInfo.Describe(ch)
 }
 
@@ -81,96 +204,111 @@ func (c *DevicesCollector) Collect(ch chan<- prometheus.Metric) {
 	c.devicesLock.RLock()
 	defer c.devicesLock.RUnlock()
 
+	// The metrics are rebuilt from the cache by every Collect, so removed
+	// objects disappear and concurrent Gathers don't share any state
+	devicesTotal := c.devicesTotal.vec()
+	devicesCompliantTotal := c.devicesCompliantTotal.vec()
+	devicesManagedTotal := c.devicesManagedTotal.vec()
+	devicesRootedTotal := c.devicesRootedTotal.vec()
+	devicesInfo := c.devicesInfo.vec()
+	byOS := c.devicesByOSTotal.counts()
+	byTrustType := c.devicesByTrustTypeTotal.counts()
+	bySyncSource := c.devicesBySyncSource.counts()
+	byOwnership := c.devicesByOwnership.counts()
+	byProfileType := c.devicesByProfileType.counts()
+
 	// Collect devices metrics
 	for tenantID, devicesList := range c.devicesList {
-		c.devicesTotal.WithLabelValues(tenantID).Set(float64(len(devicesList)))
-
-		for _, device := range devicesList {
-			accountEnabled := "false"
-			if device.GetAccountEnabled() != nil && *device.GetAccountEnabled() {
-				accountEnabled = "true"
-			}
-
-			operatingSystem := "unknown"
-			if device.GetOperatingSystem() != nil {
-				operatingSystem = *device.GetOperatingSystem()
-			}
+		if c.isCacheExpired(tenantID) {
+			continue
+		}
 
-			operatingSystemVersion := "unknown"
-			if device.GetOperatingSystemVersion() != nil {
-				operatingSystemVersion = *device.GetOperatingSystemVersion()
-			}
+		devicesTotal.WithLabelValues(tenantID).Set(float64(len(devicesList)))
 
-			trustType := "unknown"
-			if device.GetTrustType() != nil {
-				trustType = *device.GetTrustType()
+		// Breakdowns, so dashboards don't need to count the info series
+		bySyncSource.Add(0, tenantID, syncSource(false))
+		bySyncSource.Add(0, tenantID, syncSource(true))
+		var compliant, managed, rooted int
+		for _, device := range devicesList {
+			byOS.Inc(tenantID, device.operatingSystem)
+			byTrustType.Inc(tenantID, device.trustType)
+			bySyncSource.Inc(tenantID, syncSource(device.onPremisesSynced))
+			byOwnership.Inc(tenantID, device.ownership)
+			byProfileType.Inc(tenantID, device.profileType)
+			if device.isCompliant {
+				compliant++
 			}
-
-			deviceCategory := "unknown"
-			if device.GetDeviceCategory() != nil {
-				deviceCategory = *device.GetDeviceCategory()
+			if device.isManaged {
+				managed++
 			}
-
-			enrollmentType := "unknown"
-			if device.GetEnrollmentType() != nil {
-				enrollmentType = *device.GetEnrollmentType()
-			}
-
-			managementType := "unknown"
-			if device.GetManagementType() != nil {
-				managementType = *device.GetManagementType()
+			if device.isRooted {
+				rooted++
 			}
+		}
+		devicesCompliantTotal.WithLabelValues(tenantID).Set(float64(compliant))
+		devicesManagedTotal.WithLabelValues(tenantID).Set(float64(managed))
+		devicesRootedTotal.WithLabelValues(tenantID).Set(float64(rooted))
 
-			registrationDateTime := "unknown"
-			if device.GetRegistrationDateTime() != nil {
-				registrationDateTime = device.GetRegistrationDateTime().Format(time.RFC3339)
-			}
+		// Per object series are left out in aggregate only mode
+		if c.collectorConfig.IsAggregateOnly(c.config.Metrics.AggregateOnly) {
+			continue
+		}
 
-			c.devicesInfo.WithLabelValues(
+		for _, device := range devicesList {
+			devicesInfo.WithLabelValues(
 				tenantID,
-				*device.GetId(),
-				*device.GetDisplayName(),
-				deviceCategory,
-				operatingSystem,
-				operatingSystemVersion,
-				trustType,
-				enrollmentType,
-				accountEnabled,
-				managementType,
-				"n/a",
-				registrationDateTime,
+				device.id,
+				c.redact(device.displayName),
+				device.deviceCategory,
+				device.operatingSystem,
+				device.operatingSystemVersion,
+				device.trustType,
+				device.enrollmentType,
+				strconv.FormatBool(device.accountEnabled),
+				device.managementType,
+				device.ownership,
+				device.profileType,
+				strconv.FormatBool(device.isManaged),
+				strconv.FormatBool(device.isCompliant),
+				device.registrationDateTime,
 			).Set(1)
 		}
 	}
 
-	c.devicesTotal.Collect(ch)
-	c.devicesInfo.Collect(ch)
+	c.collectCached(ch, devicesTotal, byOS, byTrustType, bySyncSource, byOwnership, byProfileType, devicesCompliantTotal, devicesManagedTotal, devicesRootedTotal, devicesInfo)
 }
 
 // collect gets all devices
-func (c *DevicesCollector) collect() {
-	c.Lock()
-	defer c.Unlock()
-
+func (c *DevicesCollector) collect(ctx context.Context) {
 	tenants := c.GetTenants()
 	c.logger.Debugf("Starting devices collection for %d tenants", len(tenants))
 
 	for _, tenantID := range tenants {
+		if ctx.Err() != nil {
+			c.logger.Debugf("Stopping devices collection: %v", ctx.Err())
+			return
+		}
+
+		if !c.tenantAllowed(tenantID) {
+			continue
+		}
+
 		start := time.Now()
-		c.logger.Debugf("Collecting devices for tenant %s", tenantID)
+		c.sampledDebugf("Collecting devices for tenant %s", tenantID)
 
-		client, err := c.GetGraphClient(tenantID)
+		client, err := c.GetGraphClient(ctx, tenantID)
 		if err != nil {
 			c.logger.Errorf("Failed to get Graph client for tenant %s: %v", tenantID, err)
 			c.logger.Debugf("API request details for devices: tenantID=%s", tenantID)
 			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			c.tenantFailed(tenantID, err)
 			continue
 		}
 
 		// Set up pagination
-		var devicesList []models.Deviceable
+		var devicesList []cachedDevice
 		pageSize := int32(100)
-		c.logger.Debugf("Using page size %d for devices collection", pageSize)
+		c.sampledDebugf("Using page size %d for devices collection", pageSize)
 
 		query := devices.DevicesRequestBuilderGetQueryParameters{
 			Top: &pageSize,
@@ -178,67 +316,164 @@ func (c *DevicesCollector) collect() {
 			Select: []string{
 				"id", "displayName", "operatingSystem", "operatingSystemVersion", 
 				"accountEnabled", "trustType", "enrollmentType", "deviceCategory",
-				"managementType", "registrationDateTime",
+				"managementType", "registrationDateTime", "onPremisesSyncEnabled",
+				"deviceOwnership", "profileType", "isManaged", "isCompliant", "isRooted",
 			},
 		}
 
+		if filter := c.collectorConfig.Filter; filter != "" {
+			c.logger.Debugf("Using filter %q for devices collection", filter)
+			query.Filter = &filter
+		}
+
 		reqConfig := devices.DevicesRequestBuilderGetRequestConfiguration{
 			QueryParameters: &query,
 		}
 
-		// Get the first page
-		c.logger.Debugf("Fetching first page of devices for tenant %s", tenantID)
-		result, err := client.Devices().Get(context.Background(), &reqConfig)
-		if err != nil {
-			c.logger.Errorf("Failed to get devices for tenant %s: %v", tenantID, err)
-			c.logger.Debugf("API request details for devices: tenantID=%s", tenantID)
-			c.scrapeErrors.WithLabelValues(tenantID).Inc()
-			continue
-		}
+		// Fetch the pages in the background, the next page is requested while
+		// the current one is processed
+		pageCtx, cancelPages := context.WithCancel(ctx)
+		pages := prefetchPages(pageCtx, func(ctx context.Context, nextLink string) ([]models.Deviceable, *string, error) {
+			builder := client.Devices()
+			requestConfig := &reqConfig
+			if nextLink != "" {
+				// Fetch the next page using the nextLink directly
+				builder = builder.WithUrl(nextLink)
+				requestConfig = nil
+			}
 
-		// Store the first page of devices
-		if result.GetValue() != nil {
-			pageDevices := result.GetValue()
-			devicesList = append(devicesList, pageDevices...)
-			c.logger.Debugf("Retrieved %d devices in first page for tenant %s", len(pageDevices), tenantID)
-		} else {
-			c.logger.Warnf("No devices returned in API response for tenant %s", tenantID)
-		}
-
-		// Handle pagination manually
-		pageCount := 1
-		for result.GetOdataNextLink() != nil && len(*result.GetOdataNextLink()) > 0 {
-			pageCount++
-			c.logger.Debugf("Fetching page %d of devices for tenant %s", pageCount, tenantID)
-			
-			// Fetch the next page using the nextLink directly
-			var nextReqConfig *devices.DevicesRequestBuilderGetRequestConfiguration
-			result, err = client.Devices().Get(context.Background(), nextReqConfig)
+			result, err := builder.Get(ctx, requestConfig)
 			if err != nil {
-				c.logger.Errorf("Failed to get next page of devices for tenant %s: %v", tenantID, err)
+				return nil, nil, err
+			}
+			return result.GetValue(), result.GetOdataNextLink(), nil
+		})
+
+		failedPage := 0
+		var pageErr error
+		tooManyObjects := false
+		pageCount := 0
+		for page := range pages {
+			pageCount = page.number
+			c.checkSlowPage(tenantID, "devices", page.number, page.duration)
+			if page.err != nil {
+				if ctx.Err() != nil {
+					cancelPages()
+					c.logger.Debugf("Devices collection for tenant %s cancelled on page %d", tenantID, page.number)
+					return
+				}
+				c.logger.WithFields(graphErrorFields(page.err)).Errorf("Failed to get page %d of devices for tenant %s: %v", page.number, tenantID, graphErrorMessage(page.err))
+				c.logger.Debugf("API request details for devices: tenantID=%s", tenantID)
 				c.scrapeErrors.WithLabelValues(tenantID).Inc()
+				failedPage = page.number
+				pageErr = page.err
 				break
 			}
-			
+
 			// Store this page's devices
-			if result.GetValue() != nil {
-				pageDevices := result.GetValue()
-				devicesList = append(devicesList, pageDevices...)
-				c.logger.Debugf("Retrieved %d devices in page %d for tenant %s", len(pageDevices), pageCount, tenantID)
-			} else {
-				c.logger.Warnf("No devices returned in page %d for tenant %s", pageCount, tenantID)
+			if len(page.items) == 0 {
+				c.logger.Warnf("No devices returned in page %d for tenant %s", page.number, tenantID)
+				continue
+			}
+			for _, item := range page.items {
+				devicesList = append(devicesList, newCachedDevice(item))
 			}
+			c.sampledDebugf("Retrieved %d devices in page %d for tenant %s", len(page.items), page.number, tenantID)
+
+			if c.exceedsMaxObjects(tenantID, len(devicesList)) {
+				tooManyObjects = true
+				break
+			}
+		}
+		cancelPages()
+
+		// The pages may stop early without an error when cancelled
+		if ctx.Err() != nil {
+			c.logger.Debugf("Devices collection for tenant %s cancelled", tenantID)
+			return
+		}
+
+		// Keep the previous cache, Graph itself worked fine
+		if tooManyObjects {
+			c.tenantAborted(tenantID)
+			continue
+		}
+
+		// Keep the previous cache if not even the first page could be fetched
+		if failedPage == 1 {
+			c.tenantFailed(tenantID, pageErr)
+			continue
 		}
 
 		// Update the devices list
 		c.devicesLock.Lock()
 		c.devicesList[tenantID] = devicesList
 		c.devicesLock.Unlock()
+		c.cacheUpdated(tenantID, len(devicesList))
+		c.recordStats(tenantID, start, pageCount, len(devicesList))
+
+		if failedPage > 0 {
+			c.tenantFailed(tenantID, pageErr)
+		} else {
+			c.tenantSucceeded(tenantID)
+		}
 
 		// Update scrape metrics
 		duration := time.Since(start).Seconds()
-		c.scrapeDuration.WithLabelValues(tenantID).Observe(duration)
+		c.observeDuration(tenantID, duration)
 		c.lastScrapeTime.WithLabelValues(tenantID).Set(float64(time.Now().Unix()))
-		c.logger.Debugf("Completed devices collection for tenant %s in %.2f seconds: %d devices", tenantID, duration, len(devicesList))
+		c.sampledDebugf("Completed devices collection for tenant %s in %.2f seconds: %d devices", tenantID, duration, len(devicesList))
 	}
 }
+
+// Inventory implements InventoryExporter
+func (c *DevicesCollector) Inventory() ([]string, iter.Seq[[]string]) {
+	// Copy the cache references so slow consumers don't block collections,
+	// cached lists are replaced and never modified
+	c.devicesLock.RLock()
+	inventory := make(map[string][]cachedDevice, len(c.devicesList))
+	for tenantID, devicesList := range c.devicesList {
+		if !c.isCacheExpired(tenantID) {
+			inventory[tenantID] = devicesList
+		}
+	}
+	c.devicesLock.RUnlock()
+
+	header := []string{
+		"tenant_id",
+		"device_id",
+		"display_name",
+		"device_category",
+		"operating_system",
+		"operating_system_version",
+		"trust_type",
+		"enrollment_type",
+		"account_enabled",
+		"management_type",
+		"registration_datetime",
+		"ownership",
+		"profile_type",
+		"is_managed",
+		"is_compliant",
+	}
+
+	return header, inventoryRows(inventory, func(tenantID string, device cachedDevice) []string {
+		return []string{
+			tenantID,
+			device.id,
+			c.redact(device.displayName),
+			device.deviceCategory,
+			device.operatingSystem,
+			device.operatingSystemVersion,
+			device.trustType,
+			device.enrollmentType,
+			strconv.FormatBool(device.accountEnabled),
+			device.managementType,
+			device.registrationDateTime,
+			device.ownership,
+			device.profileType,
+			strconv.FormatBool(device.isManaged),
+			strconv.FormatBool(device.isCompliant),
+		}
+	})
+}
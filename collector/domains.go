@@ -0,0 +1,267 @@
+package collector
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/domains"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/entra-exporter/config"
+)
+
+// cachedFederatedDomain holds the federation configuration of a domain
+type cachedFederatedDomain struct {
+	domain    string
+	issuerURI string
+	protocol  string
+
+	// Signing certificate expiry by certificate, "current" or "next"
+	certificates map[string]time.Time
+}
+
+// cachedDomains holds the domains of a tenant
+type cachedDomains struct {
+	// Domains by authentication type, "Managed" or "Federated"
+	byAuthenticationType map[string]int
+	federated            []cachedFederatedDomain
+}
+
+// DomainsCollector collects domain and federation configuration metrics
+type DomainsCollector struct {
+	*BaseCollector
+
+	// Domains cache
+	domainsLock sync.RWMutex
+	domains     map[string]cachedDomains
+
+	// Metrics
+	domainsTotal          *gaugeVec
+	federationInfo        *infoVec
+	signingCertExpiration *gaugeVec
+}
+
+// NewDomainsCollector creates a new DomainsCollector
+func NewDomainsCollector(ctx context.Context, config *config.Config, logger *logrus.Entry) *DomainsCollector {
+	c := &DomainsCollector{
+		BaseCollector: NewBaseCollector("domains", config.Collector.Domains, config, logger),
+		domains:       map[string]cachedDomains{},
+		domainsTotal: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_domains_total",
+				Help: "Number of domains by authentication type",
+			},
+			[]string{"tenant_id", "authentication_type"},
+		),
+		federationInfo: newInfoVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_domain_federation_info",
+				Help: "Federation configuration of federated domains",
+			},
+			[]string{"tenant_id", "domain", "issuer_uri", "protocol"},
+			&config.Collector.Domains,
+		),
+		signingCertExpiration: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_domain_federation_signing_certificate_expiry_timestamp_seconds",
+				Help: "Expiry of the token signing certificates of federated domains",
+			},
+			[]string{"tenant_id", "domain", "certificate"},
+		),
+	}
+
+	// Start background collection
+	c.StartCacheInvalidator(ctx, c.collect)
+
+	return c
+}
+
+// Describe implements prometheus.Collector
+func (c *DomainsCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.BaseCollector.Describe(ch)
+	c.domainsTotal.Describe(ch)
+	c.federationInfo.Describe(ch)
+	c.signingCertExpiration.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *DomainsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.BaseCollector.Collect(ch)
+
+	c.domainsLock.RLock()
+	defer c.domainsLock.RUnlock()
+
+	// Rebuild the metrics from the cache so removed domains disappear
+	domainsTotal := c.domainsTotal.vec()
+	signingCertExpiration := c.signingCertExpiration.vec()
+	federationInfo := c.federationInfo.vec()
+
+	for tenantID, domains := range c.domains {
+		if c.isCacheExpired(tenantID) {
+			continue
+		}
+
+		for authenticationType, count := range domains.byAuthenticationType {
+			domainsTotal.WithLabelValues(tenantID, authenticationType).Set(float64(count))
+		}
+
+		for _, domain := range domains.federated {
+			federationInfo.WithLabelValues(tenantID, domain.domain, domain.issuerURI, domain.protocol).Set(1)
+			for certificate, expiry := range domain.certificates {
+				signingCertExpiration.WithLabelValues(tenantID, domain.domain, certificate).Set(float64(expiry.Unix()))
+			}
+		}
+	}
+
+	c.collectCached(ch, domainsTotal, federationInfo, signingCertExpiration)
+}
+
+// collect gets the domains and the federation configuration of the federated ones
+func (c *DomainsCollector) collect(ctx context.Context) {
+	for _, tenantID := range c.GetTenants() {
+		if ctx.Err() != nil {
+			c.logger.Debugf("Stopping domains collection: %v", ctx.Err())
+			return
+		}
+
+		if !c.tenantAllowed(tenantID) {
+			continue
+		}
+
+		start := time.Now()
+		c.sampledDebugf("Collecting domains for tenant %s", tenantID)
+
+		client, err := c.GetGraphClient(ctx, tenantID)
+		if err != nil {
+			c.logger.Errorf("Failed to get Graph client for tenant %s: %v", tenantID, err)
+			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			c.tenantFailed(tenantID, err)
+			continue
+		}
+
+		tenantDomains := cachedDomains{byAuthenticationType: map[string]int{}}
+		var federatedIDs []string
+		reqConfig := domains.DomainsRequestBuilderGetRequestConfiguration{
+			QueryParameters: &domains.DomainsRequestBuilderGetQueryParameters{
+				Select: []string{"id", "authenticationType"},
+			},
+		}
+		pages, err := readPages(ctx, c.BaseCollector, tenantID, "domains", func(ctx context.Context, nextLink string) ([]models.Domainable, *string, error) {
+			builder := client.Domains()
+			requestConfig := &reqConfig
+			if nextLink != "" {
+				builder = builder.WithUrl(nextLink)
+				requestConfig = nil
+			}
+
+			result, err := builder.Get(ctx, requestConfig)
+			if err != nil {
+				return nil, nil, err
+			}
+			return result.GetValue(), result.GetOdataNextLink(), nil
+		}, func(domain models.Domainable) {
+			authenticationType := stringValue(domain.GetAuthenticationType(), "unknown")
+			tenantDomains.byAuthenticationType[authenticationType]++
+			if authenticationType == "Federated" && domain.GetId() != nil {
+				federatedIDs = append(federatedIDs, *domain.GetId())
+			}
+		})
+		if ctx.Err() != nil {
+			c.logger.Debugf("Domains collection for tenant %s cancelled", tenantID)
+			return
+		}
+		if err != nil {
+			c.logger.WithFields(graphErrorFields(err)).Errorf("Failed to get domains for tenant %s: %v", tenantID, graphErrorMessage(err))
+			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			c.tenantFailed(tenantID, err)
+			continue
+		}
+
+		// A missing federation configuration only drops the domain's series
+		var collectErr error
+		for _, domainID := range federatedIDs {
+			pages++
+			result, err := client.Domains().ByDomainId(domainID).FederationConfiguration().Get(ctx, nil)
+			if err != nil {
+				if ctx.Err() != nil {
+					c.logger.Debugf("Domains collection for tenant %s cancelled", tenantID)
+					return
+				}
+				c.logger.WithFields(graphErrorFields(err)).Errorf("Failed to get federation configuration of domain %s for tenant %s: %v", domainID, tenantID, graphErrorMessage(err))
+				c.scrapeErrors.WithLabelValues(tenantID).Inc()
+				collectErr = err
+				continue
+			}
+			for _, federation := range result.GetValue() {
+				tenantDomains.federated = append(tenantDomains.federated, c.newCachedFederatedDomain(domainID, federation))
+			}
+		}
+
+		c.domainsLock.Lock()
+		c.domains[tenantID] = tenantDomains
+		c.domainsLock.Unlock()
+		objects := 0
+		for _, count := range tenantDomains.byAuthenticationType {
+			objects += count
+		}
+		c.cacheUpdated(tenantID, objects)
+		c.recordStats(tenantID, start, pages, objects)
+
+		if collectErr != nil {
+			c.tenantFailed(tenantID, collectErr)
+		} else {
+			c.tenantSucceeded(tenantID)
+		}
+
+		// Update scrape metrics
+		duration := time.Since(start).Seconds()
+		c.observeDuration(tenantID, duration)
+		c.lastScrapeTime.WithLabelValues(tenantID).Set(float64(time.Now().Unix()))
+		c.sampledDebugf("Completed domains collection for tenant %s in %.2f seconds: %d domains, %d federated", tenantID, duration, objects, len(tenantDomains.federated))
+	}
+}
+
+// newCachedFederatedDomain maps a federation configuration, certificates which
+// can't be parsed are left out
+func (c *DomainsCollector) newCachedFederatedDomain(domainID string, federation models.InternalDomainFederationable) cachedFederatedDomain {
+	cached := cachedFederatedDomain{
+		domain:       domainID,
+		issuerURI:    stringValue(federation.GetIssuerUri(), ""),
+		protocol:     "unknown",
+		certificates: map[string]time.Time{},
+	}
+	if federation.GetPreferredAuthenticationProtocol() != nil {
+		cached.protocol = federation.GetPreferredAuthenticationProtocol().String()
+	}
+
+	for certificate, value := range map[string]*string{"current": federation.GetSigningCertificate(), "next": federation.GetNextSigningCertificate()} {
+		if value == nil || *value == "" {
+			continue
+		}
+		expiry, err := certificateExpiry(*value)
+		if err != nil {
+			c.logger.Warnf("Failed to parse %s signing certificate of domain %s: %v", certificate, domainID, err)
+			continue
+		}
+		cached.certificates[certificate] = expiry
+	}
+	return cached
+}
+
+// certificateExpiry returns the expiry of a base64 encoded DER certificate
+func certificateExpiry(value string) (time.Time, error) {
+	der, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("decoding certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cert.NotAfter, nil
+}
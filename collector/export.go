@@ -0,0 +1,192 @@
+package collector
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"sort"
+	"strings"
+)
+
+// InventoryExporter is implemented by collectors which cache an object
+// inventory that can be exported as a point in time snapshot
+type InventoryExporter interface {
+	Name() string
+
+	// Inventory returns the column names and the rows of the cached
+	// inventory of all tenants
+	Inventory() ([]string, iter.Seq[[]string])
+
+	// InventoryComplete returns if the cached inventory of the tenant is
+	// complete, e.g. not partial because a page failed
+	InventoryComplete(tenantID string) bool
+}
+
+// WriteInventoryCSV writes the cached inventory of the collector as CSV
+func WriteInventoryCSV(w io.Writer, exporter InventoryExporter) error {
+	header, rows := exporter.Inventory()
+	return writeCSV(w, header, rows)
+}
+
+// writeCSV writes the header and rows as CSV
+func writeCSV(w io.Writer, header []string, rows iter.Seq[[]string]) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for row := range rows {
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Inventory formats
+const (
+	InventoryJSON   = "json"
+	InventoryNDJSON = "ndjson"
+	InventoryCSV    = "csv"
+)
+
+// WriteInventory writes the cached inventory of the collector in the given
+// format, limited to the objects whose columns have the filtered values.
+// Filter names match the columns ignoring case and underscores, so both
+// accountEnabled and account_enabled filter the account_enabled column.
+// Unknown filters return an InvalidFilterError before anything is written.
+func WriteInventory(w io.Writer, exporter InventoryExporter, format string, filters map[string]string) error {
+	header, rows, err := FilterInventory(exporter, filters)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case InventoryCSV:
+		return writeCSV(w, header, rows)
+	case InventoryNDJSON:
+		encoder := json.NewEncoder(w)
+		for row := range rows {
+			if err := encoder.Encode(rowObject(header, row)); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		if _, err := io.WriteString(w, "["); err != nil {
+			return err
+		}
+		first := true
+		for row := range rows {
+			if !first {
+				if _, err := io.WriteString(w, ","); err != nil {
+					return err
+				}
+			}
+			first = false
+
+			encoded, err := json.Marshal(rowObject(header, row))
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(encoded); err != nil {
+				return err
+			}
+		}
+		_, err := io.WriteString(w, "]\n")
+		return err
+	}
+}
+
+// FilterInventory returns the column names and the rows of the cached
+// inventory whose columns have the filtered values, see WriteInventory
+func FilterInventory(exporter InventoryExporter, filters map[string]string) ([]string, iter.Seq[[]string], error) {
+	header, rows := exporter.Inventory()
+
+	rows, err := filterRows(header, rows, filters)
+	if err != nil {
+		return nil, nil, err
+	}
+	return header, rows, nil
+}
+
+// InvalidFilterError is returned for filters not matching any column
+type InvalidFilterError struct {
+	Filter string
+}
+
+func (e *InvalidFilterError) Error() string {
+	return fmt.Sprintf("unknown filter %q", e.Filter)
+}
+
+// filterRows returns the rows whose columns have the filtered values
+func filterRows(header []string, rows iter.Seq[[]string], filters map[string]string) (iter.Seq[[]string], error) {
+	if len(filters) == 0 {
+		return rows, nil
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, column := range header {
+		columns[normalizeColumn(column)] = i
+	}
+
+	expected := make(map[int]string, len(filters))
+	for name, value := range filters {
+		column, exists := columns[normalizeColumn(name)]
+		if !exists {
+			return nil, &InvalidFilterError{Filter: name}
+		}
+		expected[column] = value
+	}
+
+	return func(yield func([]string) bool) {
+		for row := range rows {
+			matches := true
+			for column, value := range expected {
+				if !strings.EqualFold(row[column], value) {
+					matches = false
+					break
+				}
+			}
+			if matches && !yield(row) {
+				return
+			}
+		}
+	}, nil
+}
+
+// normalizeColumn makes column names comparable regardless of case and underscores
+func normalizeColumn(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
+
+// rowObject maps the columns of an inventory row to their values
+func rowObject(header, row []string) map[string]string {
+	object := make(map[string]string, len(header))
+	for i, column := range header {
+		object[column] = row[i]
+	}
+	return object
+}
+
+// inventoryRows returns a row per object of every tenant, tenants are
+// iterated in a stable order
+func inventoryRows[T any](inventory map[string][]T, row func(tenantID string, object T) []string) iter.Seq[[]string] {
+	tenantIDs := make([]string, 0, len(inventory))
+	for tenantID := range inventory {
+		tenantIDs = append(tenantIDs, tenantID)
+	}
+	sort.Strings(tenantIDs)
+
+	return func(yield func([]string) bool) {
+		for _, tenantID := range tenantIDs {
+			for _, object := range inventory[tenantID] {
+				if !yield(row(tenantID, object)) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package collector
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/entra-exporter/config"
+)
+
+var (
+	// Exporter wide metrics shared by all collectors
+	tenantCircuitOpen = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "entraid_exporter_tenant_circuit_open",
+			Help: "Whether collections for a tenant are paused by the circuit breaker (1 = open)",
+		},
+		[]string{"tenant_id"},
+	)
+	graphRateLimitWait = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "entraid_exporter_graph_rate_limit_wait_seconds_total",
+			Help: "Total time Graph requests spent waiting on the rate limiter in seconds",
+		},
+	)
+	graphRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "entraid_exporter_graph_requests_total",
+			Help: "Total number of Graph requests sent by the exporter",
+		},
+		[]string{"tenant_id", "endpoint", "status_code"},
+	)
+	graphRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "entraid_exporter_graph_request_duration_seconds",
+			Help:    "Duration of Graph requests sent by the exporter in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"tenant_id", "endpoint"},
+	)
+	collectorSuccess = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "entraid_collector_success",
+			Help: "Whether the last collection of a collector for a tenant succeeded (1 = success)",
+		},
+		[]string{"collector", "tenant_id"},
+	)
+	collectorLastSuccess = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "entraid_collector_last_success_timestamp_seconds",
+			Help: "Time of the last successful collection of a collector for a tenant in seconds since epoch",
+		},
+		[]string{"collector", "tenant_id"},
+	)
+	collectorRestarts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "entraid_collector_restarts_total",
+			Help: "Number of times a collector was restarted after a panic of its scheduler or a collection cycle",
+		},
+		[]string{"collector"},
+	)
+
+	// Cache metrics, computed from the collectors at scrape time
+	cacheAgeDesc = prometheus.NewDesc(
+		"entraid_exporter_cache_age_seconds",
+		"Age of the cached data served for a collector and tenant in seconds",
+		[]string{"collector", "tenant_id"},
+		nil,
+	)
+	cachedObjectsDesc = prometheus.NewDesc(
+		"entraid_exporter_cached_objects",
+		"Number of objects cached for a collector and tenant",
+		[]string{"collector", "tenant_id"},
+		nil,
+	)
+
+	// Last error metrics, computed from the collectors at scrape time
+	lastErrorInfoDesc = prometheus.NewDesc(
+		"entraid_collector_last_error_info",
+		"Last error of a collector for a tenant",
+		[]string{"collector", "tenant_id", "error"},
+		nil,
+	)
+	lastErrorTimeDesc = prometheus.NewDesc(
+		"entraid_collector_last_error_timestamp_seconds",
+		"Time of the last error of a collector for a tenant in seconds since epoch",
+		[]string{"collector", "tenant_id"},
+		nil,
+	)
+
+	// Leader election state, only exported with leader election enabled
+	leaderDesc = prometheus.NewDesc(
+		"entraid_exporter_leader",
+		"Whether this replica is the elected leader collecting from Graph (1 = leader, 0 = standby)",
+		nil,
+		nil,
+	)
+
+	pausedDesc = prometheus.NewDesc(
+		"entraid_exporter_paused",
+		"Whether the collections are paused via /-/pause",
+		nil,
+		nil,
+	)
+)
+
+// maxErrorLabelLength limits the length of error messages used as label
+const maxErrorLabelLength = 200
+
+// ExporterCollector exports metrics about the exporter itself which are
+// shared by all collectors
+type ExporterCollector struct {
+	logger *logrus.Entry
+	config *config.Config
+}
+
+// NewExporterCollector creates a new ExporterCollector
+func NewExporterCollector(config *config.Config, logger *logrus.Entry) *ExporterCollector {
+	return &ExporterCollector{
+		logger: logger,
+		config: config,
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *ExporterCollector) Describe(ch chan<- *prometheus.Desc) {
+	tenantCircuitOpen.Describe(ch)
+	graphRateLimitWait.Describe(ch)
+	graphRequestsTotal.Describe(ch)
+	graphEndpointLastUsed.Describe(ch)
+	graphRequestDuration.Describe(ch)
+	collectorSuccess.Describe(ch)
+	collectorLastSuccess.Describe(ch)
+	collectorRestarts.Describe(ch)
+	ch <- cacheAgeDesc
+	ch <- cachedObjectsDesc
+	ch <- lastErrorInfoDesc
+	ch <- lastErrorTimeDesc
+	ch <- pausedDesc
+	if c.config.LeaderElection.IsEnabled() {
+		ch <- leaderDesc
+	}
+}
+
+// Collect implements prometheus.Collector
+func (c *ExporterCollector) Collect(ch chan<- prometheus.Metric) {
+	tenantCircuitOpen.Collect(ch)
+	graphRateLimitWait.Collect(ch)
+	graphRequestsTotal.Collect(ch)
+	graphEndpointLastUsed.Collect(ch)
+	graphRequestDuration.Collect(ch)
+	collectorSuccess.Collect(ch)
+	collectorLastSuccess.Collect(ch)
+	collectorRestarts.Collect(ch)
+
+	ch <- prometheus.MustNewConstMetric(pausedDesc, prometheus.GaugeValue, boolFloat(paused.Load()))
+	if c.config.LeaderElection.IsEnabled() {
+		ch <- prometheus.MustNewConstMetric(leaderDesc, prometheus.GaugeValue, boolFloat(!standby.Load()))
+	}
+
+	for _, collector := range registeredCollectors() {
+		for tenantID, info := range collector.cacheInfos() {
+			ch <- prometheus.MustNewConstMetric(cacheAgeDesc, prometheus.GaugeValue, time.Since(info.updated).Seconds(), collector.name, tenantID)
+			ch <- prometheus.MustNewConstMetric(cachedObjectsDesc, prometheus.GaugeValue, float64(info.objects), collector.name, tenantID)
+		}
+
+		for tenantID, lastError := range collector.lastErrorsByTenant() {
+			ch <- prometheus.MustNewConstMetric(lastErrorInfoDesc, prometheus.GaugeValue, 1, collector.name, tenantID, errorLabel(lastError.Message))
+			ch <- prometheus.MustNewConstMetric(lastErrorTimeDesc, prometheus.GaugeValue, float64(lastError.Time.Unix()), collector.name, tenantID)
+		}
+	}
+}
+
+// errorLabel turns an error message into a single line label value of limited length
+func errorLabel(message string) string {
+	message = strings.Join(strings.Fields(message), " ")
+	if len(message) > maxErrorLabelLength {
+		message = message[:maxErrorLabelLength] + "..."
+	}
+	return message
+}
@@ -2,14 +2,23 @@ package collector
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
 
+	mgraph "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/organization"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	"github.com/your-username/entra-exporter/config"
 )
 
+// tenantInfo is the display name and default domain of a tenant
+type tenantInfo struct {
+	displayName   string
+	defaultDomain string
+}
+
 // GeneralCollector collects general Entra ID metrics
 type GeneralCollector struct {
 	*BaseCollector
@@ -18,28 +27,39 @@ type GeneralCollector struct {
 	statsLock sync.RWMutex
 	stats     map[string]map[string]float64
 
+	// Tenant names are only resolved once per tenant
+	tenants map[string]tenantInfo
+
 	// Metrics
-	statsMetric *prometheus.GaugeVec
+	statsMetric *gaugeVec
+	tenantInfo  *infoVec
 }
 
 // NewGeneralCollector creates a new GeneralCollector
-func NewGeneralCollector(config *config.Config, logger *logrus.Entry) *GeneralCollector {
-	scrapeTime := config.Collector.General.ScrapeTime
-
+func NewGeneralCollector(ctx context.Context, config *config.Config, logger *logrus.Entry) *GeneralCollector {
 	c := &GeneralCollector{
-		BaseCollector: NewBaseCollector("general", scrapeTime, config, logger),
+		BaseCollector: NewBaseCollector("general", config.Collector.General, config, logger),
 		stats:         map[string]map[string]float64{},
-		statsMetric: prometheus.NewGaugeVec(
+		tenants:       map[string]tenantInfo{},
+		statsMetric: newGaugeVec(
 			prometheus.GaugeOpts{
 				Name: "entraid_stats",
 				Help: "Entra ID directory statistics",
 			},
 			[]string{"tenant_id", "metric"},
 		),
+		tenantInfo: newInfoVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_tenant_info",
+				Help: "Display name and default domain of the tenant, always 1",
+			},
+			[]string{"tenant_id", "display_name", "default_domain"},
+			&config.Collector.General,
+		),
 	}
 
 	// Start background collection
-	c.StartCacheInvalidator(c.collect)
+	c.StartCacheInvalidator(ctx, c.collect)
 
 	return c
 }
@@ -48,6 +68,7 @@ func NewGeneralCollector(config *config.Config, logger *logrus.Entry) *GeneralCo
 func (c *GeneralCollector) Describe(ch chan<- *prometheus.Desc) {
 	c.BaseCollector.Describe(ch)
 	c.statsMetric.Describe(ch)
+	c.tenantInfo.Describe(ch)
 }
 
 // Collect implements prometheus.Collector
@@ -57,89 +78,177 @@ func (c *GeneralCollector) Collect(ch chan<- prometheus.Metric) {
 	c.statsLock.RLock()
 	defer c.statsLock.RUnlock()
 
+	// The metrics are rebuilt from the cache by every Collect, so removed
+	// objects disappear and concurrent Gathers don't share any state
+	statsMetric := c.statsMetric.vec()
+	tenantInfos := c.tenantInfo.vec()
+
 	// Collect stats metrics
 	for tenantID, metrics := range c.stats {
+		if c.isCacheExpired(tenantID) {
+			continue
+		}
+
 		for metric, value := range metrics {
-			c.statsMetric.WithLabelValues(tenantID, metric).Set(value)
+			statsMetric.WithLabelValues(tenantID, metric).Set(value)
+		}
+
+		if info, exists := c.tenants[tenantID]; exists {
+			tenantInfos.WithLabelValues(tenantID, info.displayName, info.defaultDomain).Set(1)
 		}
 	}
 
-	c.statsMetric.Collect(ch)
+	c.collectCached(ch, statsMetric, tenantInfos)
 }
 
 // collect gets all the general statistics
-func (c *GeneralCollector) collect() {
-	c.Lock()
-	defer c.Unlock()
-
+func (c *GeneralCollector) collect(ctx context.Context) {
 	for _, tenantID := range c.GetTenants() {
+		if ctx.Err() != nil {
+			c.logger.Debugf("Stopping general metrics collection: %v", ctx.Err())
+			return
+		}
+
+		if !c.tenantAllowed(tenantID) {
+			continue
+		}
+
 		start := time.Now()
-		c.logger.Debugf("Collecting general metrics for tenant %s", tenantID)
+		c.sampledDebugf("Collecting general metrics for tenant %s", tenantID)
 
-		client, err := c.GetGraphClient(tenantID)
+		client, err := c.GetGraphClient(ctx, tenantID)
 		if err != nil {
 			c.logger.Errorf("Failed to get Graph client for tenant %s: %v", tenantID, err)
 			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			c.tenantFailed(tenantID, err)
 			continue
 		}
 
 		// Create a new stats map for this tenant
 		stats := make(map[string]float64)
+		var collectErr error
+		requests := 0
 
 		// Collect user count
-		usersPage, err := client.Users().Get(context.Background(), nil)
+		requests++
+		usersPage, err := client.Users().Get(ctx, nil)
 		if err != nil {
-			c.logger.Errorf("Failed to get users for tenant %s: %v", tenantID, err)
+			c.logger.WithFields(graphErrorFields(err)).Errorf("Failed to get users for tenant %s: %v", tenantID, graphErrorMessage(err))
 			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			collectErr = err
 		} else if usersPage != nil && usersPage.GetOdataCount() != nil {
 			stats["user_count"] = float64(*usersPage.GetOdataCount())
 		}
 
 		// Collect device count
-		devicesPage, err := client.Devices().Get(context.Background(), nil)
+		requests++
+		devicesPage, err := client.Devices().Get(ctx, nil)
 		if err != nil {
-			c.logger.Errorf("Failed to get devices for tenant %s: %v", tenantID, err)
+			c.logger.WithFields(graphErrorFields(err)).Errorf("Failed to get devices for tenant %s: %v", tenantID, graphErrorMessage(err))
 			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			collectErr = err
 		} else if devicesPage != nil && devicesPage.GetOdataCount() != nil {
 			stats["device_count"] = float64(*devicesPage.GetOdataCount())
 		}
 
 		// Collect application count
-		appsPage, err := client.Applications().Get(context.Background(), nil)
+		requests++
+		appsPage, err := client.Applications().Get(ctx, nil)
 		if err != nil {
-			c.logger.Errorf("Failed to get applications for tenant %s: %v", tenantID, err)
+			c.logger.WithFields(graphErrorFields(err)).Errorf("Failed to get applications for tenant %s: %v", tenantID, graphErrorMessage(err))
 			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			collectErr = err
 		} else if appsPage != nil && appsPage.GetOdataCount() != nil {
 			stats["application_count"] = float64(*appsPage.GetOdataCount())
 		}
 
 		// Collect service principal count
-		spsPage, err := client.ServicePrincipals().Get(context.Background(), nil)
+		requests++
+		spsPage, err := client.ServicePrincipals().Get(ctx, nil)
 		if err != nil {
-			c.logger.Errorf("Failed to get service principals for tenant %s: %v", tenantID, err)
+			c.logger.WithFields(graphErrorFields(err)).Errorf("Failed to get service principals for tenant %s: %v", tenantID, graphErrorMessage(err))
 			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			collectErr = err
 		} else if spsPage != nil && spsPage.GetOdataCount() != nil {
 			stats["service_principal_count"] = float64(*spsPage.GetOdataCount())
 		}
 
 		// Collect group count
-		groupsPage, err := client.Groups().Get(context.Background(), nil)
+		requests++
+		groupsPage, err := client.Groups().Get(ctx, nil)
 		if err != nil {
-			c.logger.Errorf("Failed to get groups for tenant %s: %v", tenantID, err)
+			c.logger.WithFields(graphErrorFields(err)).Errorf("Failed to get groups for tenant %s: %v", tenantID, graphErrorMessage(err))
 			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			collectErr = err
 		} else if groupsPage != nil && groupsPage.GetOdataCount() != nil {
 			stats["group_count"] = float64(*groupsPage.GetOdataCount())
 		}
 
+		// Resolve the tenant name until it succeeded once
+		c.statsLock.RLock()
+		_, resolved := c.tenants[tenantID]
+		c.statsLock.RUnlock()
+		var info *tenantInfo
+		if !resolved {
+			requests++
+			info, err = c.fetchTenantInfo(ctx, client)
+			if err != nil {
+				c.logger.WithFields(graphErrorFields(err)).Errorf("Failed to get organization for tenant %s: %v", tenantID, graphErrorMessage(err))
+				c.scrapeErrors.WithLabelValues(tenantID).Inc()
+				collectErr = err
+			}
+		}
+
+		// Don't replace the cached stats with a partial set
+		if ctx.Err() != nil {
+			c.logger.Debugf("General metrics collection for tenant %s cancelled", tenantID)
+			return
+		}
+
 		// Store the collected stats
 		c.statsLock.Lock()
 		c.stats[tenantID] = stats
+		if info != nil {
+			c.tenants[tenantID] = *info
+		}
 		c.statsLock.Unlock()
+		c.cacheUpdated(tenantID, len(stats))
+		c.recordStats(tenantID, start, requests, len(stats))
+
+		if collectErr != nil {
+			c.tenantFailed(tenantID, collectErr)
+		} else {
+			c.tenantSucceeded(tenantID)
+		}
 
 		// Update scrape metrics
 		duration := time.Since(start).Seconds()
-		c.scrapeDuration.WithLabelValues(tenantID).Observe(duration)
+		c.observeDuration(tenantID, duration)
 		c.lastScrapeTime.WithLabelValues(tenantID).Set(float64(time.Now().Unix()))
-		c.logger.Debugf("Completed general metrics collection for tenant %s in %.2f seconds", tenantID, duration)
+		c.sampledDebugf("Completed general metrics collection for tenant %s in %.2f seconds", tenantID, duration)
+	}
+}
+
+// fetchTenantInfo gets the display name and default domain of the tenant
+func (c *GeneralCollector) fetchTenantInfo(ctx context.Context, client *mgraph.GraphServiceClient) (*tenantInfo, error) {
+	result, err := client.Organization().Get(ctx, &organization.OrganizationRequestBuilderGetRequestConfiguration{
+		QueryParameters: &organization.OrganizationRequestBuilderGetQueryParameters{
+			Select: []string{"id", "displayName", "verifiedDomains"},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(result.GetValue()) == 0 {
+		return nil, fmt.Errorf("no organization returned")
+	}
+	org := result.GetValue()[0]
+
+	info := &tenantInfo{displayName: stringValue(org.GetDisplayName(), "")}
+	for _, domain := range org.GetVerifiedDomains() {
+		if boolValue(domain.GetIsDefault()) {
+			info.defaultDomain = stringValue(domain.GetName(), "")
+		}
 	}
+	return info, nil
 }
@@ -0,0 +1,211 @@
+package collector
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	abstractions "github.com/microsoft/kiota-abstractions-go"
+	khttp "github.com/microsoft/kiota-http-go"
+	mgraph "github.com/microsoftgraph/msgraph-sdk-go"
+	msgraphcore "github.com/microsoftgraph/msgraph-sdk-go-core"
+	"github.com/microsoftgraph/msgraph-sdk-go/models/odataerrors"
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/entra-exporter/config"
+	"golang.org/x/time/rate"
+)
+
+var (
+	// graphRateLimiter is shared by the Graph clients of all collectors and tenants
+	graphRateLimiter     *rate.Limiter
+	graphRateLimiterOnce sync.Once
+)
+
+// getGraphRateLimiter returns the shared Graph rate limiter, nil if rate limiting is disabled
+func getGraphRateLimiter(cfg config.RateLimitConfig) *rate.Limiter {
+	graphRateLimiterOnce.Do(func() {
+		if cfg.IsEnabled() {
+			graphRateLimiter = rate.NewLimiter(rate.Limit(cfg.RequestsPerSecond), cfg.GetBurst())
+		}
+	})
+	return graphRateLimiter
+}
+
+// graphUserAgent is the User-Agent of all Graph requests
+var graphUserAgent = "entra-exporter"
+
+// SetUserAgent sets the User-Agent of all Graph requests
+func SetUserAgent(userAgent string) {
+	graphUserAgent = userAgent
+}
+
+// newGraphHTTPClient creates the HTTP client used by the Graph request adapter
+// of a tenant, using the default Graph middlewares extended by the exporter's own
+func (c *BaseCollector) newGraphHTTPClient(tenantID string) *http.Client {
+	clientOptions := mgraph.GetDefaultClientOptions()
+
+	// First so the SDK appends its product to the exporter's User-Agent
+	middlewares := []khttp.Middleware{&userAgentMiddleware{requestID: c.config.Graph.DisableSDKTelemetry}}
+	for _, middleware := range msgraphcore.GetDefaultMiddlewaresWithOptions(&clientOptions) {
+		if c.config.Graph.DisableSDKTelemetry {
+			switch middleware.(type) {
+			case *msgraphcore.GraphTelemetryHandler, *khttp.UserAgentHandler:
+				continue
+			}
+		}
+		middlewares = append(middlewares, middleware)
+	}
+
+	// Appended last so every attempt, including retries, passes through them.
+	// Metrics come after the rate limiter so waiting isn't counted as request time.
+	if limiter := getGraphRateLimiter(c.config.Graph.RateLimit); limiter != nil {
+		middlewares = append(middlewares, &rateLimitMiddleware{limiter: limiter})
+	}
+	middlewares = append(middlewares, &metricsMiddleware{tenantID: tenantID, collector: c})
+
+	return msgraphcore.GetDefaultClient(&clientOptions, middlewares...)
+}
+
+// userAgentMiddleware sets the exporter's User-Agent on Graph requests
+type userAgentMiddleware struct {
+	// Add the client-request-id otherwise set by the SDK telemetry, it
+	// correlates failed requests with Microsoft support
+	requestID bool
+}
+
+// Intercept implements khttp.Middleware
+func (m *userAgentMiddleware) Intercept(pipeline khttp.Pipeline, middlewareIndex int, req *http.Request) (*http.Response, error) {
+	req.Header.Set("User-Agent", graphUserAgent)
+	if m.requestID && req.Header.Get("client-request-id") == "" {
+		req.Header.Set("client-request-id", uuid.NewString())
+	}
+	return pipeline.Next(req, middlewareIndex)
+}
+
+// rateLimitMiddleware delays Graph requests according to the shared rate limiter
+type rateLimitMiddleware struct {
+	limiter *rate.Limiter
+}
+
+// Intercept implements khttp.Middleware
+func (m *rateLimitMiddleware) Intercept(pipeline khttp.Pipeline, middlewareIndex int, req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	if err := m.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	graphRateLimitWait.Add(time.Since(start).Seconds())
+
+	return pipeline.Next(req, middlewareIndex)
+}
+
+// graphIDPattern matches object IDs in Graph request paths
+var graphIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// graphEndpoint returns the Graph endpoint of a request path without the API
+// version and with object IDs replaced, e.g. /v1.0/users/<id>/memberOf
+// becomes users/{id}/memberOf
+func graphEndpoint(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) > 0 && (segments[0] == "v1.0" || segments[0] == "beta") {
+		segments = segments[1:]
+	}
+
+	for i, segment := range segments {
+		if graphIDPattern.MatchString(segment) {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// metricsMiddleware counts and times the Graph requests of a tenant
+type metricsMiddleware struct {
+	tenantID  string
+	collector *BaseCollector
+}
+
+// Intercept implements khttp.Middleware
+func (m *metricsMiddleware) Intercept(pipeline khttp.Pipeline, middlewareIndex int, req *http.Request) (*http.Response, error) {
+	endpoint := graphEndpoint(req.URL.Path)
+
+	start := time.Now()
+	resp, err := pipeline.Next(req, middlewareIndex)
+	graphRequestDuration.WithLabelValues(m.tenantID, endpoint).Observe(time.Since(start).Seconds())
+
+	statusCode := "error"
+	if resp != nil {
+		statusCode = strconv.Itoa(resp.StatusCode)
+		tenantRequests.record(m.tenantID, resp.StatusCode)
+	} else {
+		tenantRequests.record(m.tenantID, 0)
+	}
+	graphRequestsTotal.WithLabelValues(m.tenantID, endpoint, statusCode).Inc()
+	m.collector.endpointUsed(endpoint)
+
+	return resp, err
+}
+
+// graphDiagnosticHeaders are the response headers of failed Graph requests
+// which help correlating failures with Microsoft support and throttling
+var graphDiagnosticHeaders = map[string]string{
+	"request-id":                     "request_id",
+	"client-request-id":              "client_request_id",
+	"retry-after":                    "retry_after",
+	"x-ms-throttle-limit-percentage": "throttle_limit_percentage",
+	"x-ms-throttle-scope":            "throttle_scope",
+	"x-ms-throttle-information":      "throttle_information",
+	"x-ms-resource-unit":             "resource_unit",
+}
+
+// graphErrorFields returns the log fields describing a failed Graph request,
+// such as the request IDs and throttling headers
+func graphErrorFields(err error) logrus.Fields {
+	fields := logrus.Fields{}
+
+	var apiErr abstractions.ApiErrorable
+	if errors.As(err, &apiErr) {
+		if apiErr.GetStatusCode() != 0 {
+			fields["status_code"] = apiErr.GetStatusCode()
+		}
+		if headers := apiErr.GetResponseHeaders(); headers != nil {
+			for header, field := range graphDiagnosticHeaders {
+				if values := headers.Get(header); len(values) > 0 {
+					fields[field] = strings.Join(values, ",")
+				}
+			}
+		}
+	}
+
+	var odataErr *odataerrors.ODataError
+	if errors.As(err, &odataErr) && odataErr.GetErrorEscaped() != nil {
+		mainErr := odataErr.GetErrorEscaped()
+		if mainErr.GetCode() != nil {
+			fields["error_code"] = *mainErr.GetCode()
+		}
+		if innerErr := mainErr.GetInnerError(); innerErr != nil {
+			if innerErr.GetRequestId() != nil {
+				fields["request_id"] = *innerErr.GetRequestId()
+			}
+			if innerErr.GetClientRequestId() != nil {
+				fields["client_request_id"] = *innerErr.GetClientRequestId()
+			}
+		}
+	}
+
+	return fields
+}
+
+// graphErrorMessage returns the message of a Graph error, ODataErrors only
+// carry it in the error body
+func graphErrorMessage(err error) string {
+	var odataErr *odataerrors.ODataError
+	if errors.As(err, &odataErr) && odataErr.GetErrorEscaped() != nil && odataErr.GetErrorEscaped().GetMessage() != nil {
+		return *odataErr.GetErrorEscaped().GetMessage()
+	}
+	return err.Error()
+}
@@ -0,0 +1,352 @@
+package collector
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	mgraph "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/microsoftgraph/msgraph-sdk-go/organization"
+	"github.com/microsoftgraph/msgraph-sdk-go/serviceprincipals"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/entra-exporter/config"
+)
+
+// cachedSyncJob holds the status of a cloud sync provisioning job
+type cachedSyncJob struct {
+	servicePrincipalID string
+	servicePrincipal   string
+	jobID              string
+	templateID         string
+	status             string
+	quarantined        bool
+	lastSuccess        time.Time
+}
+
+// cachedHybridSync holds the directory synchronization state of a tenant
+type cachedHybridSync struct {
+	enabled  bool
+	lastSync time.Time
+
+	// Enabled synchronization features by name, nil if they couldn't be read
+	features map[string]bool
+
+	jobs []cachedSyncJob
+}
+
+// HybridSyncCollector collects Entra Connect and cloud sync health metrics
+type HybridSyncCollector struct {
+	*BaseCollector
+
+	// Sync state cache
+	syncLock sync.RWMutex
+	sync     map[string]cachedHybridSync
+
+	// Metrics
+	syncEnabled        *gaugeVec
+	lastSync           *gaugeVec
+	featureEnabled     *gaugeVec
+	jobStatus          *gaugeVec
+	jobQuarantined     *gaugeVec
+	jobLastSuccessTime *gaugeVec
+}
+
+// NewHybridSyncCollector creates a new HybridSyncCollector
+func NewHybridSyncCollector(ctx context.Context, config *config.Config, logger *logrus.Entry) *HybridSyncCollector {
+	jobLabels := []string{"tenant_id", "service_principal_id", "job_id"}
+
+	c := &HybridSyncCollector{
+		BaseCollector: NewBaseCollector("hybridSync", config.Collector.HybridSync, config, logger),
+		sync:          map[string]cachedHybridSync{},
+		syncEnabled: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_sync_enabled",
+				Help: "Whether directory synchronization from on-premises is enabled for the tenant",
+			},
+			[]string{"tenant_id"},
+		),
+		lastSync: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_sync_last_sync_timestamp_seconds",
+				Help: "Time of the last directory synchronization from on-premises",
+			},
+			[]string{"tenant_id"},
+		),
+		featureEnabled: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_sync_feature_enabled",
+				Help: "Whether a directory synchronization feature is enabled",
+			},
+			[]string{"tenant_id", "feature"},
+		),
+		jobStatus: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_cloud_sync_job_status",
+				Help: "Status of the cloud sync provisioning jobs",
+			},
+			[]string{"tenant_id", "service_principal_id", "service_principal", "job_id", "template_id", "status"},
+		),
+		jobQuarantined: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_cloud_sync_job_quarantined",
+				Help: "Whether the cloud sync provisioning job is in quarantine",
+			},
+			jobLabels,
+		),
+		jobLastSuccessTime: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_cloud_sync_job_last_success_timestamp_seconds",
+				Help: "End of the last successful run of the cloud sync provisioning job",
+			},
+			jobLabels,
+		),
+	}
+
+	// Start background collection
+	c.StartCacheInvalidator(ctx, c.collect)
+
+	return c
+}
+
+// Describe implements prometheus.Collector
+func (c *HybridSyncCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.BaseCollector.Describe(ch)
+	c.syncEnabled.Describe(ch)
+	c.lastSync.Describe(ch)
+	c.featureEnabled.Describe(ch)
+	c.jobStatus.Describe(ch)
+	c.jobQuarantined.Describe(ch)
+	c.jobLastSuccessTime.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *HybridSyncCollector) Collect(ch chan<- prometheus.Metric) {
+	c.BaseCollector.Collect(ch)
+
+	c.syncLock.RLock()
+	defer c.syncLock.RUnlock()
+
+	// Rebuild the metrics from the cache so removed jobs disappear
+	syncEnabled := c.syncEnabled.vec()
+	lastSync := c.lastSync.vec()
+	featureEnabled := c.featureEnabled.vec()
+	jobStatus := c.jobStatus.vec()
+	jobQuarantined := c.jobQuarantined.vec()
+	jobLastSuccessTime := c.jobLastSuccessTime.vec()
+
+	for tenantID, state := range c.sync {
+		if c.isCacheExpired(tenantID) {
+			continue
+		}
+
+		syncEnabled.WithLabelValues(tenantID).Set(boolFloat(state.enabled))
+		if !state.lastSync.IsZero() {
+			lastSync.WithLabelValues(tenantID).Set(float64(state.lastSync.Unix()))
+		}
+		for feature, enabled := range state.features {
+			featureEnabled.WithLabelValues(tenantID, feature).Set(boolFloat(enabled))
+		}
+
+		for _, job := range state.jobs {
+			jobStatus.WithLabelValues(tenantID, job.servicePrincipalID, job.servicePrincipal, job.jobID, job.templateID, job.status).Set(1)
+			jobQuarantined.WithLabelValues(tenantID, job.servicePrincipalID, job.jobID).Set(boolFloat(job.quarantined))
+			if !job.lastSuccess.IsZero() {
+				jobLastSuccessTime.WithLabelValues(tenantID, job.servicePrincipalID, job.jobID).Set(float64(job.lastSuccess.Unix()))
+			}
+		}
+	}
+
+	c.collectCached(ch, syncEnabled, lastSync, featureEnabled, jobStatus, jobQuarantined, jobLastSuccessTime)
+}
+
+// collect gets the synchronization state of all tenants
+func (c *HybridSyncCollector) collect(ctx context.Context) {
+	for _, tenantID := range c.GetTenants() {
+		if ctx.Err() != nil {
+			c.logger.Debugf("Stopping hybrid sync collection: %v", ctx.Err())
+			return
+		}
+
+		if !c.tenantAllowed(tenantID) {
+			continue
+		}
+
+		start := time.Now()
+		c.sampledDebugf("Collecting hybrid sync state for tenant %s", tenantID)
+
+		client, err := c.GetGraphClient(ctx, tenantID)
+		if err != nil {
+			c.logger.Errorf("Failed to get Graph client for tenant %s: %v", tenantID, err)
+			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			c.tenantFailed(tenantID, err)
+			continue
+		}
+
+		result, err := client.Organization().Get(ctx, &organization.OrganizationRequestBuilderGetRequestConfiguration{
+			QueryParameters: &organization.OrganizationRequestBuilderGetQueryParameters{
+				Select: []string{"id", "onPremisesSyncEnabled", "onPremisesLastSyncDateTime"},
+			},
+		})
+		if err != nil || len(result.GetValue()) == 0 {
+			if ctx.Err() != nil {
+				c.logger.Debugf("Hybrid sync collection for tenant %s cancelled", tenantID)
+				return
+			}
+			if err == nil {
+				c.logger.Errorf("No organization returned for tenant %s", tenantID)
+			} else {
+				c.logger.WithFields(graphErrorFields(err)).Errorf("Failed to get organization for tenant %s: %v", tenantID, graphErrorMessage(err))
+			}
+			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			c.tenantFailed(tenantID, err)
+			continue
+		}
+		org := result.GetValue()[0]
+
+		state := cachedHybridSync{enabled: boolValue(org.GetOnPremisesSyncEnabled())}
+		if org.GetOnPremisesLastSyncDateTime() != nil {
+			state.lastSync = *org.GetOnPremisesLastSyncDateTime()
+		}
+		requests := 1
+
+		// Only readable with OnPremDirectorySynchronization.Read.All, the
+		// feature series are left out otherwise
+		requests++
+		state.features = c.fetchSyncFeatures(ctx, client, tenantID)
+
+		var collectErr error
+		if filter := c.collectorConfig.Filter; filter != "" {
+			var jobRequests int
+			state.jobs, jobRequests, collectErr = c.fetchSyncJobs(ctx, client, tenantID, filter)
+			requests += jobRequests
+		}
+
+		if ctx.Err() != nil {
+			c.logger.Debugf("Hybrid sync collection for tenant %s cancelled", tenantID)
+			return
+		}
+
+		c.syncLock.Lock()
+		c.sync[tenantID] = state
+		c.syncLock.Unlock()
+		c.cacheUpdated(tenantID, 1+len(state.jobs))
+		c.recordStats(tenantID, start, requests, 1+len(state.jobs))
+
+		if collectErr != nil {
+			c.tenantFailed(tenantID, collectErr)
+		} else {
+			c.tenantSucceeded(tenantID)
+		}
+
+		// Update scrape metrics
+		duration := time.Since(start).Seconds()
+		c.observeDuration(tenantID, duration)
+		c.lastScrapeTime.WithLabelValues(tenantID).Set(float64(time.Now().Unix()))
+		c.sampledDebugf("Completed hybrid sync collection for tenant %s in %.2f seconds: %d cloud sync jobs", tenantID, duration, len(state.jobs))
+	}
+}
+
+// fetchSyncFeatures returns the enabled state of the synchronization
+// features, or nil if they couldn't be fetched
+func (c *HybridSyncCollector) fetchSyncFeatures(ctx context.Context, client *mgraph.GraphServiceClient, tenantID string) map[string]bool {
+	result, err := client.Directory().OnPremisesSynchronization().Get(ctx, nil)
+	if err != nil {
+		c.logger.WithFields(graphErrorFields(err)).Warnf("Failed to get on-premises synchronization for tenant %s: %v", tenantID, graphErrorMessage(err))
+		return nil
+	}
+	if len(result.GetValue()) == 0 || result.GetValue()[0].GetFeatures() == nil {
+		return nil
+	}
+
+	features := result.GetValue()[0].GetFeatures()
+	return map[string]bool{
+		"passwordSync":        boolValue(features.GetPasswordSyncEnabled()),
+		"passwordWriteback":   boolValue(features.GetPasswordWritebackEnabled()),
+		"groupWriteback":      boolValue(features.GetGroupWriteBackEnabled()),
+		"deviceWriteback":     boolValue(features.GetDeviceWritebackEnabled()),
+		"userWriteback":       boolValue(features.GetUserWritebackEnabled()),
+		"directoryExtensions": boolValue(features.GetDirectoryExtensionsEnabled()),
+		"softMatchOnUpn":      boolValue(features.GetSoftMatchOnUpnEnabled()),
+		"blockSoftMatch":      boolValue(features.GetBlockSoftMatchEnabled()),
+		"blockCloudObjectTakeoverThroughHardMatch": boolValue(features.GetBlockCloudObjectTakeoverThroughHardMatchEnabled()),
+	}
+}
+
+// fetchSyncJobs returns the provisioning jobs of the service principals
+// matching the filter and the number of requests made
+func (c *HybridSyncCollector) fetchSyncJobs(ctx context.Context, client *mgraph.GraphServiceClient, tenantID, filter string) ([]cachedSyncJob, int, error) {
+	type syncClient struct {
+		id, name string
+	}
+	var clients []syncClient
+
+	reqConfig := serviceprincipals.ServicePrincipalsRequestBuilderGetRequestConfiguration{
+		QueryParameters: &serviceprincipals.ServicePrincipalsRequestBuilderGetQueryParameters{
+			Filter: &filter,
+			Select: []string{"id", "displayName"},
+		},
+	}
+	requests, err := readPages(ctx, c.BaseCollector, tenantID, "servicePrincipals", func(ctx context.Context, nextLink string) ([]models.ServicePrincipalable, *string, error) {
+		builder := client.ServicePrincipals()
+		requestConfig := &reqConfig
+		if nextLink != "" {
+			builder = builder.WithUrl(nextLink)
+			requestConfig = nil
+		}
+
+		result, err := builder.Get(ctx, requestConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		return result.GetValue(), result.GetOdataNextLink(), nil
+	}, func(sp models.ServicePrincipalable) {
+		if sp.GetId() != nil {
+			clients = append(clients, syncClient{id: *sp.GetId(), name: stringValue(sp.GetDisplayName(), "")})
+		}
+	})
+	if err != nil {
+		c.logger.WithFields(graphErrorFields(err)).Errorf("Failed to get cloud sync service principals for tenant %s: %v", tenantID, graphErrorMessage(err))
+		c.scrapeErrors.WithLabelValues(tenantID).Inc()
+		return nil, requests, err
+	}
+
+	// A failing client only drops its own jobs
+	var jobs []cachedSyncJob
+	var collectErr error
+	for _, syncClient := range clients {
+		requests++
+		result, err := client.ServicePrincipals().ByServicePrincipalId(syncClient.id).Synchronization().Jobs().Get(ctx, nil)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, requests, ctx.Err()
+			}
+			c.logger.WithFields(graphErrorFields(err)).Errorf("Failed to get synchronization jobs of service principal %s for tenant %s: %v", syncClient.id, tenantID, graphErrorMessage(err))
+			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			collectErr = err
+			continue
+		}
+
+		for _, job := range result.GetValue() {
+			cached := cachedSyncJob{
+				servicePrincipalID: syncClient.id,
+				servicePrincipal:   syncClient.name,
+				jobID:              stringValue(job.GetId(), ""),
+				templateID:         stringValue(job.GetTemplateId(), ""),
+				status:             "unknown",
+			}
+			if status := job.GetStatus(); status != nil {
+				if status.GetCode() != nil {
+					cached.status = status.GetCode().String()
+				}
+				cached.quarantined = status.GetQuarantine() != nil
+				if execution := status.GetLastSuccessfulExecution(); execution != nil && execution.GetTimeEnded() != nil {
+					cached.lastSuccess = *execution.GetTimeEnded()
+				}
+			}
+			jobs = append(jobs, cached)
+		}
+	}
+	return jobs, requests, collectErr
+}
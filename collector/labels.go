@@ -0,0 +1,161 @@
+package collector
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/your-username/entra-exporter/config"
+)
+
+// gaugeVec is a GaugeVec whose series are built from the caches in Collect.
+// Every Collect fills a GaugeVec of its own, so concurrent Gathers don't
+// share any state and removed objects disappear without a Reset.
+type gaugeVec struct {
+	opts   prometheus.GaugeOpts
+	labels []string
+	desc   *prometheus.Desc
+}
+
+// newGaugeVec creates a gaugeVec with the given labels
+func newGaugeVec(opts prometheus.GaugeOpts, labels []string) *gaugeVec {
+	return &gaugeVec{
+		opts:   opts,
+		labels: labels,
+		desc:   prometheus.NewDesc(prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name), opts.Help, labels, opts.ConstLabels),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (v *gaugeVec) Describe(ch chan<- *prometheus.Desc) {
+	ch <- v.desc
+}
+
+// vec returns the empty GaugeVec of a single Collect
+func (v *gaugeVec) vec() *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(v.opts, v.labels)
+}
+
+// infoVec is the gaugeVec of an info metric whose labels are filtered by the
+// collector's keepLabels and dropLabels. Label values are still passed for
+// all labels, those of left out labels are ignored.
+type infoVec struct {
+	*gaugeVec
+
+	// Indexes of the kept label values, nil if all are kept
+	kept []int
+}
+
+// newInfoVec creates the gaugeVec of an info metric with the labels the
+// collector config keeps, tenant_id is always kept
+func newInfoVec(opts prometheus.GaugeOpts, labels []string, collectorConfig *config.CollectorConfig) *infoVec {
+	var kept []int
+	var keptLabels []string
+	for i, label := range labels {
+		if label == "tenant_id" || collectorConfig.KeepsLabel(label) {
+			kept = append(kept, i)
+			keptLabels = append(keptLabels, label)
+		}
+	}
+	if len(kept) == len(labels) {
+		kept = nil
+	}
+
+	return &infoVec{
+		gaugeVec: newGaugeVec(opts, keptLabels),
+		kept:     kept,
+	}
+}
+
+// vec returns the empty info GaugeVec of a single Collect
+func (v *infoVec) vec() *infoGauges {
+	return &infoGauges{
+		GaugeVec: v.gaugeVec.vec(),
+		kept:     v.kept,
+	}
+}
+
+// infoGauges is the GaugeVec of an info metric of a single Collect
+type infoGauges struct {
+	*prometheus.GaugeVec
+
+	// Indexes of the kept label values, nil if all are kept
+	kept []int
+}
+
+// WithLabelValues returns the gauge of the values of all labels, including
+// the left out ones
+func (v *infoGauges) WithLabelValues(values ...string) prometheus.Gauge {
+	if v.kept == nil {
+		return v.GaugeVec.WithLabelValues(values...)
+	}
+
+	keptValues := make([]string, 0, len(v.kept))
+	for _, i := range v.kept {
+		keptValues = append(keptValues, values[i])
+	}
+	return v.GaugeVec.WithLabelValues(keptValues...)
+}
+
+// countVec is a gauge of object counts built from the caches in Collect. The
+// counts of a Collect are aggregated locally and sent as const metrics, so
+// concurrent Gathers don't share any state.
+type countVec struct {
+	desc *prometheus.Desc
+}
+
+// newCountVec creates a countVec with the given labels
+func newCountVec(opts prometheus.GaugeOpts, labels []string) *countVec {
+	return &countVec{
+		desc: prometheus.NewDesc(opts.Name, opts.Help, labels, opts.ConstLabels),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (v *countVec) Describe(ch chan<- *prometheus.Desc) {
+	ch <- v.desc
+}
+
+// counts returns the empty counts of a single Collect
+func (v *countVec) counts() *counts {
+	return &counts{
+		vec:         v,
+		labelValues: map[string][]string{},
+		values:      map[string]float64{},
+	}
+}
+
+// counts holds the counts per label values of a single Collect, it
+// implements prometheus.Collector to send them
+type counts struct {
+	vec *countVec
+
+	// Label values and counts by the joined label values
+	labelValues map[string][]string
+	values      map[string]float64
+}
+
+// Add adds the value to the count of the label values
+func (c *counts) Add(value float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+	if _, exists := c.labelValues[key]; !exists {
+		c.labelValues[key] = labelValues
+	}
+	c.values[key] += value
+}
+
+// Inc increments the count of the label values
+func (c *counts) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Describe implements prometheus.Collector
+func (c *counts) Describe(ch chan<- *prometheus.Desc) {
+	c.vec.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *counts) Collect(ch chan<- prometheus.Metric) {
+	for key, value := range c.values {
+		ch <- prometheus.MustNewConstMetric(c.vec.desc, prometheus.GaugeValue, value, c.labelValues[key]...)
+	}
+}
@@ -0,0 +1,72 @@
+package collector
+
+import (
+	"context"
+	"time"
+)
+
+// graphPage is a single page of a paginated Graph collection
+type graphPage[T any] struct {
+	number   int
+	items    []T
+	err      error
+	duration time.Duration
+}
+
+// fetchPageFunc fetches a page of a Graph collection. An empty nextLink
+// requests the first page. It returns the page items and the link to the
+// next page, nil on the last page.
+type fetchPageFunc[T any] func(ctx context.Context, nextLink string) ([]T, *string, error)
+
+// prefetchPages fetches all pages of a Graph collection in the background.
+// The next page is fetched while the previous one is being processed, with
+// at most one page buffered. The channel is closed after the last page or
+// after a page with an error; cancel the context to stop early.
+func prefetchPages[T any](ctx context.Context, fetch fetchPageFunc[T]) <-chan graphPage[T] {
+	pages := make(chan graphPage[T], 1)
+
+	go func() {
+		defer close(pages)
+
+		nextLink := ""
+		for number := 1; ; number++ {
+			start := time.Now()
+			items, next, err := fetch(ctx, nextLink)
+			duration := time.Since(start)
+
+			select {
+			case pages <- graphPage[T]{number: number, items: items, err: err, duration: duration}:
+			case <-ctx.Done():
+				return
+			}
+
+			if err != nil || next == nil || len(*next) == 0 {
+				return
+			}
+			nextLink = *next
+		}
+	}()
+
+	return pages
+}
+
+// readPages reads all pages of a Graph collection and passes their items to
+// handle. It returns the number of pages read and the error of the failed
+// page, or the context error when cancelled.
+func readPages[T any](ctx context.Context, c *BaseCollector, tenantID, endpoint string, fetch fetchPageFunc[T], handle func(T)) (int, error) {
+	pageCtx, cancelPages := context.WithCancel(ctx)
+	defer cancelPages()
+
+	pageCount := 0
+	for page := range prefetchPages(pageCtx, fetch) {
+		pageCount = page.number
+		c.checkSlowPage(tenantID, endpoint, page.number, page.duration)
+		if page.err != nil {
+			return pageCount, page.err
+		}
+		for _, item := range page.items {
+			handle(item)
+		}
+	}
+	return pageCount, ctx.Err()
+}
@@ -0,0 +1,188 @@
+package collector
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/your-username/entra-exporter/config"
+)
+
+// collectorPermissions are the Microsoft Graph application permissions each
+// implemented collector needs regardless of its options
+var collectorPermissions = map[string][]string{
+	"general":             {"Directory.Read.All"},
+	"users":               {"User.Read.All"},
+	"devices":             {"Device.Read.All"},
+	"pim":                 {"RoleManagement.Read.Directory"},
+	"authMethods":         {"AuditLog.Read.All"},
+	"signIns":             {"AuditLog.Read.All"},
+	"autopilot":           {"DeviceManagementServiceConfig.Read.All"},
+	"consents":            {"Application.Read.All", "DelegatedPermissionGrant.Read.All"},
+	"administrativeUnits": {"AdministrativeUnit.Read.All", "RoleManagement.Read.Directory"},
+	"tenantSettings":      {"Policy.Read.All"},
+	"domains":             {"Domain.Read.All"},
+	"hybridSync":          {"Organization.Read.All", "OnPremDirectorySynchronization.Read.All"},
+	"deletedItems":        {"User.Read.All", "Group.Read.All"},
+	"securityAlerts":      {"SecurityAlert.Read.All"},
+	"accessPackages":      {"EntitlementManagement.Read.All"},
+	"termsOfUse":          {"Agreement.Read.All", "AgreementAcceptance.Read.All"},
+	// Depends on the configured queries
+	"custom": {},
+}
+
+// KnownCollectors returns the names of all implemented collectors, sorted
+func KnownCollectors() []string {
+	names := make([]string, 0, len(collectorPermissions))
+	for name := range collectorPermissions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RequiredPermissions returns the Microsoft Graph application permissions the
+// collector needs with its configuration, options like users.licenses add to
+// the collector's base permissions
+func RequiredPermissions(name string, cfg *config.Config) []string {
+	permissions := slices.Clone(collectorPermissions[name])
+	switch name {
+	case "users":
+		if cfg.Collector.Users.Licenses {
+			permissions = append(permissions, "Organization.Read.All")
+		}
+		if cfg.Collector.Users.InactiveGuests.IsEnabled() {
+			permissions = append(permissions, "AuditLog.Read.All")
+		}
+	case "hybridSync":
+		// Cloud sync jobs are only read with a filter for the sync clients
+		if cfg.Collector.HybridSync.Filter != "" {
+			permissions = append(permissions, "Application.Read.All", "Synchronization.Read.All")
+		}
+	}
+	return permissions
+}
+
+// permissionProbes are lightweight Graph requests which only succeed with
+// the permission, permissions without one are only checked in the token
+var permissionProbes = map[string]string{
+	"Directory.Read.All":                      "directoryRoles?$select=id",
+	"User.Read.All":                           "users?$top=1&$select=id",
+	"Device.Read.All":                         "devices?$top=1&$select=id",
+	"Group.Read.All":                          "groups?$top=1&$select=id",
+	"Application.Read.All":                    "applications?$top=1&$select=id",
+	"DelegatedPermissionGrant.Read.All":       "oauth2PermissionGrants?$top=1",
+	"RoleManagement.Read.Directory":           "roleManagement/directory/roleDefinitions?$top=1&$select=id",
+	"AuditLog.Read.All":                       "auditLogs/signIns?$top=1",
+	"DeviceManagementServiceConfig.Read.All":  "deviceManagement/windowsAutopilotDeviceIdentities?$top=1",
+	"AdministrativeUnit.Read.All":             "directory/administrativeUnits?$top=1&$select=id",
+	"Policy.Read.All":                         "policies/authorizationPolicy",
+	"Domain.Read.All":                         "domains?$select=id",
+	"Organization.Read.All":                   "subscribedSkus",
+	"OnPremDirectorySynchronization.Read.All": "directory/onPremisesSynchronization",
+	"SecurityAlert.Read.All":                  "security/alerts_v2?$top=1",
+	"EntitlementManagement.Read.All":          "identityGovernance/entitlementManagement/accessPackages?$top=1",
+	"Agreement.Read.All":                      "identityGovernance/termsOfUse/agreements",
+}
+
+// PermissionCheck is the result of checking a permission for a tenant
+type PermissionCheck struct {
+	Permission string
+	// Whether the permission is one of the token's roles
+	InToken bool
+	// Outcome of the probe request, empty without a probe
+	Probe string
+	// Whether the permission is in the token and its probe succeeded
+	Passed bool
+}
+
+// CheckPermissions requests a Graph token for the tenant like a collector
+// with the given configuration does, and checks every permission against the
+// token's roles and with its probe request on the collector's Graph endpoint
+func CheckPermissions(ctx context.Context, tenantID string, collectorConfig *config.CollectorConfig, permissions []string) ([]PermissionCheck, error) {
+	cred, err := newCredential(tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create credential: %v", err)
+	}
+
+	token, err := cred.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{collectorConfig.GetGraphScope()}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token: %v", err)
+	}
+	roles, err := tokenRoles(token.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	checks := make([]PermissionCheck, 0, len(permissions))
+	for _, permission := range permissions {
+		check := PermissionCheck{
+			Permission: permission,
+			InToken:    slices.Contains(roles, permission),
+		}
+		check.Passed = check.InToken
+
+		if path, exists := permissionProbes[permission]; exists {
+			status, err := probeGraph(ctx, client, token.Token, collectorConfig.GetGraphBaseURL(), path)
+			switch {
+			case err != nil:
+				check.Probe = err.Error()
+				check.Passed = false
+			case status >= 200 && status < 300:
+				check.Probe = "ok"
+			default:
+				check.Probe = http.StatusText(status)
+				check.Passed = false
+			}
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+// tokenRoles returns the application permissions in the roles claim of the
+// access token, the signature is not verified as Graph does that anyway
+func tokenRoles(token string) ([]string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("unexpected access token format")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode access token: %v", err)
+	}
+	var claims struct {
+		Roles []string `json:"roles"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("failed to parse access token claims: %v", err)
+	}
+	return claims.Roles, nil
+}
+
+// probeGraph requests the path on the Graph endpoint and returns the response status
+func probeGraph(ctx context.Context, client *http.Client, token, baseURL, path string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/"+path, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("User-Agent", graphUserAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
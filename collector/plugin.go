@@ -0,0 +1,229 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/entra-exporter/config"
+)
+
+// PluginCollector runs an external command per tenant and exports the
+// metrics it writes to stdout in exposition format. They are only known
+// once it ran, so they are not described.
+type PluginCollector struct {
+	*BaseCollector
+
+	plugin config.PluginConfig
+
+	// Metrics of the last run per tenant
+	metricsLock sync.RWMutex
+	metrics     map[string][]prometheus.Metric
+}
+
+// NewPluginCollector creates a new PluginCollector
+func NewPluginCollector(ctx context.Context, plugin config.PluginConfig, config *config.Config, logger *logrus.Entry) *PluginCollector {
+	c := &PluginCollector{
+		BaseCollector: NewBaseCollector("plugin_"+plugin.Name, pluginCollectorConfig(plugin), config, logger),
+		plugin:        plugin,
+		metrics:       map[string][]prometheus.Metric{},
+	}
+
+	// Start background collection
+	c.StartCacheInvalidator(ctx, c.collect)
+
+	return c
+}
+
+// pluginCollectorConfig returns the collector configuration of a plugin
+func pluginCollectorConfig(plugin config.PluginConfig) config.CollectorConfig {
+	return config.CollectorConfig{
+		ScrapeTime: plugin.ScrapeTime,
+		CacheTTL:   plugin.CacheTTL,
+		BaseURL:    plugin.BaseURL,
+	}
+}
+
+// Collect implements prometheus.Collector
+func (c *PluginCollector) Collect(ch chan<- prometheus.Metric) {
+	c.BaseCollector.Collect(ch)
+
+	c.metricsLock.RLock()
+	defer c.metricsLock.RUnlock()
+
+	for tenantID, metrics := range c.metrics {
+		if c.isCacheExpired(tenantID) {
+			continue
+		}
+
+		for _, metric := range metrics {
+			ch <- metric
+		}
+	}
+}
+
+// collect runs the command for every tenant
+func (c *PluginCollector) collect(ctx context.Context) {
+	for _, tenantID := range c.GetTenants() {
+		if ctx.Err() != nil {
+			c.logger.Debugf("Stopping plugin %s: %v", c.plugin.Name, ctx.Err())
+			return
+		}
+
+		if !c.tenantAllowed(tenantID) {
+			continue
+		}
+
+		start := time.Now()
+		c.sampledDebugf("Running plugin %s for tenant %s", c.plugin.Name, tenantID)
+
+		metrics, err := c.run(ctx, tenantID)
+		if ctx.Err() != nil {
+			c.logger.Debugf("Plugin %s for tenant %s cancelled", c.plugin.Name, tenantID)
+			return
+		}
+		if err != nil {
+			c.logger.Errorf("Plugin %s failed for tenant %s: %v", c.plugin.Name, tenantID, err)
+			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			c.tenantFailed(tenantID, err)
+			continue
+		}
+
+		c.metricsLock.Lock()
+		c.metrics[tenantID] = metrics
+		c.metricsLock.Unlock()
+		c.cacheUpdated(tenantID, len(metrics))
+		c.recordStats(tenantID, start, 0, len(metrics))
+		c.tenantSucceeded(tenantID)
+
+		// Update scrape metrics
+		duration := time.Since(start).Seconds()
+		c.observeDuration(tenantID, duration)
+		c.lastScrapeTime.WithLabelValues(tenantID).Set(float64(time.Now().Unix()))
+		c.sampledDebugf("Completed plugin %s for tenant %s in %.2f seconds: %d series", c.plugin.Name, tenantID, duration, len(metrics))
+	}
+}
+
+// run runs the command for the tenant and parses its output. The command
+// only gets PATH, the tenant and the configured variables in its environment,
+// not the exporter's credentials.
+func (c *PluginCollector) run(ctx context.Context, tenantID string) ([]prometheus.Metric, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.plugin.GetTimeout())
+	defer cancel()
+
+	env := []string{
+		"PATH=" + os.Getenv("PATH"),
+		"ENTRA_TENANT_ID=" + tenantID,
+		"ENTRA_PLUGIN=" + c.plugin.Name,
+	}
+	for name, value := range c.plugin.Env {
+		env = append(env, name+"="+value)
+	}
+	if c.plugin.GraphToken {
+		cred, err := newCredential(tenantID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create credential: %v", err)
+		}
+		token, err := cred.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{c.collectorConfig.GetGraphScope()}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get Graph token: %v", err)
+		}
+		env = append(env, "ENTRA_GRAPH_TOKEN="+token.Token)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.plugin.Command[0], c.plugin.Command[1:]...)
+	cmd.Env = env
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("timed out after %s", c.plugin.GetTimeout())
+		}
+		return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(&stdout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse output: %v", err)
+	}
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var metrics []prometheus.Metric
+	for _, name := range names {
+		familyMetrics, err := constMetrics(families[name], tenantID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid metric %s: %v", name, err)
+		}
+		metrics = append(metrics, familyMetrics...)
+	}
+	return metrics, nil
+}
+
+// constMetrics turns a parsed metric family into constant metrics, the
+// tenant_id label is added unless the plugin set it
+func constMetrics(family *dto.MetricFamily, tenantID string) ([]prometheus.Metric, error) {
+	var metrics []prometheus.Metric
+	for _, m := range family.GetMetric() {
+		var labelNames, labelValues []string
+		for _, label := range m.GetLabel() {
+			labelNames = append(labelNames, label.GetName())
+			labelValues = append(labelValues, label.GetValue())
+		}
+		if !slices.Contains(labelNames, "tenant_id") {
+			labelNames = append(labelNames, "tenant_id")
+			labelValues = append(labelValues, tenantID)
+		}
+		desc := prometheus.NewDesc(family.GetName(), family.GetHelp(), labelNames, nil)
+
+		var metric prometheus.Metric
+		var err error
+		switch family.GetType() {
+		case dto.MetricType_COUNTER:
+			metric, err = prometheus.NewConstMetric(desc, prometheus.CounterValue, m.GetCounter().GetValue(), labelValues...)
+		case dto.MetricType_GAUGE:
+			metric, err = prometheus.NewConstMetric(desc, prometheus.GaugeValue, m.GetGauge().GetValue(), labelValues...)
+		case dto.MetricType_HISTOGRAM:
+			buckets := map[float64]uint64{}
+			for _, bucket := range m.GetHistogram().GetBucket() {
+				buckets[bucket.GetUpperBound()] = bucket.GetCumulativeCount()
+			}
+			metric, err = prometheus.NewConstHistogram(desc, m.GetHistogram().GetSampleCount(), m.GetHistogram().GetSampleSum(), buckets, labelValues...)
+		case dto.MetricType_SUMMARY:
+			quantiles := map[float64]float64{}
+			for _, quantile := range m.GetSummary().GetQuantile() {
+				quantiles[quantile.GetQuantile()] = quantile.GetValue()
+			}
+			metric, err = prometheus.NewConstSummary(desc, m.GetSummary().GetSampleCount(), m.GetSummary().GetSampleSum(), quantiles, labelValues...)
+		default:
+			metric, err = prometheus.NewConstMetric(desc, prometheus.UntypedValue, m.GetUntyped().GetValue(), labelValues...)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if m.TimestampMs != nil {
+			metric = prometheus.NewMetricWithTimestamp(time.UnixMilli(m.GetTimestampMs()), metric)
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics, nil
+}
@@ -0,0 +1,34 @@
+package collector
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/your-username/entra-exporter/config"
+)
+
+// redact applies the configured privacy redaction to a personal value like a
+// user principal name, in metric labels and inventory rows alike. Hashed
+// values stay joinable between metrics, sinks and restarts, dropped values
+// leave the label out and the column empty.
+func (c *BaseCollector) redact(value string) string {
+	if value == "" {
+		return value
+	}
+
+	switch c.config.Privacy.Redaction {
+	case config.RedactionHash:
+		if key := c.config.Privacy.HashKey; key != "" {
+			mac := hmac.New(sha256.New, []byte(key))
+			mac.Write([]byte(value))
+			return hex.EncodeToString(mac.Sum(nil)[:8])
+		}
+		sum := sha256.Sum256([]byte(value))
+		return hex.EncodeToString(sum[:8])
+	case config.RedactionDrop:
+		return ""
+	default:
+		return value
+	}
+}
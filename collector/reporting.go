@@ -0,0 +1,94 @@
+package collector
+
+import (
+	"errors"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	abstractions "github.com/microsoft/kiota-abstractions-go"
+)
+
+// ErrorReporter forwards errors which need attention beyond the Prometheus
+// alerts to an error tracking service
+type ErrorReporter interface {
+	// ReportPanic reports a recovered panic of a collector
+	ReportPanic(collector string, recovered interface{}, stack []byte)
+
+	// ReportAuthFailure reports a tenant whose authentication keeps failing
+	ReportAuthFailure(collector, tenantID string, failures int, err error)
+
+	// Flush waits until queued reports are sent or the timeout passed
+	Flush(timeout time.Duration)
+}
+
+var (
+	errorReporter     ErrorReporter
+	errorReporterLock sync.RWMutex
+)
+
+// SetErrorReporter sets the reporter used by all collectors, nil disables reporting
+func SetErrorReporter(reporter ErrorReporter) {
+	errorReporterLock.Lock()
+	defer errorReporterLock.Unlock()
+	errorReporter = reporter
+}
+
+// getErrorReporter returns the configured reporter, nil if reporting is disabled
+func getErrorReporter() ErrorReporter {
+	errorReporterLock.RLock()
+	defer errorReporterLock.RUnlock()
+	return errorReporter
+}
+
+// reportPanic forwards a recovered panic, must be called from the deferred
+// function which recovered it to capture the stack of the panic
+func reportPanic(collector string, recovered interface{}) {
+	if reporter := getErrorReporter(); reporter != nil {
+		reporter.ReportPanic(collector, recovered, debug.Stack())
+	}
+}
+
+// isAuthError returns if the error is caused by failed authentication or
+// missing permissions of the tenant
+func isAuthError(err error) bool {
+	var authErr *azidentity.AuthenticationFailedError
+	if errors.As(err, &authErr) {
+		return true
+	}
+
+	var apiErr abstractions.ApiErrorable
+	if errors.As(err, &apiErr) {
+		switch apiErr.GetStatusCode() {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return true
+		}
+	}
+	return false
+}
+
+// checkAuthFailure counts consecutive auth failures of the tenant and reports
+// them once the threshold is reached
+func (c *BaseCollector) checkAuthFailure(tenantID string, err error) {
+	c.lastErrorsLock.Lock()
+	if !isAuthError(err) {
+		delete(c.authFailures, tenantID)
+		c.lastErrorsLock.Unlock()
+		return
+	}
+	c.authFailures[tenantID]++
+	failures := c.authFailures[tenantID]
+	c.lastErrorsLock.Unlock()
+
+	// Only reported once until the tenant recovers
+	if failures != c.config.ErrorReporting.GetAuthFailureThreshold() {
+		return
+	}
+
+	c.logger.Errorf("Authentication for tenant %s failed %d times in a row", tenantID, failures)
+	if reporter := getErrorReporter(); reporter != nil {
+		reporter.ReportAuthFailure(c.name, tenantID, failures, err)
+	}
+}
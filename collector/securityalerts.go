@@ -0,0 +1,194 @@
+package collector
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	securitymodels "github.com/microsoftgraph/msgraph-sdk-go/models/security"
+	"github.com/microsoftgraph/msgraph-sdk-go/security"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/entra-exporter/config"
+)
+
+// alertSeverities are the severities exported even without unresolved alerts
+var alertSeverities = []string{"informational", "low", "medium", "high"}
+
+// unresolvedAlerts counts the unresolved alerts of a severity
+type unresolvedAlerts struct {
+	count  int
+	oldest time.Time
+}
+
+// SecurityAlertsCollector collects the backlog of unresolved security alerts
+type SecurityAlertsCollector struct {
+	*BaseCollector
+
+	// Unresolved alerts by severity per tenant
+	alertsLock sync.RWMutex
+	alerts     map[string]map[string]unresolvedAlerts
+
+	// Metrics
+	unresolvedTotal *gaugeVec
+	oldestAge       *gaugeVec
+}
+
+// NewSecurityAlertsCollector creates a new SecurityAlertsCollector
+func NewSecurityAlertsCollector(ctx context.Context, config *config.Config, logger *logrus.Entry) *SecurityAlertsCollector {
+	c := &SecurityAlertsCollector{
+		BaseCollector: NewBaseCollector("securityAlerts", config.Collector.SecurityAlerts, config, logger),
+		alerts:        map[string]map[string]unresolvedAlerts{},
+		unresolvedTotal: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_security_alerts_unresolved_total",
+				Help: "Number of unresolved security alerts by severity",
+			},
+			[]string{"tenant_id", "severity"},
+		),
+		oldestAge: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_security_alerts_oldest_unresolved_age_seconds",
+				Help: "Age of the oldest unresolved security alert by severity",
+			},
+			[]string{"tenant_id", "severity"},
+		),
+	}
+
+	// Start background collection
+	c.StartCacheInvalidator(ctx, c.collect)
+
+	return c
+}
+
+// Describe implements prometheus.Collector
+func (c *SecurityAlertsCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.BaseCollector.Describe(ch)
+	c.unresolvedTotal.Describe(ch)
+	c.oldestAge.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *SecurityAlertsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.BaseCollector.Collect(ch)
+
+	c.alertsLock.RLock()
+	defer c.alertsLock.RUnlock()
+
+	// Rebuild the metrics from the cache so severities without alerts lose their age
+	unresolvedTotal := c.unresolvedTotal.vec()
+	oldestAge := c.oldestAge.vec()
+
+	// The age is relative to the scrape so it grows between collections
+	now := time.Now()
+
+	for tenantID, alerts := range c.alerts {
+		if c.isCacheExpired(tenantID) {
+			continue
+		}
+
+		for _, severity := range alertSeverities {
+			unresolvedTotal.WithLabelValues(tenantID, severity).Set(0)
+		}
+		for severity, unresolved := range alerts {
+			unresolvedTotal.WithLabelValues(tenantID, severity).Set(float64(unresolved.count))
+			oldestAge.WithLabelValues(tenantID, severity).Set(now.Sub(unresolved.oldest).Seconds())
+		}
+	}
+
+	c.collectCached(ch, unresolvedTotal, oldestAge)
+}
+
+// collect gets the unresolved alerts of all tenants
+func (c *SecurityAlertsCollector) collect(ctx context.Context) {
+	for _, tenantID := range c.GetTenants() {
+		if ctx.Err() != nil {
+			c.logger.Debugf("Stopping security alerts collection: %v", ctx.Err())
+			return
+		}
+
+		if !c.tenantAllowed(tenantID) {
+			continue
+		}
+
+		start := time.Now()
+		c.sampledDebugf("Collecting unresolved security alerts for tenant %s", tenantID)
+
+		client, err := c.GetGraphClient(ctx, tenantID)
+		if err != nil {
+			c.logger.Errorf("Failed to get Graph client for tenant %s: %v", tenantID, err)
+			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			c.tenantFailed(tenantID, err)
+			continue
+		}
+
+		filter := "status ne 'resolved'"
+		if extra := c.collectorConfig.Filter; extra != "" {
+			filter += " and (" + extra + ")"
+		}
+		reqConfig := security.Alerts_v2RequestBuilderGetRequestConfiguration{
+			QueryParameters: &security.Alerts_v2RequestBuilderGetQueryParameters{
+				Filter: &filter,
+				Select: []string{"id", "severity", "status", "createdDateTime"},
+			},
+		}
+
+		alerts := map[string]unresolvedAlerts{}
+		total := 0
+		pages, err := readPages(ctx, c.BaseCollector, tenantID, "alerts_v2", func(ctx context.Context, nextLink string) ([]securitymodels.Alertable, *string, error) {
+			builder := client.Security().Alerts_v2()
+			requestConfig := &reqConfig
+			if nextLink != "" {
+				builder = builder.WithUrl(nextLink)
+				requestConfig = nil
+			}
+
+			result, err := builder.Get(ctx, requestConfig)
+			if err != nil {
+				return nil, nil, err
+			}
+			return result.GetValue(), result.GetOdataNextLink(), nil
+		}, func(alert securitymodels.Alertable) {
+			total++
+			if alert.GetCreatedDateTime() == nil {
+				return
+			}
+			severity := "unknown"
+			if alert.GetSeverity() != nil {
+				severity = alert.GetSeverity().String()
+			}
+			unresolved := alerts[severity]
+			unresolved.count++
+			if unresolved.oldest.IsZero() || alert.GetCreatedDateTime().Before(unresolved.oldest) {
+				unresolved.oldest = *alert.GetCreatedDateTime()
+			}
+			alerts[severity] = unresolved
+		})
+
+		if ctx.Err() != nil {
+			c.logger.Debugf("Security alerts collection for tenant %s cancelled", tenantID)
+			return
+		}
+
+		// Partial counts would hide the oldest alerts, keep the previous ones
+		if err != nil {
+			c.logger.WithFields(graphErrorFields(err)).Errorf("Failed to get security alerts for tenant %s: %v", tenantID, graphErrorMessage(err))
+			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			c.tenantFailed(tenantID, err)
+			continue
+		}
+
+		c.alertsLock.Lock()
+		c.alerts[tenantID] = alerts
+		c.alertsLock.Unlock()
+		c.cacheUpdated(tenantID, total)
+		c.recordStats(tenantID, start, pages, total)
+		c.tenantSucceeded(tenantID)
+
+		// Update scrape metrics
+		duration := time.Since(start).Seconds()
+		c.observeDuration(tenantID, duration)
+		c.lastScrapeTime.WithLabelValues(tenantID).Set(float64(time.Now().Unix()))
+		c.sampledDebugf("Completed security alerts collection for tenant %s in %.2f seconds: %d unresolved alerts", tenantID, duration, total)
+	}
+}
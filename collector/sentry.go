@@ -0,0 +1,60 @@
+package collector
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/your-username/entra-exporter/config"
+)
+
+// sentryReporter reports errors to Sentry
+type sentryReporter struct{}
+
+// NewSentryReporter creates an error reporter sending to the configured Sentry DSN
+func NewSentryReporter(cfg config.SentryConfig, release string) (ErrorReporter, error) {
+	err := sentry.Init(sentry.ClientOptions{
+		Dsn:         cfg.DSN,
+		Environment: cfg.Environment,
+		Release:     release,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Sentry: %w", err)
+	}
+	return &sentryReporter{}, nil
+}
+
+// ReportPanic implements ErrorReporter
+func (r *sentryReporter) ReportPanic(collector string, recovered interface{}, stack []byte) {
+	hub := sentry.CurrentHub().Clone()
+	hub.ConfigureScope(func(scope *sentry.Scope) {
+		scope.SetTag("collector", collector)
+		scope.SetLevel(sentry.LevelFatal)
+		scope.SetContext("panic", sentry.Context{"stack": string(stack)})
+	})
+
+	if err, ok := recovered.(error); ok {
+		hub.CaptureException(fmt.Errorf("panic in %s collector: %w", collector, err))
+		return
+	}
+	hub.CaptureException(fmt.Errorf("panic in %s collector: %v", collector, recovered))
+}
+
+// ReportAuthFailure implements ErrorReporter
+func (r *sentryReporter) ReportAuthFailure(collector, tenantID string, failures int, err error) {
+	hub := sentry.CurrentHub().Clone()
+	hub.ConfigureScope(func(scope *sentry.Scope) {
+		scope.SetTag("collector", collector)
+		scope.SetTag("tenant_id", tenantID)
+		scope.SetExtra("consecutive_failures", failures)
+		// Group by tenant instead of by the error message, which contains request IDs
+		scope.SetFingerprint([]string{"auth-failure", tenantID})
+	})
+
+	hub.CaptureException(fmt.Errorf("authentication for tenant %s failed %d times in a row: %w", tenantID, failures, err))
+}
+
+// Flush implements ErrorReporter
+func (r *sentryReporter) Flush(timeout time.Duration) {
+	sentry.Flush(timeout)
+}
@@ -0,0 +1,396 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/auditlogs"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/entra-exporter/config"
+)
+
+// signInErrorFamilies groups the common sign-in error codes, see
+// https://learn.microsoft.com/entra/identity-platform/reference-error-codes
+var signInErrorFamilies = map[int32]string{
+	50034:   "user_not_found",
+	50053:   "locked",
+	50055:   "password_expired",
+	50056:   "invalid_credentials",
+	50057:   "disabled",
+	50126:   "invalid_credentials",
+	50074:   "mfa_required",
+	50076:   "mfa_required",
+	50079:   "mfa_required",
+	50158:   "mfa_required",
+	500121:  "mfa_failed",
+	50140:   "interrupt",
+	50097:   "device_auth_required",
+	53000:   "conditional_access",
+	53001:   "conditional_access",
+	53002:   "conditional_access",
+	53003:   "conditional_access",
+	530032:  "conditional_access",
+	7000215: "invalid_credentials",
+}
+
+// signInErrorFamily returns the family of a sign-in error code
+func signInErrorFamily(code int32) string {
+	if family, exists := signInErrorFamilies[code]; exists {
+		return family
+	}
+	return "other"
+}
+
+// signInCountryKey groups the sign-ins of a country by risk state
+type signInCountryKey struct {
+	country   string
+	riskState string
+}
+
+// signInPolicyKey groups the evaluations of a conditional access policy by result
+type signInPolicyKey struct {
+	policyID string
+	result   string
+}
+
+// signInRiskKey groups risky sign-ins by aggregated risk level and detection type
+type signInRiskKey struct {
+	riskLevel     string
+	detectionType string
+}
+
+// signInStats aggregates the sign-ins of a tenant within the window
+type signInStats struct {
+	total int
+
+	// Failed sign-ins by error code
+	failures map[int32]int
+
+	// Sign-ins by country, the risk state is only set with riskStateLabel
+	countries map[signInCountryKey]int
+
+	// Conditional access policy evaluations by result and the policy names by ID
+	policies    map[signInPolicyKey]int
+	policyNames map[string]string
+
+	// Risky sign-ins by risk level and detection type
+	risky map[signInRiskKey]int
+}
+
+// SignInsCollector collects sign-in log metrics over a sliding window
+type SignInsCollector struct {
+	*BaseCollector
+
+	// Aggregated sign-ins per tenant
+	statsLock sync.RWMutex
+	stats     map[string]*signInStats
+
+	// Metrics
+	signInsTotal  *gaugeVec
+	failuresTotal *gaugeVec
+	countryTotal  *countVec
+	policyResults *gaugeVec
+	riskyTotal    *gaugeVec
+	window        prometheus.Gauge
+}
+
+// NewSignInsCollector creates a new SignInsCollector
+func NewSignInsCollector(ctx context.Context, config *config.Config, logger *logrus.Entry) *SignInsCollector {
+	countryLabels := []string{"tenant_id", "country"}
+	if config.Collector.SignIns.RiskStateLabel {
+		countryLabels = append(countryLabels, "risk_state")
+	}
+
+	c := &SignInsCollector{
+		BaseCollector: NewBaseCollector("signIns", config.Collector.SignIns.CollectorConfig, config, logger),
+		stats:         map[string]*signInStats{},
+		signInsTotal: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_signins_total",
+				Help: "Number of sign-ins within the window",
+			},
+			[]string{"tenant_id"},
+		),
+		failuresTotal: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_signin_failures_total",
+				Help: "Number of failed sign-ins within the window by error code",
+			},
+			[]string{"tenant_id", "error_code", "family"},
+		),
+		countryTotal: newCountVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_signins_by_country_total",
+				Help: "Number of sign-ins within the window by country, countries below the minimum count are merged into \"other\"",
+			},
+			countryLabels,
+		),
+		policyResults: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_signin_conditional_access_results_total",
+				Help: "Number of conditional access policy evaluations of the sign-ins within the window by result",
+			},
+			[]string{"tenant_id", "policy_id", "policy", "result"},
+		),
+		riskyTotal: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_signins_risky_total",
+				Help: "Number of risky sign-ins within the window by aggregated risk level and detection type",
+			},
+			[]string{"tenant_id", "risk_level", "detection_type"},
+		),
+		window: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "entraid_signins_window_seconds",
+				Help: "Sliding window of the sign-in counts in seconds",
+			},
+		),
+	}
+	c.window.Set(config.Collector.SignIns.GetWindow().Seconds())
+
+	// Start background collection
+	c.StartCacheInvalidator(ctx, c.collect)
+
+	return c
+}
+
+// Describe implements prometheus.Collector
+func (c *SignInsCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.BaseCollector.Describe(ch)
+	c.signInsTotal.Describe(ch)
+	c.failuresTotal.Describe(ch)
+	c.countryTotal.Describe(ch)
+	c.policyResults.Describe(ch)
+	c.riskyTotal.Describe(ch)
+	c.window.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *SignInsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.BaseCollector.Collect(ch)
+
+	c.statsLock.RLock()
+	defer c.statsLock.RUnlock()
+
+	// Rebuild the metrics from the cache so error codes no longer seen disappear
+	signInsTotal := c.signInsTotal.vec()
+	failuresTotal := c.failuresTotal.vec()
+	policyResults := c.policyResults.vec()
+	riskyTotal := c.riskyTotal.vec()
+
+	// Countries below the minimum count are merged per Collect, so concurrent
+	// Gathers don't add to the same "other" series
+	countryTotal := c.countryTotal.counts()
+
+	for tenantID, stats := range c.stats {
+		if c.isCacheExpired(tenantID) {
+			continue
+		}
+
+		signInsTotal.WithLabelValues(tenantID).Set(float64(stats.total))
+		for code, failures := range stats.failures {
+			failuresTotal.WithLabelValues(tenantID, strconv.Itoa(int(code)), signInErrorFamily(code)).Set(float64(failures))
+		}
+
+		for key, signIns := range stats.countries {
+			country := key.country
+			if signIns < c.config.Collector.SignIns.MinCount {
+				country = "other"
+			}
+
+			labels := []string{tenantID, country}
+			if c.config.Collector.SignIns.RiskStateLabel {
+				labels = append(labels, key.riskState)
+			}
+			countryTotal.Add(float64(signIns), labels...)
+		}
+
+		for key, evaluations := range stats.policies {
+			policyResults.WithLabelValues(tenantID, key.policyID, stats.policyNames[key.policyID], key.result).Set(float64(evaluations))
+		}
+
+		for key, signIns := range stats.risky {
+			riskyTotal.WithLabelValues(tenantID, key.riskLevel, key.detectionType).Set(float64(signIns))
+		}
+	}
+
+	c.collectCached(ch, signInsTotal, failuresTotal, countryTotal, policyResults, riskyTotal)
+	c.window.Collect(ch)
+}
+
+// collect aggregates the sign-ins of the window
+func (c *SignInsCollector) collect(ctx context.Context) {
+	window := c.config.Collector.SignIns.GetWindow()
+
+	for _, tenantID := range c.GetTenants() {
+		if ctx.Err() != nil {
+			c.logger.Debugf("Stopping sign-ins collection: %v", ctx.Err())
+			return
+		}
+
+		if !c.tenantAllowed(tenantID) {
+			continue
+		}
+
+		start := time.Now()
+		since := start.Add(-window).UTC()
+		c.sampledDebugf("Collecting sign-ins since %s for tenant %s", since.Format(time.RFC3339), tenantID)
+
+		client, err := c.GetGraphClient(ctx, tenantID)
+		if err != nil {
+			c.logger.Errorf("Failed to get Graph client for tenant %s: %v", tenantID, err)
+			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			c.tenantFailed(tenantID, err)
+			continue
+		}
+
+		filter := fmt.Sprintf("createdDateTime ge %s", since.Format(time.RFC3339))
+		if c.collectorConfig.Filter != "" {
+			filter = fmt.Sprintf("%s and (%s)", filter, c.collectorConfig.Filter)
+		}
+		pageSize := int32(1000)
+		reqConfig := auditlogs.SignInsRequestBuilderGetRequestConfiguration{
+			QueryParameters: &auditlogs.SignInsRequestBuilderGetQueryParameters{
+				Top:    &pageSize,
+				Filter: &filter,
+				Select: []string{"id", "createdDateTime", "status", "location", "riskState", "riskLevelAggregated", "riskEventTypes", "appliedConditionalAccessPolicies"},
+			},
+		}
+
+		pageCtx, cancelPages := context.WithCancel(ctx)
+		pages := prefetchPages(pageCtx, func(ctx context.Context, nextLink string) ([]models.SignInable, *string, error) {
+			builder := client.AuditLogs().SignIns()
+			requestConfig := &reqConfig
+			if nextLink != "" {
+				builder = builder.WithUrl(nextLink)
+				requestConfig = nil
+			}
+
+			result, err := builder.Get(ctx, requestConfig)
+			if err != nil {
+				return nil, nil, err
+			}
+			return result.GetValue(), result.GetOdataNextLink(), nil
+		})
+
+		stats := &signInStats{
+			failures:    map[int32]int{},
+			countries:   map[signInCountryKey]int{},
+			policies:    map[signInPolicyKey]int{},
+			policyNames: map[string]string{},
+			risky:       map[signInRiskKey]int{},
+		}
+		var pageErr error
+		tooManyObjects := false
+		pageCount := 0
+		for page := range pages {
+			pageCount = page.number
+			c.checkSlowPage(tenantID, "signIns", page.number, page.duration)
+			if page.err != nil {
+				if ctx.Err() != nil {
+					cancelPages()
+					c.logger.Debugf("Sign-ins collection for tenant %s cancelled on page %d", tenantID, page.number)
+					return
+				}
+				c.logger.WithFields(graphErrorFields(page.err)).Errorf("Failed to get page %d of sign-ins for tenant %s: %v", page.number, tenantID, graphErrorMessage(page.err))
+				c.scrapeErrors.WithLabelValues(tenantID).Inc()
+				pageErr = page.err
+				break
+			}
+
+			for _, signIn := range page.items {
+				stats.add(signIn, c.config.Collector.SignIns.RiskStateLabel)
+			}
+			c.sampledDebugf("Retrieved %d sign-ins in page %d for tenant %s", len(page.items), page.number, tenantID)
+
+			if c.exceedsMaxObjects(tenantID, stats.total) {
+				tooManyObjects = true
+				break
+			}
+		}
+		cancelPages()
+
+		if ctx.Err() != nil {
+			c.logger.Debugf("Sign-ins collection for tenant %s cancelled", tenantID)
+			return
+		}
+
+		if tooManyObjects {
+			c.tenantAborted(tenantID)
+			continue
+		}
+
+		// Partial counts would look like a drop in sign-ins, keep the previous ones
+		if pageErr != nil {
+			c.tenantFailed(tenantID, pageErr)
+			continue
+		}
+
+		c.statsLock.Lock()
+		c.stats[tenantID] = stats
+		c.statsLock.Unlock()
+		c.cacheUpdated(tenantID, stats.total)
+		c.recordStats(tenantID, start, pageCount, stats.total)
+		c.tenantSucceeded(tenantID)
+
+		// Update scrape metrics
+		duration := time.Since(start).Seconds()
+		c.observeDuration(tenantID, duration)
+		c.lastScrapeTime.WithLabelValues(tenantID).Set(float64(time.Now().Unix()))
+		c.sampledDebugf("Completed sign-ins collection for tenant %s in %.2f seconds: %d sign-ins", tenantID, duration, stats.total)
+	}
+}
+
+// add counts a sign-in
+func (s *signInStats) add(signIn models.SignInable, riskState bool) {
+	s.total++
+
+	// Error code 0 is a successful sign-in
+	if status := signIn.GetStatus(); status != nil && status.GetErrorCode() != nil && *status.GetErrorCode() != 0 {
+		s.failures[*status.GetErrorCode()]++
+	}
+
+	key := signInCountryKey{country: "unknown"}
+	if location := signIn.GetLocation(); location != nil && location.GetCountryOrRegion() != nil && *location.GetCountryOrRegion() != "" {
+		key.country = *location.GetCountryOrRegion()
+	}
+	if riskState {
+		key.riskState = "unknown"
+		if signIn.GetRiskState() != nil {
+			key.riskState = signIn.GetRiskState().String()
+		}
+	}
+	s.countries[key]++
+
+	for _, policy := range signIn.GetAppliedConditionalAccessPolicies() {
+		if policy.GetId() == nil {
+			continue
+		}
+		policyKey := signInPolicyKey{policyID: *policy.GetId(), result: "unknown"}
+		if policy.GetResult() != nil {
+			policyKey.result = policy.GetResult().String()
+		}
+		s.policies[policyKey]++
+		s.policyNames[policyKey.policyID] = stringValue(policy.GetDisplayName(), "")
+	}
+
+	// Only low, medium and high are risky, hidden means the tenant lacks the
+	// license to see the risk level
+	if riskLevel := signIn.GetRiskLevelAggregated(); riskLevel != nil {
+		switch *riskLevel {
+		case models.LOW_RISKLEVEL, models.MEDIUM_RISKLEVEL, models.HIGH_RISKLEVEL:
+			detectionTypes := signIn.GetRiskEventTypes()
+			if len(detectionTypes) == 0 {
+				s.risky[signInRiskKey{riskLevel: riskLevel.String(), detectionType: "unknown"}]++
+			}
+			for _, detectionType := range detectionTypes {
+				s.risky[signInRiskKey{riskLevel: riskLevel.String(), detectionType: detectionType.String()}]++
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package collector
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/sirupsen/logrus"
+)
+
+// tokenExpiries holds the expiry of the last Graph token per tenant
+var (
+	tokenExpiries     = map[string]time.Time{}
+	tokenExpiriesLock sync.RWMutex
+)
+
+// expiryTrackingCredential records the expiry of every token it hands out
+type expiryTrackingCredential struct {
+	azcore.TokenCredential
+	tenantID string
+}
+
+// GetToken implements azcore.TokenCredential
+func (c *expiryTrackingCredential) GetToken(ctx context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	token, err := c.TokenCredential.GetToken(ctx, options)
+	if err == nil {
+		tokenExpiriesLock.Lock()
+		tokenExpiries[c.tenantID] = token.ExpiresOn
+		tokenExpiriesLock.Unlock()
+	}
+	return token, err
+}
+
+// DumpState logs the internal state of the exporter and all collectors, to
+// debug stuck collections without restarting
+func DumpState(logger *logrus.Entry) {
+	logger.WithFields(logrus.Fields{
+		"goroutines": runtime.NumGoroutine(),
+		"standby":    standby.Load(),
+		"paused":     paused.Load(),
+	}).Info("State dump")
+
+	for _, c := range registeredCollectors() {
+		// The lock is held during collections, a stuck one must not block the dump
+		var lastCollect time.Time
+		if c.TryLock() {
+			lastCollect = c.lastCollect
+			c.Unlock()
+		}
+
+		fields := logrus.Fields{
+			"collector": c.name,
+			"running":   c.running.Load(),
+		}
+		if !lastCollect.IsZero() {
+			fields["lastCollect"] = lastCollect.Format(time.RFC3339)
+		}
+		logger.WithFields(fields).Info("Collector state")
+
+		caches := c.cacheInfos()
+		lastErrors := c.lastErrorsByTenant()
+		tenants := make([]string, 0, len(caches))
+		for tenantID := range caches {
+			tenants = append(tenants, tenantID)
+		}
+		for tenantID := range lastErrors {
+			if _, exists := caches[tenantID]; !exists {
+				tenants = append(tenants, tenantID)
+			}
+		}
+		sort.Strings(tenants)
+
+		for _, tenantID := range tenants {
+			fields := logrus.Fields{
+				"collector": c.name,
+				"tenant":    tenantID,
+			}
+			if info, exists := caches[tenantID]; exists {
+				fields["cachedObjects"] = info.objects
+				fields["cacheAge"] = time.Since(info.updated).Round(time.Second).String()
+			}
+			if lastError, exists := lastErrors[tenantID]; exists {
+				fields["lastError"] = lastError.Message
+				fields["lastErrorTime"] = lastError.Time.Format(time.RFC3339)
+			}
+			logger.WithFields(fields).Info("Tenant state")
+		}
+	}
+
+	tokenExpiriesLock.RLock()
+	defer tokenExpiriesLock.RUnlock()
+	for tenantID, expiry := range tokenExpiries {
+		logger.WithFields(logrus.Fields{
+			"tenant":    tenantID,
+			"expiresIn": time.Until(expiry).Round(time.Second).String(),
+		}).Info("Graph token state")
+	}
+}
@@ -0,0 +1,129 @@
+package collector
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// requestWindowMinutes is the time span of the Graph request counts per tenant
+const requestWindowMinutes = 60
+
+// requestBucket counts the Graph requests of a tenant within one minute
+type requestBucket struct {
+	minute    int64
+	requests  int
+	throttled int
+}
+
+// requestWindow counts the Graph requests per tenant over the last hour
+type requestWindow struct {
+	sync.Mutex
+	tenants map[string]*[requestWindowMinutes]requestBucket
+}
+
+// tenantRequests is shared by the Graph clients of all collectors
+var tenantRequests = &requestWindow{
+	tenants: map[string]*[requestWindowMinutes]requestBucket{},
+}
+
+// record counts a Graph request of the tenant
+func (w *requestWindow) record(tenantID string, statusCode int) {
+	w.Lock()
+	defer w.Unlock()
+
+	buckets, exists := w.tenants[tenantID]
+	if !exists {
+		buckets = &[requestWindowMinutes]requestBucket{}
+		w.tenants[tenantID] = buckets
+	}
+
+	minute := time.Now().Unix() / 60
+	bucket := &buckets[minute%requestWindowMinutes]
+	if bucket.minute != minute {
+		*bucket = requestBucket{minute: minute}
+	}
+
+	bucket.requests++
+	if statusCode == http.StatusTooManyRequests {
+		bucket.throttled++
+	}
+}
+
+// counts returns the Graph requests and throttled requests of the tenant
+// within the last hour
+func (w *requestWindow) counts(tenantID string) (requests, throttled int) {
+	w.Lock()
+	defer w.Unlock()
+
+	buckets, exists := w.tenants[tenantID]
+	if !exists {
+		return 0, 0
+	}
+
+	minute := time.Now().Unix() / 60
+	for _, bucket := range buckets {
+		if minute-bucket.minute < requestWindowMinutes {
+			requests += bucket.requests
+			throttled += bucket.throttled
+		}
+	}
+	return requests, throttled
+}
+
+// TenantCollectorReport is the last collection of a collector for a tenant
+type TenantCollectorReport struct {
+	Collector       string           `json:"collector"`
+	LastRun         *time.Time       `json:"lastRun,omitempty"`
+	DurationSeconds float64          `json:"durationSeconds"`
+	Objects         int              `json:"objects"`
+	LastError       *CollectionError `json:"lastError,omitempty"`
+}
+
+// TenantReport summarizes the collections and Graph usage of a tenant
+type TenantReport struct {
+	TenantID                  string                  `json:"tenantId"`
+	Collectors                []TenantCollectorReport `json:"collectors"`
+	GraphRequestsLastHour     int                     `json:"graphRequestsLastHour"`
+	ThrottledRequestsLastHour int                     `json:"throttledRequestsLastHour"`
+}
+
+// TenantReports returns a report for every tenant known to the collectors
+func TenantReports() []TenantReport {
+	reports := map[string]*TenantReport{}
+
+	for _, c := range registeredCollectors() {
+		for _, tenant := range c.Status().Tenants {
+			report, exists := reports[tenant.TenantID]
+			if !exists {
+				report = &TenantReport{
+					TenantID:   tenant.TenantID,
+					Collectors: []TenantCollectorReport{},
+				}
+				reports[tenant.TenantID] = report
+			}
+
+			report.Collectors = append(report.Collectors, TenantCollectorReport{
+				Collector:       c.Name(),
+				LastRun:         tenant.LastRun,
+				DurationSeconds: tenant.DurationSeconds,
+				Objects:         tenant.Objects,
+				LastError:       tenant.LastError,
+			})
+		}
+	}
+
+	result := make([]TenantReport, 0, len(reports))
+	for _, report := range reports {
+		report.GraphRequestsLastHour, report.ThrottledRequestsLastHour = tenantRequests.counts(report.TenantID)
+		sort.Slice(report.Collectors, func(i, j int) bool {
+			return report.Collectors[i].Collector < report.Collectors[j].Collector
+		})
+		result = append(result, *report)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].TenantID < result[j].TenantID
+	})
+	return result
+}
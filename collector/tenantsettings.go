@@ -0,0 +1,199 @@
+package collector
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/entra-exporter/config"
+)
+
+// guestInviteRestrictions are the allowInvitesFrom values of the authorization policy
+var guestInviteRestrictions = []string{"none", "adminsAndGuestInviters", "adminsGuestInvitersAndAllMembers", "everyone"}
+
+// guestUserAccessLevels are the guest user access levels by guestUserRoleId
+var guestUserAccessLevels = map[string]string{
+	"a0b1b346-4d3e-4e8b-98f8-753987be4970": "member",
+	"10dae51f-b6af-4d18-a5f2-c1cc51b74e3c": "limited",
+	"2af84b1e-32c8-42b7-82bc-daa82404023b": "restricted",
+}
+
+// cachedTenantSettings holds the external collaboration settings of a tenant
+type cachedTenantSettings struct {
+	allowInvitesFrom string
+	guestAccessLevel string
+
+	// Boolean authorization policy settings by property name
+	settings map[string]bool
+}
+
+// TenantSettingsCollector collects the tenant's external collaboration
+// settings from the authorization policy
+type TenantSettingsCollector struct {
+	*BaseCollector
+
+	// Settings cache
+	settingsLock sync.RWMutex
+	settings     map[string]cachedTenantSettings
+
+	// Metrics
+	guestInviteRestriction *gaugeVec
+	guestUserAccessLevel   *gaugeVec
+	authorizationSetting   *gaugeVec
+}
+
+// NewTenantSettingsCollector creates a new TenantSettingsCollector
+func NewTenantSettingsCollector(ctx context.Context, config *config.Config, logger *logrus.Entry) *TenantSettingsCollector {
+	c := &TenantSettingsCollector{
+		BaseCollector: NewBaseCollector("tenantSettings", config.Collector.TenantSettings, config, logger),
+		settings:      map[string]cachedTenantSettings{},
+		guestInviteRestriction: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_tenant_guest_invite_restriction",
+				Help: "Who can invite guests, 1 for the configured allow_invites_from value and 0 for the others",
+			},
+			[]string{"tenant_id", "allow_invites_from"},
+		),
+		guestUserAccessLevel: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_tenant_guest_user_access_level",
+				Help: "Directory access of guest users, 1 for the configured access_level and 0 for the others",
+			},
+			[]string{"tenant_id", "access_level"},
+		),
+		authorizationSetting: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_tenant_authorization_setting",
+				Help: "Boolean settings of the tenant's authorization policy, 1 if enabled",
+			},
+			[]string{"tenant_id", "setting"},
+		),
+	}
+
+	// Start background collection
+	c.StartCacheInvalidator(ctx, c.collect)
+
+	return c
+}
+
+// Describe implements prometheus.Collector
+func (c *TenantSettingsCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.BaseCollector.Describe(ch)
+	c.guestInviteRestriction.Describe(ch)
+	c.guestUserAccessLevel.Describe(ch)
+	c.authorizationSetting.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *TenantSettingsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.BaseCollector.Collect(ch)
+
+	c.settingsLock.RLock()
+	defer c.settingsLock.RUnlock()
+
+	// Rebuild the metrics from the cache
+	guestInviteRestriction := c.guestInviteRestriction.vec()
+	guestUserAccessLevel := c.guestUserAccessLevel.vec()
+	authorizationSetting := c.authorizationSetting.vec()
+
+	for tenantID, settings := range c.settings {
+		if c.isCacheExpired(tenantID) {
+			continue
+		}
+
+		// Every known value is exported so a change is a step in two series
+		for _, restriction := range guestInviteRestrictions {
+			guestInviteRestriction.WithLabelValues(tenantID, restriction).Set(0)
+		}
+		guestInviteRestriction.WithLabelValues(tenantID, settings.allowInvitesFrom).Set(1)
+
+		for _, level := range guestUserAccessLevels {
+			guestUserAccessLevel.WithLabelValues(tenantID, level).Set(0)
+		}
+		guestUserAccessLevel.WithLabelValues(tenantID, settings.guestAccessLevel).Set(1)
+
+		for setting, enabled := range settings.settings {
+			authorizationSetting.WithLabelValues(tenantID, setting).Set(boolFloat(enabled))
+		}
+	}
+
+	c.collectCached(ch, guestInviteRestriction, guestUserAccessLevel, authorizationSetting)
+}
+
+// collect gets the authorization policy of all tenants
+func (c *TenantSettingsCollector) collect(ctx context.Context) {
+	for _, tenantID := range c.GetTenants() {
+		if ctx.Err() != nil {
+			c.logger.Debugf("Stopping tenant settings collection: %v", ctx.Err())
+			return
+		}
+
+		if !c.tenantAllowed(tenantID) {
+			continue
+		}
+
+		start := time.Now()
+		c.sampledDebugf("Collecting tenant settings for tenant %s", tenantID)
+
+		client, err := c.GetGraphClient(ctx, tenantID)
+		if err != nil {
+			c.logger.Errorf("Failed to get Graph client for tenant %s: %v", tenantID, err)
+			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			c.tenantFailed(tenantID, err)
+			continue
+		}
+
+		policy, err := client.Policies().AuthorizationPolicy().Get(ctx, nil)
+		if err != nil {
+			if ctx.Err() != nil {
+				c.logger.Debugf("Tenant settings collection for tenant %s cancelled", tenantID)
+				return
+			}
+			c.logger.WithFields(graphErrorFields(err)).Errorf("Failed to get authorization policy for tenant %s: %v", tenantID, graphErrorMessage(err))
+			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			c.tenantFailed(tenantID, err)
+			continue
+		}
+
+		settings := cachedTenantSettings{
+			allowInvitesFrom: "unknown",
+			guestAccessLevel: "unknown",
+			settings: map[string]bool{
+				"allowEmailVerifiedUsersToJoinOrganization": boolValue(policy.GetAllowEmailVerifiedUsersToJoinOrganization()),
+				"allowedToSignUpEmailBasedSubscriptions":    boolValue(policy.GetAllowedToSignUpEmailBasedSubscriptions()),
+				"allowedToUseSSPR":                          boolValue(policy.GetAllowedToUseSSPR()),
+				"allowUserConsentForRiskyApps":              boolValue(policy.GetAllowUserConsentForRiskyApps()),
+				"blockMsolPowerShell":                       boolValue(policy.GetBlockMsolPowerShell()),
+			},
+		}
+		if policy.GetAllowInvitesFrom() != nil {
+			settings.allowInvitesFrom = policy.GetAllowInvitesFrom().String()
+		}
+		if policy.GetGuestUserRoleId() != nil {
+			if level, exists := guestUserAccessLevels[policy.GetGuestUserRoleId().String()]; exists {
+				settings.guestAccessLevel = level
+			}
+		}
+		if permissions := policy.GetDefaultUserRolePermissions(); permissions != nil {
+			settings.settings["defaultUserRolePermissions.allowedToCreateApps"] = boolValue(permissions.GetAllowedToCreateApps())
+			settings.settings["defaultUserRolePermissions.allowedToCreateSecurityGroups"] = boolValue(permissions.GetAllowedToCreateSecurityGroups())
+			settings.settings["defaultUserRolePermissions.allowedToCreateTenants"] = boolValue(permissions.GetAllowedToCreateTenants())
+			settings.settings["defaultUserRolePermissions.allowedToReadOtherUsers"] = boolValue(permissions.GetAllowedToReadOtherUsers())
+		}
+
+		c.settingsLock.Lock()
+		c.settings[tenantID] = settings
+		c.settingsLock.Unlock()
+		c.cacheUpdated(tenantID, 1)
+		c.recordStats(tenantID, start, 1, 1)
+		c.tenantSucceeded(tenantID)
+
+		// Update scrape metrics
+		duration := time.Since(start).Seconds()
+		c.observeDuration(tenantID, duration)
+		c.lastScrapeTime.WithLabelValues(tenantID).Set(float64(time.Now().Unix()))
+		c.sampledDebugf("Completed tenant settings collection for tenant %s in %.2f seconds", tenantID, duration)
+	}
+}
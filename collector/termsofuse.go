@@ -0,0 +1,220 @@
+package collector
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	mgraph "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/identitygovernance"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/entra-exporter/config"
+)
+
+// agreementStats counts the users by their latest response to an agreement
+type agreementStats struct {
+	name    string
+	byState map[string]int
+}
+
+// userResponse is the latest recorded response of a user to an agreement
+type userResponse struct {
+	state    string
+	recorded time.Time
+}
+
+// TermsOfUseCollector collects terms of use acceptance metrics
+type TermsOfUseCollector struct {
+	*BaseCollector
+
+	// Agreements by ID per tenant
+	agreementsLock sync.RWMutex
+	agreements     map[string]map[string]agreementStats
+
+	// Metrics
+	usersTotal *gaugeVec
+}
+
+// NewTermsOfUseCollector creates a new TermsOfUseCollector
+func NewTermsOfUseCollector(ctx context.Context, config *config.Config, logger *logrus.Entry) *TermsOfUseCollector {
+	c := &TermsOfUseCollector{
+		BaseCollector: NewBaseCollector("termsOfUse", config.Collector.TermsOfUse, config, logger),
+		agreements:    map[string]map[string]agreementStats{},
+		usersTotal: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_terms_of_use_users_total",
+				Help: "Number of users by their latest response to the terms of use agreement",
+			},
+			[]string{"tenant_id", "agreement_id", "agreement", "state"},
+		),
+	}
+
+	// Start background collection
+	c.StartCacheInvalidator(ctx, c.collect)
+
+	return c
+}
+
+// Describe implements prometheus.Collector
+func (c *TermsOfUseCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.BaseCollector.Describe(ch)
+	c.usersTotal.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *TermsOfUseCollector) Collect(ch chan<- prometheus.Metric) {
+	c.BaseCollector.Collect(ch)
+
+	c.agreementsLock.RLock()
+	defer c.agreementsLock.RUnlock()
+
+	// Rebuild the metrics from the cache so removed agreements disappear
+	usersTotal := c.usersTotal.vec()
+
+	for tenantID, agreements := range c.agreements {
+		if c.isCacheExpired(tenantID) {
+			continue
+		}
+
+		for agreementID, stats := range agreements {
+			// Both states are exported so the acceptance rate is always defined
+			usersTotal.WithLabelValues(tenantID, agreementID, stats.name, "accepted").Set(0)
+			usersTotal.WithLabelValues(tenantID, agreementID, stats.name, "declined").Set(0)
+			for state, users := range stats.byState {
+				usersTotal.WithLabelValues(tenantID, agreementID, stats.name, state).Set(float64(users))
+			}
+		}
+	}
+
+	c.collectCached(ch, usersTotal)
+}
+
+// collect gets the agreements and their acceptances
+func (c *TermsOfUseCollector) collect(ctx context.Context) {
+	for _, tenantID := range c.GetTenants() {
+		if ctx.Err() != nil {
+			c.logger.Debugf("Stopping terms of use collection: %v", ctx.Err())
+			return
+		}
+
+		if !c.tenantAllowed(tenantID) {
+			continue
+		}
+
+		start := time.Now()
+		c.sampledDebugf("Collecting terms of use for tenant %s", tenantID)
+
+		client, err := c.GetGraphClient(ctx, tenantID)
+		if err != nil {
+			c.logger.Errorf("Failed to get Graph client for tenant %s: %v", tenantID, err)
+			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			c.tenantFailed(tenantID, err)
+			continue
+		}
+
+		agreementsResult, err := client.IdentityGovernance().TermsOfUse().Agreements().Get(ctx, &identitygovernance.TermsOfUseAgreementsRequestBuilderGetRequestConfiguration{
+			QueryParameters: &identitygovernance.TermsOfUseAgreementsRequestBuilderGetQueryParameters{
+				Select: []string{"id", "displayName"},
+			},
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				c.logger.Debugf("Terms of use collection for tenant %s cancelled", tenantID)
+				return
+			}
+			c.logger.WithFields(graphErrorFields(err)).Errorf("Failed to get terms of use agreements for tenant %s: %v", tenantID, graphErrorMessage(err))
+			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			c.tenantFailed(tenantID, err)
+			continue
+		}
+
+		// A failing agreement only drops its own series
+		agreements := map[string]agreementStats{}
+		var collectErr error
+		pages := 1
+		acceptances := 0
+		for _, agreement := range agreementsResult.GetValue() {
+			if agreement.GetId() == nil {
+				continue
+			}
+			agreementID := *agreement.GetId()
+
+			responses, agreementPages, err := c.fetchResponses(ctx, client, tenantID, agreementID)
+			pages += agreementPages
+			if err != nil {
+				if ctx.Err() != nil {
+					c.logger.Debugf("Terms of use collection for tenant %s cancelled", tenantID)
+					return
+				}
+				c.logger.WithFields(graphErrorFields(err)).Errorf("Failed to get acceptances of agreement %s for tenant %s: %v", agreementID, tenantID, graphErrorMessage(err))
+				c.scrapeErrors.WithLabelValues(tenantID).Inc()
+				collectErr = err
+				continue
+			}
+
+			stats := agreementStats{name: stringValue(agreement.GetDisplayName(), ""), byState: map[string]int{}}
+			for _, response := range responses {
+				stats.byState[response.state]++
+			}
+			agreements[agreementID] = stats
+			acceptances += len(responses)
+		}
+
+		c.agreementsLock.Lock()
+		c.agreements[tenantID] = agreements
+		c.agreementsLock.Unlock()
+		c.cacheUpdated(tenantID, acceptances)
+		c.recordStats(tenantID, start, pages, acceptances)
+
+		if collectErr != nil {
+			c.tenantFailed(tenantID, collectErr)
+		} else {
+			c.tenantSucceeded(tenantID)
+		}
+
+		// Update scrape metrics
+		duration := time.Since(start).Seconds()
+		c.observeDuration(tenantID, duration)
+		c.lastScrapeTime.WithLabelValues(tenantID).Set(float64(time.Now().Unix()))
+		c.sampledDebugf("Completed terms of use collection for tenant %s in %.2f seconds: %d agreements", tenantID, duration, len(agreements))
+	}
+}
+
+// fetchResponses returns the latest response of every user to the agreement,
+// users accepting again or per device have several acceptance records
+func (c *TermsOfUseCollector) fetchResponses(ctx context.Context, client *mgraph.GraphServiceClient, tenantID, agreementID string) (map[string]userResponse, int, error) {
+	responses := map[string]userResponse{}
+	reqConfig := identitygovernance.TermsOfUseAgreementsItemAcceptancesRequestBuilderGetRequestConfiguration{
+		QueryParameters: &identitygovernance.TermsOfUseAgreementsItemAcceptancesRequestBuilderGetQueryParameters{
+			Select: []string{"id", "userId", "state", "recordedDateTime"},
+		},
+	}
+	pages, err := readPages(ctx, c.BaseCollector, tenantID, "agreementAcceptances", func(ctx context.Context, nextLink string) ([]models.AgreementAcceptanceable, *string, error) {
+		builder := client.IdentityGovernance().TermsOfUse().Agreements().ByAgreementId(agreementID).Acceptances()
+		requestConfig := &reqConfig
+		if nextLink != "" {
+			builder = builder.WithUrl(nextLink)
+			requestConfig = nil
+		}
+
+		result, err := builder.Get(ctx, requestConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		return result.GetValue(), result.GetOdataNextLink(), nil
+	}, func(acceptance models.AgreementAcceptanceable) {
+		if acceptance.GetUserId() == nil || acceptance.GetState() == nil {
+			return
+		}
+		response := userResponse{state: acceptance.GetState().String()}
+		if acceptance.GetRecordedDateTime() != nil {
+			response.recorded = *acceptance.GetRecordedDateTime()
+		}
+		if previous, exists := responses[*acceptance.GetUserId()]; !exists || response.recorded.After(previous.recorded) {
+			responses[*acceptance.GetUserId()] = response
+		}
+	})
+	return responses, pages, err
+}
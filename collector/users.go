@@ -2,62 +2,346 @@ package collector
 
 import (
 	"context"
+	"fmt"
+	"iter"
+	"slices"
+	"strconv"
 	"sync"
 	"time"
 
+	mgraph "github.com/microsoftgraph/msgraph-sdk-go"
 	"github.com/microsoftgraph/msgraph-sdk-go/models"
 	"github.com/microsoftgraph/msgraph-sdk-go/users"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
 	"github.com/sirupsen/logrus"
 	"github.com/your-username/entra-exporter/config"
 )
 
+// cachedUser holds the user properties used for metric labels
+type cachedUser struct {
+	id                string
+	userPrincipalName string
+	displayName       string
+	accountEnabled    bool
+	userType          string
+	creationType      string
+	onPremisesSynced  bool
+
+	// Directory sync errors, e.g. duplicate proxy addresses
+	provisioningErrors []provisioningErrorKey
+
+	// Values of the configured extra properties, in their configured order
+	extra []string
+
+	// SKU IDs of the assigned licenses, only requested with licenses enabled
+	licenses []string
+
+	// Last interactive or non-interactive sign-in, or creation if the user
+	// never signed in, only requested with inactive guests enabled
+	lastActivity  time.Time
+	neverSignedIn bool
+
+	// Last password change, only requested with password age buckets
+	passwordChanged time.Time
+
+	// Object ID of the manager, only expanded with managers enabled
+	managerID string
+}
+
+// newCachedUser maps a Graph user into the slim cache representation
+func newCachedUser(user models.Userable, extraProperties []string) cachedUser {
+	cached := cachedUser{
+		id:                stringValue(user.GetId(), ""),
+		userPrincipalName: stringValue(user.GetUserPrincipalName(), ""),
+		displayName:       stringValue(user.GetDisplayName(), ""),
+		accountEnabled:    boolValue(user.GetAccountEnabled()),
+		userType:          stringValue(user.GetUserType(), "unknown"),
+		creationType:      stringValue(user.GetCreationType(), "unknown"),
+		onPremisesSynced:  boolValue(user.GetOnPremisesSyncEnabled()),
+	}
+	for _, provisioningError := range user.GetOnPremisesProvisioningErrors() {
+		cached.provisioningErrors = append(cached.provisioningErrors, provisioningErrorKey{
+			category: stringValue(provisioningError.GetCategory(), "unknown"),
+			property: stringValue(provisioningError.GetPropertyCausingError(), "unknown"),
+		})
+	}
+	if activity := user.GetSignInActivity(); activity != nil {
+		for _, signIn := range []*time.Time{activity.GetLastSignInDateTime(), activity.GetLastNonInteractiveSignInDateTime()} {
+			if signIn != nil && signIn.After(cached.lastActivity) {
+				cached.lastActivity = *signIn
+			}
+		}
+	}
+	if cached.lastActivity.IsZero() && user.GetCreatedDateTime() != nil {
+		cached.lastActivity = *user.GetCreatedDateTime()
+		cached.neverSignedIn = true
+	}
+	if user.GetLastPasswordChangeDateTime() != nil {
+		cached.passwordChanged = *user.GetLastPasswordChangeDateTime()
+	}
+	if manager := user.GetManager(); manager != nil {
+		cached.managerID = stringValue(manager.GetId(), "")
+	}
+	for _, license := range user.GetAssignedLicenses() {
+		if license.GetSkuId() != nil {
+			cached.licenses = append(cached.licenses, license.GetSkuId().String())
+		}
+	}
+	if len(extraProperties) > 0 {
+		cached.extra = make([]string, len(extraProperties))
+		for i, property := range extraProperties {
+			cached.extra[i] = stringValue(userExtraProperties[property].value(user), "unknown")
+		}
+	}
+	return cached
+}
+
+// provisioningErrorKey groups directory sync errors by category and the
+// property causing them, e.g. PropertyConflict on ProxyAddresses
+type provisioningErrorKey struct {
+	category string
+	property string
+}
+
+// userExtraProperty is an optional user property and its label name
+type userExtraProperty struct {
+	label string
+	value func(models.Userable) *string
+}
+
+// userExtraProperties are the supported extra properties by Graph property name
+var userExtraProperties = map[string]userExtraProperty{
+	"department":    {label: "department", value: models.Userable.GetDepartment},
+	"jobTitle":      {label: "job_title", value: models.Userable.GetJobTitle},
+	"usageLocation": {label: "usage_location", value: models.Userable.GetUsageLocation},
+	"companyName":   {label: "company_name", value: models.Userable.GetCompanyName},
+}
+
+// userBreakdown counts the users per value of an extra property
+type userBreakdown struct {
+	// Index of the property in the cached extra values
+	index int
+	gauge *countVec
+}
+
+// managerStats counts the users without a manager by user type and the
+// direct reports per manager ID of a tenant
+type managerStats struct {
+	withoutManager map[string]int
+	directReports  map[string]int
+}
+
+// newManagerStats counts the managers of the users, managers outside the
+// collected users are counted as well
+func newManagerStats(usersList []cachedUser) managerStats {
+	stats := managerStats{
+		withoutManager: map[string]int{"Member": 0, "Guest": 0},
+		directReports:  map[string]int{},
+	}
+	for _, user := range usersList {
+		if user.managerID == "" {
+			stats.withoutManager[user.userType]++
+			continue
+		}
+		stats.directReports[user.managerID]++
+	}
+	return stats
+}
+
 // UsersCollector collects Entra ID user metrics
 type UsersCollector struct {
 	*BaseCollector
 
 	// Users cache
 	usersLock sync.RWMutex
-	usersList map[string][]models.Userable
+	usersList map[string][]cachedUser
+
+	// SKU part numbers by SKU ID per tenant, only with licenses enabled
+	skuPartNumbers map[string]map[string]string
+
+	// Manager counts per tenant, only with managers enabled
+	managers map[string]managerStats
 
 	// Metrics
-	usersTotal *prometheus.GaugeVec
-	usersInfo  *prometheus.GaugeVec
+	usersTotal         *gaugeVec
+	usersEnabledTotal  *gaugeVec
+	usersDisabledTotal *gaugeVec
+	usersGuestsTotal   *gaugeVec
+	usersMembersTotal  *gaugeVec
+	usersBySyncSource  *countVec
+	usersSyncErrors    *gaugeVec
+	syncErrorsTotal    *countVec
+	usersInfo          *infoVec
+	usersBreakdowns    []userBreakdown
+	userLicenseInfo    *infoVec
+	inactiveGuests     *gaugeVec
+	inactiveGuestInfo  *infoVec
+	passwordAge        *gaugeVec
+	withoutManager     *gaugeVec
+	directReports      *gaugeVec
+
+	// Extra properties requested from Graph and the indexes of those
+	// added as info labels
+	extraProperties []string
+	extraLabels     []int
 }
 
 // NewUsersCollector creates a new UsersCollector
-func NewUsersCollector(config *config.Config, logger *logrus.Entry) *UsersCollector {
-	scrapeTime := config.Collector.Users.ScrapeTime
+func NewUsersCollector(ctx context.Context, config *config.Config, logger *logrus.Entry) *UsersCollector {
+	extraConfig := config.Collector.Users.ExtraProperties
+	extraProperties := extraConfig.Properties()
+
+	infoLabels := []string{
+		"tenant_id",
+		"user_id",
+		"user_principal_name",
+		"display_name",
+		"account_enabled",
+		"user_type",
+		"creation_type",
+	}
+	var extraLabels []int
+	for _, property := range extraConfig.Labels {
+		infoLabels = append(infoLabels, userExtraProperties[property].label)
+		extraLabels = append(extraLabels, slices.Index(extraProperties, property))
+	}
+
+	var breakdowns []userBreakdown
+	for _, property := range extraConfig.Breakdowns {
+		label := userExtraProperties[property].label
+		breakdowns = append(breakdowns, userBreakdown{
+			index: slices.Index(extraProperties, property),
+			gauge: newCountVec(
+				prometheus.GaugeOpts{
+					Name: fmt.Sprintf("entraid_users_by_%s_total", label),
+					Help: fmt.Sprintf("Number of users in Entra ID by %s", property),
+				},
+				[]string{"tenant_id", label},
+			),
+		})
+	}
 
 	c := &UsersCollector{
-		BaseCollector: NewBaseCollector("users", scrapeTime, config, logger),
-		usersList:     map[string][]models.Userable{},
-		usersTotal: prometheus.NewGaugeVec(
+		BaseCollector:   NewBaseCollector("users", config.Collector.Users.CollectorConfig, config, logger),
+		usersList:       map[string][]cachedUser{},
+		skuPartNumbers:  map[string]map[string]string{},
+		managers:        map[string]managerStats{},
+		usersBreakdowns: breakdowns,
+		extraProperties: extraProperties,
+		extraLabels:     extraLabels,
+		usersTotal: newGaugeVec(
 			prometheus.GaugeOpts{
 				Name: "entraid_users_total",
 				Help: "Total number of users in Entra ID",
 			},
 			[]string{"tenant_id"},
 		),
-		usersInfo: prometheus.NewGaugeVec(
+		usersEnabledTotal: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_users_enabled_total",
+				Help: "Number of users with an enabled account in Entra ID",
+			},
+			[]string{"tenant_id"},
+		),
+		usersDisabledTotal: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_users_disabled_total",
+				Help: "Number of users with a disabled account in Entra ID",
+			},
+			[]string{"tenant_id"},
+		),
+		usersGuestsTotal: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_users_guests_total",
+				Help: "Number of guest users in Entra ID",
+			},
+			[]string{"tenant_id"},
+		),
+		usersMembersTotal: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_users_members_total",
+				Help: "Number of member users in Entra ID",
+			},
+			[]string{"tenant_id"},
+		),
+		usersBySyncSource: newCountVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_users_by_sync_source_total",
+				Help: "Number of users in Entra ID by source, cloud only or synchronized from on-premises",
+			},
+			[]string{"tenant_id", "source"},
+		),
+		usersSyncErrors: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_users_with_provisioning_errors_total",
+				Help: "Number of users with directory sync provisioning errors",
+			},
+			[]string{"tenant_id"},
+		),
+		syncErrorsTotal: newCountVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_users_provisioning_errors_total",
+				Help: "Number of users with a directory sync provisioning error by category and property causing it",
+			},
+			[]string{"tenant_id", "category", "property"},
+		),
+		usersInfo: newInfoVec(
 			prometheus.GaugeOpts{
 				Name: "entraid_users_info",
 				Help: "Information about users in Entra ID",
 			},
-			[]string{
-				"tenant_id",
-				"user_id",
-				"user_principal_name",
-				"display_name",
-				"account_enabled",
-				"user_type",
-				"creation_type",
+			infoLabels,
+			&config.Collector.Users.CollectorConfig,
+		),
+		userLicenseInfo: newInfoVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_user_license_info",
+				Help: "Licenses assigned to users in Entra ID",
+			},
+			[]string{"tenant_id", "user_id", "sku_part_number"},
+			&config.Collector.Users.CollectorConfig,
+		),
+		inactiveGuests: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_users_inactive_guests_total",
+				Help: "Number of guest users without a sign-in (or creation if they never signed in) for longer than the threshold",
+			},
+			[]string{"tenant_id", "threshold"},
+		),
+		inactiveGuestInfo: newInfoVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_users_inactive_guest_info",
+				Help: "Guest users inactive for longer than the smallest threshold",
 			},
+			[]string{"tenant_id", "user_id", "user_principal_name", "display_name", "never_signed_in"},
+			&config.Collector.Users.CollectorConfig,
+		),
+		passwordAge: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_users_password_age_total",
+				Help: "Cumulative number of users whose password was last changed no longer ago than the bucket",
+			},
+			[]string{"tenant_id", "le"},
+		),
+		withoutManager: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_users_without_manager_total",
+				Help: "Number of users without a manager by user type",
+			},
+			[]string{"tenant_id", "user_type"},
+		),
+		directReports: newGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "entraid_users_direct_reports",
+				Help: "Number of users with the user as manager",
+			},
+			[]string{"tenant_id", "manager_id"},
 		),
 	}
 
 	// Start background collection
-	c.StartCacheInvalidator(c.collect)
+	c.StartCacheInvalidator(ctx, c.collect)
 
 	return c
 }
@@ -66,7 +350,33 @@ func NewUsersCollector(config *config.Config, logger *logrus.Entry) *UsersCollec
 func (c *UsersCollector) Describe(ch chan<- *prometheus.Desc) {
 	c.BaseCollector.Describe(ch)
 	c.usersTotal.Describe(ch)
+	c.usersEnabledTotal.Describe(ch)
+	c.usersDisabledTotal.Describe(ch)
+	c.usersGuestsTotal.Describe(ch)
+	c.usersMembersTotal.Describe(ch)
+	c.usersBySyncSource.Describe(ch)
+	c.usersSyncErrors.Describe(ch)
+	c.syncErrorsTotal.Describe(ch)
 	c.usersInfo.Describe(ch)
+	if c.config.Collector.Users.Licenses {
+		c.userLicenseInfo.Describe(ch)
+	}
+	if c.config.Collector.Users.InactiveGuests.IsEnabled() {
+		c.inactiveGuests.Describe(ch)
+		if c.config.Collector.Users.InactiveGuests.Info {
+			c.inactiveGuestInfo.Describe(ch)
+		}
+	}
+	if len(c.config.Collector.Users.PasswordAgeBuckets) > 0 {
+		c.passwordAge.Describe(ch)
+	}
+	if c.config.Collector.Users.Managers {
+		c.withoutManager.Describe(ch)
+		c.directReports.Describe(ch)
+	}
+	for _, breakdown := range c.usersBreakdowns {
+		breakdown.gauge.Describe(ch)
+	}
 }
 
 // Collect implements prometheus.Collector
@@ -76,129 +386,422 @@ func (c *UsersCollector) Collect(ch chan<- prometheus.Metric) {
 	c.usersLock.RLock()
 	defer c.usersLock.RUnlock()
 
+	// The metrics are rebuilt from the cache by every Collect, so removed
+	// objects disappear and concurrent Gathers don't share any state
+	usersTotal := c.usersTotal.vec()
+	usersEnabledTotal := c.usersEnabledTotal.vec()
+	usersDisabledTotal := c.usersDisabledTotal.vec()
+	usersGuestsTotal := c.usersGuestsTotal.vec()
+	usersMembersTotal := c.usersMembersTotal.vec()
+	usersSyncErrors := c.usersSyncErrors.vec()
+	usersInfo := c.usersInfo.vec()
+	userLicenseInfo := c.userLicenseInfo.vec()
+	inactiveGuests := c.inactiveGuests.vec()
+	inactiveGuestInfo := c.inactiveGuestInfo.vec()
+	passwordAge := c.passwordAge.vec()
+	withoutManager := c.withoutManager.vec()
+	directReports := c.directReports.vec()
+	bySyncSource := c.usersBySyncSource.counts()
+	syncErrorsTotal := c.syncErrorsTotal.counts()
+	breakdowns := make([]*counts, len(c.usersBreakdowns))
+	for i, breakdown := range c.usersBreakdowns {
+		breakdowns[i] = breakdown.gauge.counts()
+	}
+
 	// Collect users metrics
 	for tenantID, usersList := range c.usersList {
-		c.usersTotal.WithLabelValues(tenantID).Set(float64(len(usersList)))
+		if c.isCacheExpired(tenantID) {
+			continue
+		}
+
+		usersTotal.WithLabelValues(tenantID).Set(float64(len(usersList)))
 
+		// Breakdowns, so dashboards don't need to count the info series
+		var enabled, guests, members, syncErrors int
+		bySyncSource.Add(0, tenantID, syncSource(false))
+		bySyncSource.Add(0, tenantID, syncSource(true))
 		for _, user := range usersList {
-			accountEnabled := "false"
-			if user.GetAccountEnabled() != nil && *user.GetAccountEnabled() {
-				accountEnabled = "true"
+			if user.accountEnabled {
+				enabled++
 			}
-
-			userType := "unknown"
-			if user.GetUserType() != nil {
-				userType = *user.GetUserType()
+			bySyncSource.Inc(tenantID, syncSource(user.onPremisesSynced))
+			if len(user.provisioningErrors) > 0 {
+				syncErrors++
 			}
-
-			creationType := "unknown"
-			if user.GetCreationType() != nil {
-				creationType = *user.GetCreationType()
+			for _, key := range user.provisioningErrors {
+				syncErrorsTotal.Inc(tenantID, key.category, key.property)
+			}
+			switch user.userType {
+			case "Guest":
+				guests++
+			case "Member":
+				members++
+			}
+		}
+		usersEnabledTotal.WithLabelValues(tenantID).Set(float64(enabled))
+		usersDisabledTotal.WithLabelValues(tenantID).Set(float64(len(usersList) - enabled))
+		usersGuestsTotal.WithLabelValues(tenantID).Set(float64(guests))
+		usersMembersTotal.WithLabelValues(tenantID).Set(float64(members))
+		usersSyncErrors.WithLabelValues(tenantID).Set(float64(syncErrors))
+		for i, breakdown := range c.usersBreakdowns {
+			for _, user := range usersList {
+				breakdowns[i].Inc(tenantID, user.extra[breakdown.index])
 			}
+		}
+		if c.config.Collector.Users.InactiveGuests.IsEnabled() {
+			c.collectInactiveGuests(tenantID, usersList, inactiveGuests, inactiveGuestInfo)
+		}
+		if len(c.config.Collector.Users.PasswordAgeBuckets) > 0 {
+			c.collectPasswordAge(tenantID, usersList, passwordAge)
+		}
+		if c.config.Collector.Users.Managers {
+			c.collectManagers(tenantID, withoutManager, directReports)
+		}
 
-			c.usersInfo.WithLabelValues(
+		// Per object series are left out in aggregate only mode
+		if c.collectorConfig.IsAggregateOnly(c.config.Metrics.AggregateOnly) {
+			continue
+		}
+
+		for _, user := range usersList {
+			labels := []string{
 				tenantID,
-				*user.GetId(),
-				*user.GetUserPrincipalName(),
-				*user.GetDisplayName(),
-				accountEnabled,
-				userType,
-				creationType,
-			).Set(1)
+				user.id,
+				c.redact(user.userPrincipalName),
+				c.redact(user.displayName),
+				strconv.FormatBool(user.accountEnabled),
+				user.userType,
+				user.creationType,
+			}
+			for _, index := range c.extraLabels {
+				labels = append(labels, user.extra[index])
+			}
+			usersInfo.WithLabelValues(labels...).Set(1)
+
+			// Licenses of SKUs which are no longer subscribed keep their ID
+			for _, skuID := range user.licenses {
+				skuPartNumber, exists := c.skuPartNumbers[tenantID][skuID]
+				if !exists {
+					skuPartNumber = skuID
+				}
+				userLicenseInfo.WithLabelValues(tenantID, user.id, skuPartNumber).Set(1)
+			}
 		}
 	}
 
-	c.usersTotal.Collect(ch)
-	c.usersInfo.Collect(ch)
+	metrics := []prometheus.Collector{usersTotal, usersEnabledTotal, usersDisabledTotal, usersGuestsTotal, usersMembersTotal, bySyncSource, usersSyncErrors, syncErrorsTotal, usersInfo}
+	if c.config.Collector.Users.Licenses {
+		metrics = append(metrics, userLicenseInfo)
+	}
+	if c.config.Collector.Users.InactiveGuests.IsEnabled() {
+		metrics = append(metrics, inactiveGuests)
+		if c.config.Collector.Users.InactiveGuests.Info {
+			metrics = append(metrics, inactiveGuestInfo)
+		}
+	}
+	if len(c.config.Collector.Users.PasswordAgeBuckets) > 0 {
+		metrics = append(metrics, passwordAge)
+	}
+	if c.config.Collector.Users.Managers {
+		metrics = append(metrics, withoutManager, directReports)
+	}
+	for _, breakdown := range breakdowns {
+		metrics = append(metrics, breakdown)
+	}
+	c.collectCached(ch, metrics...)
 }
 
 // collect gets all users
-func (c *UsersCollector) collect() {
-	c.Lock()
-	defer c.Unlock()
-
+func (c *UsersCollector) collect(ctx context.Context) {
 	tenants := c.GetTenants()
 	c.logger.Debugf("Starting users collection for %d tenants", len(tenants))
 
 	for _, tenantID := range tenants {
+		if ctx.Err() != nil {
+			c.logger.Debugf("Stopping users collection: %v", ctx.Err())
+			return
+		}
+
+		if !c.tenantAllowed(tenantID) {
+			continue
+		}
+
 		start := time.Now()
-		c.logger.Debugf("Collecting users for tenant %s", tenantID)
+		c.sampledDebugf("Collecting users for tenant %s", tenantID)
 
-		client, err := c.GetGraphClient(tenantID)
+		client, err := c.GetGraphClient(ctx, tenantID)
 		if err != nil {
 			c.logger.Errorf("Failed to get Graph client for tenant %s: %v", tenantID, err)
 			c.logger.Debugf("API request details for users: tenantID=%s", tenantID)
 			c.scrapeErrors.WithLabelValues(tenantID).Inc()
+			c.tenantFailed(tenantID, err)
 			continue
 		}
 
 		// Set up pagination
-		var usersList []models.Userable
+		var usersList []cachedUser
 		pageSize := int32(100)
-		c.logger.Debugf("Using page size %d for users collection", pageSize)
+		c.sampledDebugf("Using page size %d for users collection", pageSize)
 		
 		query := users.UsersRequestBuilderGetQueryParameters{
 			Top: &pageSize,
 			// Add select to limit the properties returned for each user to reduce API load
-			Select: []string{"id", "userPrincipalName", "displayName", "accountEnabled", "userType", "creationType"},
+			Select: append([]string{"id", "userPrincipalName", "displayName", "accountEnabled", "userType", "creationType", "onPremisesSyncEnabled", "onPremisesProvisioningErrors"}, c.extraProperties...),
 		}
 
-		reqConfig := users.UsersRequestBuilderGetRequestConfiguration{
-			QueryParameters: &query,
+		if filter := c.collectorConfig.Filter; filter != "" {
+			c.logger.Debugf("Using filter %q for users collection", filter)
+			query.Filter = &filter
 		}
 
-		// Get the first page
-		c.logger.Debugf("Fetching first page of users for tenant %s", tenantID)
-		result, err := client.Users().Get(context.Background(), &reqConfig)
-		if err != nil {
-			c.logger.Errorf("Failed to get users for tenant %s: %v", tenantID, err)
-			c.logger.Debugf("API request details for users: tenantID=%s", tenantID)
-			c.scrapeErrors.WithLabelValues(tenantID).Inc()
-			continue
+		var skuPartNumbers map[string]string
+		if c.config.Collector.Users.Licenses {
+			query.Select = append(query.Select, "assignedLicenses")
+			skuPartNumbers = c.fetchSkuPartNumbers(ctx, client, tenantID)
+		}
+		if c.config.Collector.Users.InactiveGuests.IsEnabled() {
+			query.Select = append(query.Select, "signInActivity", "createdDateTime")
+		}
+		if len(c.config.Collector.Users.PasswordAgeBuckets) > 0 {
+			query.Select = append(query.Select, "lastPasswordChangeDateTime")
+		}
+		if c.config.Collector.Users.Managers {
+			query.Expand = []string{"manager($select=id)"}
 		}
 
-		// Store the first page of users
-		if result.GetValue() != nil {
-			pageUsers := result.GetValue()
-			usersList = append(usersList, pageUsers...)
-			c.logger.Debugf("Retrieved %d users in first page for tenant %s", len(pageUsers), tenantID)
-		} else {
-			c.logger.Warnf("No users returned in API response for tenant %s", tenantID)
+		reqConfig := users.UsersRequestBuilderGetRequestConfiguration{
+			QueryParameters: &query,
 		}
 
-		// Handle pagination manually
-		pageCount := 1
-		for result.GetOdataNextLink() != nil && len(*result.GetOdataNextLink()) > 0 {
-			pageCount++
-			c.logger.Debugf("Fetching page %d of users for tenant %s", pageCount, tenantID)
-			// Fetch the next page using the nextLink directly
-			var nextReqConfig *users.UsersRequestBuilderGetRequestConfiguration
-			result, err = client.Users().Get(context.Background(), nextReqConfig)
+		// Fetch the pages in the background, the next page is requested while
+		// the current one is processed
+		pageCtx, cancelPages := context.WithCancel(ctx)
+		pages := prefetchPages(pageCtx, func(ctx context.Context, nextLink string) ([]models.Userable, *string, error) {
+			builder := client.Users()
+			requestConfig := &reqConfig
+			if nextLink != "" {
+				// Fetch the next page using the nextLink directly
+				builder = builder.WithUrl(nextLink)
+				requestConfig = nil
+			}
+
+			result, err := builder.Get(ctx, requestConfig)
 			if err != nil {
-				c.logger.Errorf("Failed to get next page of users for tenant %s: %v", tenantID, err)
+				return nil, nil, err
+			}
+			return result.GetValue(), result.GetOdataNextLink(), nil
+		})
+
+		failedPage := 0
+		var pageErr error
+		tooManyObjects := false
+		pageCount := 0
+		for page := range pages {
+			pageCount = page.number
+			c.checkSlowPage(tenantID, "users", page.number, page.duration)
+			if page.err != nil {
+				if ctx.Err() != nil {
+					cancelPages()
+					c.logger.Debugf("Users collection for tenant %s cancelled on page %d", tenantID, page.number)
+					return
+				}
+				c.logger.WithFields(graphErrorFields(page.err)).Errorf("Failed to get page %d of users for tenant %s: %v", page.number, tenantID, graphErrorMessage(page.err))
+				c.logger.Debugf("API request details for users: tenantID=%s", tenantID)
 				c.scrapeErrors.WithLabelValues(tenantID).Inc()
+				failedPage = page.number
+				pageErr = page.err
 				break
 			}
-			
+
 			// Store this page's users
-			if result.GetValue() != nil {
-				pageUsers := result.GetValue()
-				usersList = append(usersList, pageUsers...)
-				c.logger.Debugf("Retrieved %d users in page %d for tenant %s", len(pageUsers), pageCount, tenantID)
-			} else {
-				c.logger.Warnf("No users returned in page %d for tenant %s", pageCount, tenantID)
+			if len(page.items) == 0 {
+				c.logger.Warnf("No users returned in page %d for tenant %s", page.number, tenantID)
+				continue
+			}
+			for _, item := range page.items {
+				usersList = append(usersList, newCachedUser(item, c.extraProperties))
+			}
+			c.sampledDebugf("Retrieved %d users in page %d for tenant %s", len(page.items), page.number, tenantID)
+
+			if c.exceedsMaxObjects(tenantID, len(usersList)) {
+				tooManyObjects = true
+				break
 			}
 		}
+		cancelPages()
+
+		// The pages may stop early without an error when cancelled
+		if ctx.Err() != nil {
+			c.logger.Debugf("Users collection for tenant %s cancelled", tenantID)
+			return
+		}
+
+		// Keep the previous cache, Graph itself worked fine
+		if tooManyObjects {
+			c.tenantAborted(tenantID)
+			continue
+		}
+
+		// Keep the previous cache if not even the first page could be fetched
+		if failedPage == 1 {
+			c.tenantFailed(tenantID, pageErr)
+			continue
+		}
 
 		// Update the users list
 		c.usersLock.Lock()
 		c.usersList[tenantID] = usersList
+		if skuPartNumbers != nil {
+			c.skuPartNumbers[tenantID] = skuPartNumbers
+		}
+		if c.config.Collector.Users.Managers {
+			c.managers[tenantID] = newManagerStats(usersList)
+		}
 		c.usersLock.Unlock()
+		c.cacheUpdated(tenantID, len(usersList))
+		c.recordStats(tenantID, start, pageCount, len(usersList))
+
+		if failedPage > 0 {
+			c.tenantFailed(tenantID, pageErr)
+		} else {
+			c.tenantSucceeded(tenantID)
+		}
 
 		// Update scrape metrics
 		duration := time.Since(start).Seconds()
-		c.scrapeDuration.WithLabelValues(tenantID).Observe(duration)
+		c.observeDuration(tenantID, duration)
 		c.lastScrapeTime.WithLabelValues(tenantID).Set(float64(time.Now().Unix()))
-		c.logger.Debugf("Completed users collection for tenant %s in %.2f seconds: %d users", tenantID, duration, len(usersList))
+		c.sampledDebugf("Completed users collection for tenant %s in %.2f seconds: %d users", tenantID, duration, len(usersList))
+	}
+}
+
+// collectInactiveGuests counts the guests per inactivity threshold, the
+// inactivity is relative to the scrape so the counts grow between collections
+func (c *UsersCollector) collectInactiveGuests(tenantID string, usersList []cachedUser, inactiveGuests *prometheus.GaugeVec, inactiveGuestInfo *infoGauges) {
+	thresholds := c.config.Collector.Users.InactiveGuests.Thresholds
+	now := time.Now()
+
+	inactive := make([]int, len(thresholds))
+	for _, user := range usersList {
+		if user.userType != "Guest" || user.lastActivity.IsZero() {
+			continue
+		}
+		inactivity := now.Sub(user.lastActivity)
+		for i, threshold := range thresholds {
+			if inactivity > threshold {
+				inactive[i]++
+			}
+		}
+
+		if c.config.Collector.Users.InactiveGuests.Info && !c.collectorConfig.IsAggregateOnly(c.config.Metrics.AggregateOnly) && inactivity > slices.Min(thresholds) {
+			inactiveGuestInfo.WithLabelValues(tenantID, user.id, c.redact(user.userPrincipalName), c.redact(user.displayName), strconv.FormatBool(user.neverSignedIn)).Set(1)
+		}
+	}
+
+	for i, threshold := range thresholds {
+		inactiveGuests.WithLabelValues(tenantID, model.Duration(threshold).String()).Set(float64(inactive[i]))
+	}
+}
+
+// collectPasswordAge counts the users per password age bucket like a
+// Prometheus histogram, each bucket includes the smaller ones and +Inf
+// counts all users with a known password change
+func (c *UsersCollector) collectPasswordAge(tenantID string, usersList []cachedUser, passwordAge *prometheus.GaugeVec) {
+	buckets := c.config.Collector.Users.PasswordAgeBuckets
+	now := time.Now()
+
+	counts := make([]int, len(buckets))
+	var known int
+	for _, user := range usersList {
+		if user.passwordChanged.IsZero() {
+			continue
+		}
+		known++
+		age := now.Sub(user.passwordChanged)
+		for i, bucket := range buckets {
+			if age <= bucket {
+				counts[i]++
+			}
+		}
+	}
+
+	for i, bucket := range buckets {
+		passwordAge.WithLabelValues(tenantID, model.Duration(bucket).String()).Set(float64(counts[i]))
+	}
+	passwordAge.WithLabelValues(tenantID, "+Inf").Set(float64(known))
+}
+
+// collectManagers exports the manager counts of the last collection
+func (c *UsersCollector) collectManagers(tenantID string, withoutManager, directReports *prometheus.GaugeVec) {
+	stats := c.managers[tenantID]
+	for userType, count := range stats.withoutManager {
+		withoutManager.WithLabelValues(tenantID, userType).Set(float64(count))
+	}
+
+	// The counts per manager are per object series
+	if c.collectorConfig.IsAggregateOnly(c.config.Metrics.AggregateOnly) {
+		return
+	}
+	for managerID, count := range stats.directReports {
+		directReports.WithLabelValues(tenantID, managerID).Set(float64(count))
+	}
+}
+
+// fetchSkuPartNumbers returns the part numbers of the tenant's subscribed
+// SKUs by SKU ID, or nil if they couldn't be fetched
+func (c *UsersCollector) fetchSkuPartNumbers(ctx context.Context, client *mgraph.GraphServiceClient, tenantID string) map[string]string {
+	result, err := client.SubscribedSkus().Get(ctx, nil)
+	if err != nil {
+		// Not fatal, the license metrics fall back to the previous names or the SKU IDs
+		c.logger.WithFields(graphErrorFields(err)).Warnf("Failed to get subscribed SKUs for tenant %s: %v", tenantID, graphErrorMessage(err))
+		return nil
+	}
+
+	skuPartNumbers := map[string]string{}
+	for _, sku := range result.GetValue() {
+		if sku.GetSkuId() != nil && sku.GetSkuPartNumber() != nil {
+			skuPartNumbers[sku.GetSkuId().String()] = *sku.GetSkuPartNumber()
+		}
 	}
+	return skuPartNumbers
+}
+
+// Inventory implements InventoryExporter
+func (c *UsersCollector) Inventory() ([]string, iter.Seq[[]string]) {
+	// Copy the cache references so slow consumers don't block collections,
+	// cached lists are replaced and never modified
+	c.usersLock.RLock()
+	inventory := make(map[string][]cachedUser, len(c.usersList))
+	for tenantID, usersList := range c.usersList {
+		if !c.isCacheExpired(tenantID) {
+			inventory[tenantID] = usersList
+		}
+	}
+	c.usersLock.RUnlock()
+
+	header := []string{
+		"tenant_id",
+		"user_id",
+		"user_principal_name",
+		"display_name",
+		"account_enabled",
+		"user_type",
+		"creation_type",
+	}
+	for _, property := range c.extraProperties {
+		header = append(header, userExtraProperties[property].label)
+	}
+
+	return header, inventoryRows(inventory, func(tenantID string, user cachedUser) []string {
+		row := []string{
+			tenantID,
+			user.id,
+			c.redact(user.userPrincipalName),
+			c.redact(user.displayName),
+			strconv.FormatBool(user.accountEnabled),
+			user.userType,
+			user.creationType,
+		}
+		return append(row, user.extra...)
+	})
 }
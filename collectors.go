@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/your-username/entra-exporter/collector"
+	"github.com/your-username/entra-exporter/config"
+)
+
+// collectorsCommand lists the known collectors and their configuration
+type collectorsCommand struct{}
+
+// runCollectors prints every known collector, whether it is enabled, its
+// scrape time and the Graph permissions it needs
+func runCollectors(cfg *config.Config) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "COLLECTOR\tENABLED\tSCRAPE TIME\tPERMISSIONS")
+
+	for _, name := range collector.KnownCollectors() {
+		collectorConfig := cfg.GetCollector(name)
+		scrapeTime := "-"
+		if collectorConfig.IsEnabled() {
+			scrapeTime = collectorConfig.ScrapeTime.String()
+		}
+		fmt.Fprintf(w, "%s\t%t\t%s\t%s\n",
+			name,
+			collectorConfig.IsEnabled(),
+			scrapeTime,
+			strings.Join(collector.RequiredPermissions(name, cfg), ", "),
+		)
+	}
+
+	w.Flush()
+}
@@ -1,16 +1,479 @@
 package config
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
 	"os"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/jmespath/go-jmespath"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
 )
 
+const (
+	// ModeBackground collects in a background loop every scrapeTime
+	ModeBackground = "background"
+	// ModeOnDemand collects while /metrics is being scraped
+	ModeOnDemand = "ondemand"
+
+	// DefaultOnDemandCacheTime is used when onDemandCacheTime is not set
+	DefaultOnDemandCacheTime = 30 * time.Second
+
+	// DefaultStartDelay is used when startDelay is not set
+	DefaultStartDelay = 30 * time.Second
+
+	// DefaultActivityWindow is used when the window of an activity collector is not set
+	DefaultActivityWindow = 24 * time.Hour
+
+	// DefaultExpiringWithin is used when expiringWithin is not set
+	DefaultExpiringWithin = 7 * 24 * time.Hour
+
+	// Circuit breaker defaults
+	DefaultCircuitBreakerFailureThreshold = 5
+	DefaultCircuitBreakerInitialBackoff   = 1 * time.Minute
+	DefaultCircuitBreakerMaxBackoff       = 1 * time.Hour
+
+	// DefaultAuthFailureThreshold is used when authFailureThreshold is not set
+	DefaultAuthFailureThreshold = 3
+
+	// DefaultOTLPInterval is used when the OTLP interval is not set
+	DefaultOTLPInterval = 60 * time.Second
+
+	// DefaultInfluxInterval is used when the Influx interval is not set
+	DefaultInfluxInterval = 60 * time.Second
+
+	// DefaultLogAnalyticsInterval is used when the Log Analytics interval is not set
+	DefaultLogAnalyticsInterval = 1 * time.Hour
+
+	// DefaultNotificationsInterval is used when the notifications interval is not set
+	DefaultNotificationsInterval = 5 * time.Minute
+
+	// Webhook payload formats
+	WebhookGeneric = "generic"
+	WebhookSlack   = "slack"
+	WebhookTeams   = "teams"
+
+	// DefaultSQLiteRetention is used when the snapshot retention is not set
+	DefaultSQLiteRetention = 30 * 24 * time.Hour
+
+	// Change event sink types
+	EventsWebhook = "webhook"
+	EventsNATS    = "nats"
+	EventsKafka   = "kafka"
+
+	// DefaultEventsNATSSubject is used when the NATS subject is not set
+	DefaultEventsNATSSubject = "entra.changes"
+
+	// Snapshot archive storage types
+	ArchiveAzureBlob = "azureBlob"
+	ArchiveS3        = "s3"
+
+	// DefaultChangeNotificationsDebounce is used when the debounce time is not set
+	DefaultChangeNotificationsDebounce = 30 * time.Second
+
+	// ConditionNew matches series which didn't exist at the previous evaluation
+	ConditionNew = "new"
+
+	// DefaultPluginTimeout is used when the timeout of a plugin is not set
+	DefaultPluginTimeout = 1 * time.Minute
+
+	// Leader election defaults, those of Kubernetes' own controllers
+	DefaultLeaseDuration = 15 * time.Second
+	DefaultRenewDeadline = 10 * time.Second
+	DefaultRetryPeriod   = 2 * time.Second
+)
+
+// DefaultDurationBuckets are the duration histogram buckets in seconds when
+// durationBuckets is not set
+var DefaultDurationBuckets = []float64{1, 5, 10, 30, 60, 120, 300, 600, 1800, 3600}
+
+// DefaultShutdownTimeout is the time to wait for in-flight collections on
+// shutdown when shutdownTimeout is not set
+const DefaultShutdownTimeout = 30 * time.Second
+
 // CollectorConfig is the base configuration for all collectors
 type CollectorConfig struct {
+	// How often the collector collects (not defined or 0 = disabled)
 	ScrapeTime time.Duration `yaml:"scrapeTime"`
+
+	// Optional OData filter for the collected objects
+	Filter string `yaml:"filter"`
+
+	// Collection mode, either "background" (default) or "ondemand"
+	Mode string `yaml:"mode"`
+
+	// How long on demand results are reused for subsequent scrapes
+	OnDemandCacheTime time.Duration `yaml:"onDemandCacheTime"`
+
+	// Maximum random delay before the first collection
+	StartDelay time.Duration `yaml:"startDelay"`
+
+	// Maximum random delay added to every scrapeTime interval
+	ScrapeJitter time.Duration `yaml:"scrapeJitter"`
+
+	// How long cached results are served when collections keep failing
+	// (not defined or 0 = forever)
+	CacheTTL time.Duration `yaml:"cacheTTL"`
+
+	// Abort the collection of a tenant when it returns more objects than
+	// this and keep the previous cache (not defined or 0 = unlimited)
+	MaxObjects int `yaml:"maxObjects"`
+
+	// Maximum per page and per tenant debug log lines per second, further
+	// lines are dropped and counted (not defined or 0 = unlimited)
+	DebugLogRate float64 `yaml:"debugLogRate"`
+
+	// Per object series, "aggregate" only exposes counts and breakdowns and
+	// "full" the *_info series too (not defined = metrics.aggregateOnly)
+	DetailLevel string `yaml:"detailLevel"`
+
+	// Only these labels are kept on the info metrics, tenant_id is always
+	// kept (not defined = all)
+	KeepLabels []string `yaml:"keepLabels"`
+
+	// Labels left out of the info metrics, e.g. display_name
+	DropLabels []string `yaml:"dropLabels"`
+
+	// Graph API version, "v1.0" (default) or "beta". Built-in collectors
+	// still only read v1.0 properties, custom queries get beta-only ones.
+	// Beta APIs may change without notice.
+	APIVersion string `yaml:"apiVersion"`
+
+	// Graph host of national clouds, e.g. https://graph.microsoft.us
+	// (default: https://graph.microsoft.com)
+	BaseURL string `yaml:"baseURL"`
+}
+
+// collectorOptions is the configuration of a collector with options of its
+// own, validated in addition to the common options
+type collectorOptions interface {
+	validateOptions(name string) error
+}
+
+// UsersConfig is the configuration of the users collector
+type UsersConfig struct {
+	CollectorConfig `yaml:",inline"`
+
+	// Additional object properties requested from Graph
+	ExtraProperties ExtraPropertiesConfig `yaml:"extraProperties"`
+
+	// Export a series per assigned license of every user
+	Licenses bool `yaml:"licenses"`
+
+	// Expand the manager of every user to count users without a manager
+	// and the direct reports per manager
+	Managers bool `yaml:"managers"`
+
+	// Inactive guest accounts
+	InactiveGuests InactiveGuestsConfig `yaml:"inactiveGuests"`
+
+	// Users are counted per password age bucket, e.g. [720h, 2160h]
+	PasswordAgeBuckets []time.Duration `yaml:"passwordAgeBuckets"`
+}
+
+func (c *UsersConfig) validateOptions(name string) error {
+	if err := c.ExtraProperties.validate(name); err != nil {
+		return err
+	}
+	if err := c.InactiveGuests.validate(name); err != nil {
+		return err
+	}
+	for _, bucket := range c.PasswordAgeBuckets {
+		if bucket <= 0 {
+			return fmt.Errorf("collector %s: passwordAgeBuckets must be positive", name)
+		}
+	}
+	return nil
+}
+
+// ActivityConfig is the configuration of collectors counting activities
+// within a sliding window
+type ActivityConfig struct {
+	CollectorConfig `yaml:",inline"`
+
+	// Sliding window of the counted activities, e.g. PIM activations or sign-ins
+	Window time.Duration `yaml:"window"`
+}
+
+// GetWindow returns the activity window or its default
+func (c *ActivityConfig) GetWindow() time.Duration {
+	if c.Window > 0 {
+		return c.Window
+	}
+	return DefaultActivityWindow
+}
+
+func (c *ActivityConfig) validateOptions(name string) error {
+	if c.Window < 0 {
+		return fmt.Errorf("collector %s: window must not be negative", name)
+	}
+	return nil
+}
+
+// SignInsConfig is the configuration of the signIns collector
+type SignInsConfig struct {
+	ActivityConfig `yaml:",inline"`
+
+	// Breakdown values with fewer objects are merged into "other" to limit
+	// the cardinality (not defined or 0 = all values)
+	MinCount int `yaml:"minCount"`
+
+	// Add the risk state to the country breakdown
+	RiskStateLabel bool `yaml:"riskStateLabel"`
+}
+
+func (c *SignInsConfig) validateOptions(name string) error {
+	if c.MinCount < 0 {
+		return fmt.Errorf("collector %s: minCount must not be negative", name)
+	}
+	return c.ActivityConfig.validateOptions(name)
+}
+
+// ConsentsConfig is the configuration of the consents collector
+type ConsentsConfig struct {
+	CollectorConfig `yaml:",inline"`
+
+	// Microsoft Graph permissions counted as high privilege, delegated scopes
+	// and application roles alike
+	Permissions []string `yaml:"permissions"`
+}
+
+// GetPermissions returns the high privilege permissions or their default
+func (c *ConsentsConfig) GetPermissions() []string {
+	if len(c.Permissions) > 0 {
+		return c.Permissions
+	}
+	return DefaultHighPrivilegePermissions
+}
+
+// DeletedItemsConfig is the configuration of the deletedItems collector
+type DeletedItemsConfig struct {
+	CollectorConfig `yaml:",inline"`
+
+	// Deleted objects are counted per duration they are purged within
+	PurgeWithin []time.Duration `yaml:"purgeWithin"`
+}
+
+// GetPurgeWithin returns the purge durations or their default
+func (c *DeletedItemsConfig) GetPurgeWithin() []time.Duration {
+	if len(c.PurgeWithin) > 0 {
+		return c.PurgeWithin
+	}
+	return DefaultPurgeWithin
+}
+
+func (c *DeletedItemsConfig) validateOptions(name string) error {
+	for _, within := range c.PurgeWithin {
+		if within <= 0 {
+			return fmt.Errorf("collector %s: purgeWithin durations must be positive", name)
+		}
+	}
+	return nil
+}
+
+// AccessPackagesConfig is the configuration of the accessPackages collector
+type AccessPackagesConfig struct {
+	CollectorConfig `yaml:",inline"`
+
+	// Assignments ending within this duration are counted as expiring
+	ExpiringWithin time.Duration `yaml:"expiringWithin"`
+}
+
+// GetExpiringWithin returns the expiring duration or its default
+func (c *AccessPackagesConfig) GetExpiringWithin() time.Duration {
+	if c.ExpiringWithin > 0 {
+		return c.ExpiringWithin
+	}
+	return DefaultExpiringWithin
+}
+
+func (c *AccessPackagesConfig) validateOptions(name string) error {
+	if c.ExpiringWithin < 0 {
+		return fmt.Errorf("collector %s: expiringWithin must not be negative", name)
+	}
+	return nil
+}
+
+// CustomConfig is the configuration of the custom collector
+type CustomConfig struct {
+	CollectorConfig `yaml:",inline"`
+
+	// Graph GET queries turned into metrics
+	Queries []CustomQueryConfig `yaml:"queries"`
+}
+
+func (c *CustomConfig) validateOptions(name string) error {
+	metrics := map[string]bool{}
+	for _, query := range c.Queries {
+		if err := query.validate(name); err != nil {
+			return err
+		}
+		for _, metric := range query.Metrics {
+			if metrics[metric.Name] {
+				return fmt.Errorf("collector %s: duplicate metric %s", name, metric.Name)
+			}
+			metrics[metric.Name] = true
+		}
+	}
+	return nil
+}
+
+// metricName matches valid Prometheus metric and label names
+var metricName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// CustomQueryConfig is a Graph GET query of the custom collector, all pages
+// are read and every returned object is passed to the metrics
+type CustomQueryConfig struct {
+	// Graph path relative to the API version, e.g. /groups
+	Path string `yaml:"path"`
+
+	// OData filter and selected properties
+	Filter string   `yaml:"filter"`
+	Select []string `yaml:"select"`
+
+	// Metrics extracted from the returned objects
+	Metrics []CustomMetricConfig `yaml:"metrics"`
+}
+
+// CustomMetricConfig extracts a metric from the objects of a custom query
+// with JMESPath expressions evaluated per object
+type CustomMetricConfig struct {
+	Name string `yaml:"name"`
+	Help string `yaml:"help"`
+
+	// Label names and the expressions of their values, e.g. type: groupTypes[0]
+	Labels map[string]string `yaml:"labels"`
+
+	// Expression of the value (not defined = 1 per object)
+	Value string `yaml:"value"`
+
+	// How objects with the same label values are combined, "sum" or "last" (default)
+	Aggregate string `yaml:"aggregate"`
+}
+
+func (c *CustomQueryConfig) validate(name string) error {
+	if !strings.HasPrefix(c.Path, "/") {
+		return fmt.Errorf("collector %s: query path %q must start with /", name, c.Path)
+	}
+	if len(c.Metrics) == 0 {
+		return fmt.Errorf("collector %s: query %s has no metrics", name, c.Path)
+	}
+	for _, metric := range c.Metrics {
+		if !metricName.MatchString(metric.Name) {
+			return fmt.Errorf("collector %s: query %s: invalid metric name %q", name, c.Path, metric.Name)
+		}
+		expressions := []string{metric.Value}
+		for label, expression := range metric.Labels {
+			if !metricName.MatchString(label) || label == "tenant_id" {
+				return fmt.Errorf("collector %s: metric %s: invalid label name %q", name, metric.Name, label)
+			}
+			expressions = append(expressions, expression)
+		}
+		for _, expression := range expressions {
+			if _, err := jmespath.Compile(expression); expression != "" && err != nil {
+				return fmt.Errorf("collector %s: metric %s: invalid expression %q: %v", name, metric.Name, expression, err)
+			}
+		}
+		switch metric.Aggregate {
+		case "", "last", "sum":
+		default:
+			return fmt.Errorf("collector %s: metric %s: invalid aggregate %q (sum or last)", name, metric.Name, metric.Aggregate)
+		}
+	}
+	return nil
+}
+
+// DefaultPurgeWithin is used when the deletedItems collector has no purgeWithin durations configured
+var DefaultPurgeWithin = []time.Duration{24 * time.Hour, 7 * 24 * time.Hour}
+
+// DefaultHighPrivilegePermissions are used when the consents collector has no permissions configured
+var DefaultHighPrivilegePermissions = []string{
+	"Application.ReadWrite.All",
+	"AppRoleAssignment.ReadWrite.All",
+	"Directory.ReadWrite.All",
+	"Domain.ReadWrite.All",
+	"Files.ReadWrite.All",
+	"Group.ReadWrite.All",
+	"Mail.Read",
+	"Mail.ReadWrite",
+	"Mail.Send",
+	"MailboxSettings.ReadWrite",
+	"Policy.ReadWrite.ConditionalAccess",
+	"RoleManagement.ReadWrite.Directory",
+	"Sites.FullControl.All",
+	"User.ReadWrite.All",
+}
+
+// InactiveGuestsConfig configures the inactive guest account metrics
+type InactiveGuestsConfig struct {
+	// Guests without a sign-in (or creation if they never signed in) for
+	// longer than a threshold are counted as inactive, e.g. [720h, 2160h]
+	Thresholds []time.Duration `yaml:"thresholds"`
+
+	// Export an info series per guest exceeding the smallest threshold
+	Info bool `yaml:"info"`
+}
+
+// IsEnabled returns if inactive guests are exported
+func (c *InactiveGuestsConfig) IsEnabled() bool {
+	return len(c.Thresholds) > 0
+}
+
+func (c *InactiveGuestsConfig) validate(name string) error {
+	if !c.IsEnabled() {
+		if c.Info {
+			return fmt.Errorf("collector %s: inactiveGuests.info requires inactiveGuests.thresholds", name)
+		}
+		return nil
+	}
+	for _, threshold := range c.Thresholds {
+		if threshold <= 0 {
+			return fmt.Errorf("collector %s: inactiveGuests thresholds must be positive", name)
+		}
+	}
+	return nil
+}
+
+// UserExtraProperties are the properties the users collector can additionally request
+var UserExtraProperties = []string{"department", "jobTitle", "usageLocation", "companyName"}
+
+// ExtraPropertiesConfig configures additional object properties and how they are exported
+type ExtraPropertiesConfig struct {
+	// Properties added as labels to the info series
+	Labels []string `yaml:"labels"`
+
+	// Properties exported as breakdown gauges, e.g. entraid_users_by_department_total
+	Breakdowns []string `yaml:"breakdowns"`
+}
+
+// Properties returns all configured properties without duplicates
+func (c *ExtraPropertiesConfig) Properties() []string {
+	var properties []string
+	for _, property := range append(slices.Clone(c.Labels), c.Breakdowns...) {
+		if !slices.Contains(properties, property) {
+			properties = append(properties, property)
+		}
+	}
+	return properties
+}
+
+func (c *ExtraPropertiesConfig) validate(name string) error {
+	properties := c.Properties()
+	if len(properties) == 0 {
+		return nil
+	}
+	for _, property := range properties {
+		if !slices.Contains(UserExtraProperties, property) {
+			return fmt.Errorf("collector %s: unsupported extra property %q (must be one of %s)", name, property, strings.Join(UserExtraProperties, ", "))
+		}
+	}
+	return nil
 }
 
 // IsEnabled returns if the collector is enabled
@@ -18,24 +481,925 @@ func (c *CollectorConfig) IsEnabled() bool {
 	return c.ScrapeTime.Seconds() > 0
 }
 
+// IsOnDemand returns if the collector collects at scrape time
+func (c *CollectorConfig) IsOnDemand() bool {
+	return c.Mode == ModeOnDemand
+}
+
+// GetOnDemandCacheTime returns how long on demand results are cached
+func (c *CollectorConfig) GetOnDemandCacheTime() time.Duration {
+	if c.OnDemandCacheTime > 0 {
+		return c.OnDemandCacheTime
+	}
+	return DefaultOnDemandCacheTime
+}
+
+// Detail levels of the collector metrics
+const (
+	DetailLevelAggregate = "aggregate"
+	DetailLevelFull      = "full"
+)
+
+// IsAggregateOnly returns if the collector only exposes counts and
+// breakdowns, falling back to the global setting
+func (c *CollectorConfig) IsAggregateOnly(global bool) bool {
+	switch c.DetailLevel {
+	case DetailLevelAggregate:
+		return true
+	case DetailLevelFull:
+		return false
+	default:
+		return global
+	}
+}
+
+// KeepsLabel returns if the info metrics of the collector keep the label
+func (c *CollectorConfig) KeepsLabel(label string) bool {
+	if len(c.KeepLabels) > 0 && !slices.Contains(c.KeepLabels, label) {
+		return false
+	}
+	return !slices.Contains(c.DropLabels, label)
+}
+
+// Graph API versions
+const (
+	GraphVersionStable = "v1.0"
+	GraphVersionBeta   = "beta"
+)
+
+// DefaultGraphHost is the Graph host of the global cloud
+const DefaultGraphHost = "https://graph.microsoft.com"
+
+// GetGraphHost returns the Graph host the collector requests
+func (c *CollectorConfig) GetGraphHost() string {
+	if c.BaseURL != "" {
+		return strings.TrimSuffix(c.BaseURL, "/")
+	}
+	return DefaultGraphHost
+}
+
+// GetGraphBaseURL returns the Graph endpoint the collector requests,
+// the host with the API version
+func (c *CollectorConfig) GetGraphBaseURL() string {
+	version := GraphVersionStable
+	if c.APIVersion != "" {
+		version = c.APIVersion
+	}
+	return c.GetGraphHost() + "/" + version
+}
+
+// validGraphHost returns if the Graph host is an https URL without path
+func validGraphHost(host string) bool {
+	hostURL, err := url.Parse(host)
+	return err == nil && hostURL.Scheme == "https" && hostURL.Host != "" && strings.Trim(hostURL.Path, "/") == ""
+}
+
+// GetGraphScope returns the token scope of the collector's Graph host
+func (c *CollectorConfig) GetGraphScope() string {
+	return c.GetGraphHost() + "/.default"
+}
+
+// GetStartDelay returns the maximum delay before the first collection,
+// never longer than the scrape time
+func (c *CollectorConfig) GetStartDelay() time.Duration {
+	delay := DefaultStartDelay
+	if c.StartDelay > 0 {
+		delay = c.StartDelay
+	}
+	if delay > c.ScrapeTime {
+		delay = c.ScrapeTime
+	}
+	return delay
+}
+
+// validate checks the collector configuration
+func (c *CollectorConfig) validate(name string) error {
+	if c.DebugLogRate < 0 {
+		return fmt.Errorf("collector %s: debugLogRate must not be negative", name)
+	}
+	switch c.DetailLevel {
+	case "", DetailLevelAggregate, DetailLevelFull:
+	default:
+		return fmt.Errorf("collector %s: invalid detailLevel %q (%s or %s)", name, c.DetailLevel, DetailLevelAggregate, DetailLevelFull)
+	}
+	switch c.APIVersion {
+	case "", GraphVersionStable, GraphVersionBeta:
+	default:
+		return fmt.Errorf("collector %s: invalid apiVersion %q (%s or %s)", name, c.APIVersion, GraphVersionStable, GraphVersionBeta)
+	}
+	if c.BaseURL != "" && !validGraphHost(c.BaseURL) {
+		return fmt.Errorf("collector %s: baseURL must be an https URL without path, e.g. https://graph.microsoft.us", name)
+	}
+
+	switch c.Mode {
+	case "", ModeBackground, ModeOnDemand:
+		return nil
+	default:
+		return fmt.Errorf("collector %s: invalid mode %q (must be %q or %q)", name, c.Mode, ModeBackground, ModeOnDemand)
+	}
+}
+
+// CircuitBreakerConfig configures the per tenant circuit breaker
+type CircuitBreakerConfig struct {
+	// Disable the circuit breaker
+	Disabled bool `yaml:"disabled"`
+
+	// Consecutive failed collections after which the circuit opens
+	FailureThreshold int `yaml:"failureThreshold"`
+
+	// Time until the first recovery probe, doubled after each failed probe
+	InitialBackoff time.Duration `yaml:"initialBackoff"`
+
+	// Upper bound for the time between recovery probes
+	MaxBackoff time.Duration `yaml:"maxBackoff"`
+}
+
+// GetFailureThreshold returns the failure threshold or its default
+func (c *CircuitBreakerConfig) GetFailureThreshold() int {
+	if c.FailureThreshold > 0 {
+		return c.FailureThreshold
+	}
+	return DefaultCircuitBreakerFailureThreshold
+}
+
+// GetInitialBackoff returns the initial backoff or its default
+func (c *CircuitBreakerConfig) GetInitialBackoff() time.Duration {
+	if c.InitialBackoff > 0 {
+		return c.InitialBackoff
+	}
+	return DefaultCircuitBreakerInitialBackoff
+}
+
+// GetMaxBackoff returns the maximum backoff or its default
+func (c *CircuitBreakerConfig) GetMaxBackoff() time.Duration {
+	if c.MaxBackoff > 0 {
+		return c.MaxBackoff
+	}
+	return DefaultCircuitBreakerMaxBackoff
+}
+
+// RateLimitConfig configures the Graph request rate limiter shared by all
+// collectors and tenants
+type RateLimitConfig struct {
+	// Sustained Graph requests per second (not defined or 0 = unlimited)
+	RequestsPerSecond float64 `yaml:"requestsPerSecond"`
+
+	// Maximum number of requests sent in a burst
+	Burst int `yaml:"burst"`
+}
+
+// IsEnabled returns if the rate limiter is enabled
+func (c *RateLimitConfig) IsEnabled() bool {
+	return c.RequestsPerSecond > 0
+}
+
+// GetBurst returns the burst size, at least one request
+func (c *RateLimitConfig) GetBurst() int {
+	if c.Burst > 0 {
+		return c.Burst
+	}
+	return 1
+}
+
+// SentryConfig configures error reporting to Sentry
+type SentryConfig struct {
+	// Sentry DSN (not defined = disabled)
+	DSN string `yaml:"dsn"`
+
+	// Environment reported with the events
+	Environment string `yaml:"environment"`
+}
+
+// IsEnabled returns if errors are reported to Sentry
+func (c *SentryConfig) IsEnabled() bool {
+	return c.DSN != ""
+}
+
+// ErrorReportingConfig configures which errors are forwarded to an error
+// tracking service
+type ErrorReportingConfig struct {
+	Sentry SentryConfig `yaml:"sentry"`
+
+	// Consecutive authentication failures of a tenant after which they are reported
+	AuthFailureThreshold int `yaml:"authFailureThreshold"`
+}
+
+// GetAuthFailureThreshold returns the auth failure threshold or its default
+func (c *ErrorReportingConfig) GetAuthFailureThreshold() int {
+	if c.AuthFailureThreshold > 0 {
+		return c.AuthFailureThreshold
+	}
+	return DefaultAuthFailureThreshold
+}
+
+// OTLPConfig configures pushing the metrics to an OTLP endpoint in parallel to /metrics
+type OTLPConfig struct {
+	// OTLP/HTTP metrics endpoint URL, e.g. http://otel-collector:4318/v1/metrics (not defined = disabled)
+	Endpoint string `yaml:"endpoint"`
+
+	// Additional HTTP headers, e.g. for authentication
+	Headers map[string]string `yaml:"headers"`
+
+	// How often the metrics are pushed
+	Interval time.Duration `yaml:"interval"`
+}
+
+// IsEnabled returns if metrics are pushed via OTLP
+func (c *OTLPConfig) IsEnabled() bool {
+	return c.Endpoint != ""
+}
+
+// GetInterval returns the push interval or its default
+func (c *OTLPConfig) GetInterval() time.Duration {
+	if c.Interval > 0 {
+		return c.Interval
+	}
+	return DefaultOTLPInterval
+}
+
+// InfluxConfig configures writing the metrics in Influx line protocol
+type InfluxConfig struct {
+	// Write endpoint, e.g. http://influxdb:8086/api/v2/write?org=<org>&bucket=<bucket>&precision=ns
+	URL string `yaml:"url"`
+
+	// API token sent in the Authorization header
+	Token string `yaml:"token"`
+
+	// File the lines are written to, e.g. for the Telegraf file or tail input
+	File string `yaml:"file"`
+
+	// How often the metrics are written
+	Interval time.Duration `yaml:"interval"`
+}
+
+// IsEnabled returns if the metrics are written in Influx line protocol
+func (c *InfluxConfig) IsEnabled() bool {
+	return c.URL != "" || c.File != ""
+}
+
+// GetInterval returns the write interval or its default
+func (c *InfluxConfig) GetInterval() time.Duration {
+	if c.Interval > 0 {
+		return c.Interval
+	}
+	return DefaultInfluxInterval
+}
+
+// LogAnalyticsConfig configures pushing inventory snapshots to Log Analytics
+// custom tables via the Logs Ingestion API
+type LogAnalyticsConfig struct {
+	// Data collection endpoint, e.g. https://<dce>.<region>.ingest.monitor.azure.com (not defined = disabled)
+	Endpoint string `yaml:"endpoint"`
+
+	// Immutable ID of the data collection rule
+	RuleID string `yaml:"ruleId"`
+
+	// Stream of the data collection rule per collector, e.g. users: Custom-EntraUsers
+	Streams map[string]string `yaml:"streams"`
+
+	// How often snapshots are pushed
+	Interval time.Duration `yaml:"interval"`
+}
+
+// IsEnabled returns if inventory snapshots are pushed to Log Analytics
+func (c *LogAnalyticsConfig) IsEnabled() bool {
+	return c.Endpoint != ""
+}
+
+// GetInterval returns the push interval or its default
+func (c *LogAnalyticsConfig) GetInterval() time.Duration {
+	if c.Interval > 0 {
+		return c.Interval
+	}
+	return DefaultLogAnalyticsInterval
+}
+
+func (c *LogAnalyticsConfig) validate() error {
+	if !c.IsEnabled() {
+		return nil
+	}
+	if c.RuleID == "" {
+		return fmt.Errorf("sinks.logAnalytics: ruleId is required")
+	}
+	if len(c.Streams) == 0 {
+		return fmt.Errorf("sinks.logAnalytics: at least one stream is required")
+	}
+	return nil
+}
+
+// ChangeNotificationsConfig configures Graph change notification subscriptions
+// which refresh the collectors between their scheduled collections
+type ChangeNotificationsConfig struct {
+	// Public HTTPS URL of the exporter's notification endpoint, Graph posts
+	// the notifications to it (not defined = disabled)
+	NotificationURL string `yaml:"notificationUrl"`
+
+	// Secret sent with every notification to verify its origin
+	ClientState string `yaml:"clientState"`
+
+	// Collectors whose resources are subscribed, Graph only supports change
+	// notifications for users (not defined = users)
+	Collectors []string `yaml:"collectors"`
+
+	// Time between the first notification and the refresh, to batch bursts of changes
+	Debounce time.Duration `yaml:"debounce"`
+}
+
+// IsEnabled returns if change notifications are subscribed
+func (c *ChangeNotificationsConfig) IsEnabled() bool {
+	return c.NotificationURL != ""
+}
+
+// GetDebounce returns the debounce time or its default
+func (c *ChangeNotificationsConfig) GetDebounce() time.Duration {
+	if c.Debounce > 0 {
+		return c.Debounce
+	}
+	return DefaultChangeNotificationsDebounce
+}
+
+func (c *ChangeNotificationsConfig) validate() error {
+	if !c.IsEnabled() {
+		return nil
+	}
+	if !strings.HasPrefix(c.NotificationURL, "https://") {
+		return fmt.Errorf("graph.changeNotifications: notificationUrl must be an https URL")
+	}
+	if c.ClientState == "" || len(c.ClientState) > 128 {
+		return fmt.Errorf("graph.changeNotifications: clientState is required and at most 128 characters")
+	}
+	for _, collector := range c.Collectors {
+		if collector != "users" {
+			return fmt.Errorf("graph.changeNotifications: collector %s doesn't support change notifications, only users does", collector)
+		}
+	}
+	return nil
+}
+
+// Redaction modes of personal label values
+const (
+	RedactionNone = "none"
+	RedactionHash = "hash"
+	RedactionDrop = "drop"
+)
+
+// PrivacyConfig configures how personal data is exposed in metric labels
+// and inventory exports
+type PrivacyConfig struct {
+	// Redaction of the user principal names and display names of users and
+	// devices in labels and inventory exports: "none" (default), "hash" or "drop"
+	Redaction string `yaml:"redaction"`
+
+	// HMAC-SHA256 key of the hash redaction, without it the values are
+	// hashed with plain SHA-256 and known names can be looked up
+	HashKey string `yaml:"hashKey"`
+}
+
+// minHashKeyLength is the minimum length of the privacy hash key
+const minHashKeyLength = 16
+
+func (c *PrivacyConfig) validate() error {
+	switch c.Redaction {
+	case "", RedactionNone, RedactionHash, RedactionDrop:
+	default:
+		return fmt.Errorf("privacy: invalid redaction %q (%s, %s or %s)", c.Redaction, RedactionNone, RedactionHash, RedactionDrop)
+	}
+	if c.HashKey != "" && c.Redaction != RedactionHash {
+		return fmt.Errorf("privacy: hashKey is only used with redaction %s", RedactionHash)
+	}
+	if c.HashKey != "" && len(c.HashKey) < minHashKeyLength {
+		return fmt.Errorf("privacy: hashKey must be at least %d characters", minHashKeyLength)
+	}
+	return nil
+}
+
+// LeaderElectionConfig configures the Kubernetes Lease based leader election,
+// only the leader of the replicas collects from Graph
+type LeaderElectionConfig struct {
+	// Name of the Lease object (not defined = disabled)
+	LeaseName string `yaml:"leaseName"`
+
+	// Namespace of the Lease (not defined = namespace of the pod)
+	Namespace string `yaml:"namespace"`
+
+	// Identity of this replica (not defined = hostname, the pod name)
+	Identity string `yaml:"identity"`
+
+	// How long standby replicas wait before taking over an unrenewed lease
+	LeaseDuration time.Duration `yaml:"leaseDuration"`
+
+	// How long the leader retries renewing before it steps down
+	RenewDeadline time.Duration `yaml:"renewDeadline"`
+
+	// Time between attempts to acquire or renew the lease
+	RetryPeriod time.Duration `yaml:"retryPeriod"`
+}
+
+// IsEnabled returns if leader election is enabled
+func (c *LeaderElectionConfig) IsEnabled() bool {
+	return c.LeaseName != ""
+}
+
+// GetLeaseDuration returns the lease duration or its default
+func (c *LeaderElectionConfig) GetLeaseDuration() time.Duration {
+	if c.LeaseDuration > 0 {
+		return c.LeaseDuration
+	}
+	return DefaultLeaseDuration
+}
+
+// GetRenewDeadline returns the renew deadline or its default
+func (c *LeaderElectionConfig) GetRenewDeadline() time.Duration {
+	if c.RenewDeadline > 0 {
+		return c.RenewDeadline
+	}
+	return DefaultRenewDeadline
+}
+
+// GetRetryPeriod returns the retry period or its default
+func (c *LeaderElectionConfig) GetRetryPeriod() time.Duration {
+	if c.RetryPeriod > 0 {
+		return c.RetryPeriod
+	}
+	return DefaultRetryPeriod
+}
+
+func (c *LeaderElectionConfig) validate() error {
+	if !c.IsEnabled() {
+		return nil
+	}
+	if c.GetLeaseDuration() <= c.GetRenewDeadline() {
+		return fmt.Errorf("leaderElection: leaseDuration must be greater than renewDeadline")
+	}
+	if c.GetRenewDeadline() <= c.GetRetryPeriod() {
+		return fmt.Errorf("leaderElection: renewDeadline must be greater than retryPeriod")
+	}
+	return nil
+}
+
+// pluginName restricts plugin names to what is valid in metric names
+var pluginName = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+
+// PluginConfig configures an external command whose metrics in exposition
+// format are added to the exporter's, it runs once per tenant
+type PluginConfig struct {
+	// Name of the plugin, its collector is named plugin_<name>
+	Name string `yaml:"name"`
+
+	// Command and its arguments, e.g. [/usr/local/bin/mailbox-metrics, --verbose]
+	Command []string `yaml:"command"`
+
+	// How often the command runs per tenant (not defined or 0 = disabled)
+	ScrapeTime time.Duration `yaml:"scrapeTime"`
+
+	// The command is killed when it runs longer than this
+	Timeout time.Duration `yaml:"timeout"`
+
+	// How long the last output is served when the command keeps failing
+	// (not defined or 0 = forever)
+	CacheTTL time.Duration `yaml:"cacheTTL"`
+
+	// Additional environment variables of the command
+	Env map[string]string `yaml:"env"`
+
+	// Pass a Graph access token of the tenant in ENTRA_GRAPH_TOKEN
+	GraphToken bool `yaml:"graphToken"`
+
+	// Graph host the token is requested for, e.g. https://graph.microsoft.us
+	// (default: https://graph.microsoft.com)
+	BaseURL string `yaml:"baseURL"`
+}
+
+// IsEnabled returns if the plugin runs
+func (c *PluginConfig) IsEnabled() bool {
+	return c.ScrapeTime > 0
+}
+
+// GetTimeout returns the command timeout or its default
+func (c *PluginConfig) GetTimeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return DefaultPluginTimeout
+}
+
+func (c *PluginConfig) validate() error {
+	if !pluginName.MatchString(c.Name) {
+		return fmt.Errorf("plugins: invalid name %q (letters, digits and underscores)", c.Name)
+	}
+	if len(c.Command) == 0 {
+		return fmt.Errorf("plugins: plugin %s: command is required", c.Name)
+	}
+	if c.BaseURL != "" && !validGraphHost(c.BaseURL) {
+		return fmt.Errorf("plugins: plugin %s: baseURL must be an https URL without path, e.g. https://graph.microsoft.us", c.Name)
+	}
+	return nil
+}
+
+// SQLiteConfig configures the SQLite store recording an inventory snapshot
+// of every collection cycle
+type SQLiteConfig struct {
+	// Path of the database file (not defined = disabled)
+	Path string `yaml:"path"`
+
+	// How long snapshots are kept
+	Retention time.Duration `yaml:"retention"`
+}
+
+// IsEnabled returns if inventory snapshots are recorded
+func (c *SQLiteConfig) IsEnabled() bool {
+	return c.Path != ""
+}
+
+// GetRetention returns the snapshot retention or its default
+func (c *SQLiteConfig) GetRetention() time.Duration {
+	if c.Retention > 0 {
+		return c.Retention
+	}
+	return DefaultSQLiteRetention
+}
+
+// EventsWebhookConfig configures posting change events to a webhook
+type EventsWebhookConfig struct {
+	URL string `yaml:"url"`
+}
+
+// EventsNATSConfig configures publishing change events to NATS
+type EventsNATSConfig struct {
+	URL string `yaml:"url"`
+
+	// Subject prefix, events are published to <subject>.<collector>.<type>
+	Subject string `yaml:"subject"`
+}
+
+// GetSubject returns the subject prefix or its default
+func (c *EventsNATSConfig) GetSubject() string {
+	if c.Subject != "" {
+		return c.Subject
+	}
+	return DefaultEventsNATSSubject
+}
+
+// EventsKafkaConfig configures writing change events to a Kafka topic
+type EventsKafkaConfig struct {
+	Brokers []string `yaml:"brokers"`
+	Topic   string   `yaml:"topic"`
+}
+
+// EventsConfig configures the stream of directory change events
+type EventsConfig struct {
+	// Sink type, "webhook", "nats" or "kafka" (not defined = disabled)
+	Type string `yaml:"type"`
+
+	// Collectors whose changes are published (not defined = all inventories)
+	Collectors []string `yaml:"collectors"`
+
+	Webhook EventsWebhookConfig `yaml:"webhook"`
+	NATS    EventsNATSConfig    `yaml:"nats"`
+	Kafka   EventsKafkaConfig   `yaml:"kafka"`
+}
+
+// IsEnabled returns if change events are published
+func (c *EventsConfig) IsEnabled() bool {
+	return c.Type != ""
+}
+
+func (c *EventsConfig) validate() error {
+	switch c.Type {
+	case "":
+	case EventsWebhook:
+		if c.Webhook.URL == "" {
+			return fmt.Errorf("sinks.events: webhook.url is required")
+		}
+	case EventsNATS:
+		if c.NATS.URL == "" {
+			return fmt.Errorf("sinks.events: nats.url is required")
+		}
+	case EventsKafka:
+		if len(c.Kafka.Brokers) == 0 || c.Kafka.Topic == "" {
+			return fmt.Errorf("sinks.events: kafka.brokers and kafka.topic are required")
+		}
+	default:
+		return fmt.Errorf("sinks.events: invalid type %q (must be %q, %q or %q)", c.Type, EventsWebhook, EventsNATS, EventsKafka)
+	}
+	return nil
+}
+
+// ArchiveAzureBlobConfig configures uploading snapshots to an Azure Blob Storage container
+type ArchiveAzureBlobConfig struct {
+	// Service URL of the storage account, e.g. https://<account>.blob.core.windows.net
+	URL       string `yaml:"url"`
+	Container string `yaml:"container"`
+}
+
+// ArchiveS3Config configures uploading snapshots to an S3 bucket
+type ArchiveS3Config struct {
+	Bucket string `yaml:"bucket"`
+
+	// Region of the bucket (not defined = from the AWS environment)
+	Region string `yaml:"region"`
+
+	// Endpoint of S3 compatible storage, e.g. MinIO (not defined = AWS)
+	Endpoint string `yaml:"endpoint"`
+}
+
+// ArchiveConfig configures uploading the inventory snapshot of every
+// collection cycle to object storage
+type ArchiveConfig struct {
+	// Storage type, "azureBlob" or "s3" (not defined = disabled)
+	Type string `yaml:"type"`
+
+	// Collectors whose snapshots are uploaded (not defined = all inventories)
+	Collectors []string `yaml:"collectors"`
+
+	// Prefix of the object names, e.g. entra/
+	Prefix string `yaml:"prefix"`
+
+	// How long snapshots are kept before they are deleted (not defined = forever)
+	Retention time.Duration `yaml:"retention"`
+
+	AzureBlob ArchiveAzureBlobConfig `yaml:"azureBlob"`
+	S3        ArchiveS3Config        `yaml:"s3"`
+}
+
+// IsEnabled returns if inventory snapshots are uploaded
+func (c *ArchiveConfig) IsEnabled() bool {
+	return c.Type != ""
+}
+
+func (c *ArchiveConfig) validate() error {
+	switch c.Type {
+	case "":
+	case ArchiveAzureBlob:
+		if c.AzureBlob.URL == "" || c.AzureBlob.Container == "" {
+			return fmt.Errorf("sinks.archive: azureBlob.url and azureBlob.container are required")
+		}
+	case ArchiveS3:
+		if c.S3.Bucket == "" {
+			return fmt.Errorf("sinks.archive: s3.bucket is required")
+		}
+	default:
+		return fmt.Errorf("sinks.archive: invalid type %q (must be %q or %q)", c.Type, ArchiveAzureBlob, ArchiveS3)
+	}
+	if c.Retention < 0 {
+		return fmt.Errorf("sinks.archive: retention must not be negative")
+	}
+	return nil
+}
+
+// Webhook is a notification target
+type Webhook struct {
+	Name string `yaml:"name"`
+
+	// Payload format, "generic" (default), "slack" or "teams"
+	Type string `yaml:"type"`
+
+	URL string `yaml:"url"`
+}
+
+// NotificationRule is a condition on a collected metric
+type NotificationRule struct {
+	Name string `yaml:"name"`
+
+	// Metric name and optional label values the series must have
+	Metric string            `yaml:"metric"`
+	Labels map[string]string `yaml:"labels"`
+
+	// One of <, <=, >, >=, ==, != compared against value, or "new" for
+	// series which didn't exist at the previous evaluation
+	Condition string  `yaml:"condition"`
+	Value     float64 `yaml:"value"`
+
+	// Notify again while the series keeps matching (not defined or 0 = once)
+	RepeatInterval time.Duration `yaml:"repeatInterval"`
+
+	// Names of the webhooks to notify (not defined = all)
+	Webhooks []string `yaml:"webhooks"`
+}
+
+// Matches returns if the value fulfills the condition of the rule
+func (r *NotificationRule) Matches(value float64) bool {
+	switch r.Condition {
+	case "<":
+		return value < r.Value
+	case "<=":
+		return value <= r.Value
+	case ">":
+		return value > r.Value
+	case ">=":
+		return value >= r.Value
+	case "==":
+		return value == r.Value
+	case "!=":
+		return value != r.Value
+	case ConditionNew:
+		return true
+	default:
+		return false
+	}
+}
+
+// NotificationsConfig configures the notification rules and webhooks
+type NotificationsConfig struct {
+	// How often the rules are evaluated
+	Interval time.Duration `yaml:"interval"`
+
+	Webhooks []Webhook          `yaml:"webhooks"`
+	Rules    []NotificationRule `yaml:"rules"`
+}
+
+// IsEnabled returns if notification rules are configured
+func (c *NotificationsConfig) IsEnabled() bool {
+	return len(c.Rules) > 0
+}
+
+// GetInterval returns the evaluation interval or its default
+func (c *NotificationsConfig) GetInterval() time.Duration {
+	if c.Interval > 0 {
+		return c.Interval
+	}
+	return DefaultNotificationsInterval
+}
+
+// WebhooksOf returns the webhooks notified by the rule
+func (c *NotificationsConfig) WebhooksOf(rule NotificationRule) []Webhook {
+	if len(rule.Webhooks) == 0 {
+		return c.Webhooks
+	}
+
+	var webhooks []Webhook
+	for _, webhook := range c.Webhooks {
+		for _, name := range rule.Webhooks {
+			if webhook.Name == name {
+				webhooks = append(webhooks, webhook)
+			}
+		}
+	}
+	return webhooks
+}
+
+func (c *NotificationsConfig) validate() error {
+	webhooks := map[string]bool{}
+	for _, webhook := range c.Webhooks {
+		switch webhook.Type {
+		case "", WebhookGeneric, WebhookSlack, WebhookTeams:
+		default:
+			return fmt.Errorf("notifications: webhook %s: invalid type %q", webhook.Name, webhook.Type)
+		}
+		if webhook.URL == "" {
+			return fmt.Errorf("notifications: webhook %s: url is required", webhook.Name)
+		}
+		webhooks[webhook.Name] = true
+	}
+
+	rules := map[string]bool{}
+	for _, rule := range c.Rules {
+		if rule.Name == "" || rules[rule.Name] {
+			return fmt.Errorf("notifications: rules need a unique name, got %q", rule.Name)
+		}
+		rules[rule.Name] = true
+
+		if rule.Metric == "" {
+			return fmt.Errorf("notifications: rule %s: metric is required", rule.Name)
+		}
+		switch rule.Condition {
+		case "<", "<=", ">", ">=", "==", "!=", ConditionNew:
+		default:
+			return fmt.Errorf("notifications: rule %s: invalid condition %q", rule.Name, rule.Condition)
+		}
+		for _, name := range rule.Webhooks {
+			if !webhooks[name] {
+				return fmt.Errorf("notifications: rule %s: unknown webhook %q", rule.Name, name)
+			}
+		}
+	}
+	return nil
+}
+
 // Config is the root configuration
 type Config struct {
 	Logger *logrus.Logger
 
+	// Oneshot disables background collection, collectors only collect when
+	// RunOnce is called
+	Oneshot bool `yaml:"-"`
+
 	Azure struct {
 		// List of tenant IDs
 		Tenants []string `yaml:"tenants"`
+
+		// Only scrape tenants whose ID fully matches this regex (not defined = all)
+		TenantInclude string `yaml:"tenantInclude"`
+
+		// Don't scrape tenants whose ID fully matches this regex
+		TenantExclude string `yaml:"tenantExclude"`
 	} `yaml:"azure"`
 
+	// Compiled tenant filters
+	tenantInclude *regexp.Regexp
+	tenantExclude *regexp.Regexp
+
+	Graph struct {
+		// Circuit breaker for tenants whose collections keep failing
+		CircuitBreaker CircuitBreakerConfig `yaml:"circuitBreaker"`
+
+		// Rate limit for Graph requests across all collectors and tenants
+		RateLimit RateLimitConfig `yaml:"rateLimit"`
+
+		// Log page requests taking longer than this at warn level (not defined or 0 = disabled)
+		SlowRequestThreshold time.Duration `yaml:"slowRequestThreshold"`
+
+		// Change notification subscriptions refreshing the collectors on changes
+		ChangeNotifications ChangeNotificationsConfig `yaml:"changeNotifications"`
+
+		// Log the Graph endpoints and permissions each collection cycle used
+		AuditLog bool `yaml:"auditLog"`
+
+		// User-Agent of all Graph requests (default: entra-exporter/<version>)
+		UserAgent string `yaml:"userAgent"`
+
+		// Don't send the SDK telemetry, the SdkVersion header and the SDK
+		// product appended to the User-Agent
+		DisableSDKTelemetry bool `yaml:"disableSdkTelemetry"`
+	} `yaml:"graph"`
+
+	// Forwarding of collector panics and persistent auth failures
+	ErrorReporting ErrorReportingConfig `yaml:"errorReporting"`
+
+	// Only the elected leader of several replicas collects from Graph
+	LeaderElection LeaderElectionConfig `yaml:"leaderElection"`
+
+	// Redaction of personal data in metric labels and inventory exports
+	Privacy PrivacyConfig `yaml:"privacy"`
+
+	Web struct {
+		// Bearer token required by the /debug/, /-/, /inventory/, /export/
+		// and /history/ endpoints, /-/pause and /-/resume are only available
+		// with it (not defined = no authentication)
+		DebugToken string `yaml:"debugToken"`
+
+		// Time to wait for in-flight collections on shutdown
+		// (default: 30s)
+		ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+	} `yaml:"web"`
+
+	Metrics struct {
+		// Expose collection durations as histograms in addition to the summaries
+		DurationHistogram bool `yaml:"durationHistogram"`
+
+		// Buckets of the duration histograms in seconds
+		DurationBuckets []float64 `yaml:"durationBuckets"`
+
+		// Only expose counts and breakdowns, no per object *_info series
+		AggregateOnly bool `yaml:"aggregateOnly"`
+
+		// Attach the time the data was collected from Graph to the cached samples
+		CollectionTimestamps bool `yaml:"collectionTimestamps"`
+
+		// Push the metrics to an OTLP endpoint
+		OTLP OTLPConfig `yaml:"otlp"`
+
+		// Write the metrics in Influx line protocol
+		Influx InfluxConfig `yaml:"influx"`
+	} `yaml:"metrics"`
+
+	// Webhook notifications for conditions on the collected metrics
+	Notifications NotificationsConfig `yaml:"notifications"`
+
+	// Destinations collected inventories are pushed to
+	Sinks struct {
+		LogAnalytics LogAnalyticsConfig `yaml:"logAnalytics"`
+		SQLite       SQLiteConfig       `yaml:"sqlite"`
+		Events       EventsConfig       `yaml:"events"`
+		Archive      ArchiveConfig      `yaml:"archive"`
+	} `yaml:"sinks"`
+
+	// External commands adding their metrics, e.g. for endpoints the exporter doesn't support
+	Plugins []PluginConfig `yaml:"plugins"`
+
 	Collector struct {
-		General                  CollectorConfig `yaml:"general"`
-		Users                    CollectorConfig `yaml:"users"`
-		Devices                  CollectorConfig `yaml:"devices"`
-		Applications             CollectorConfig `yaml:"applications"`
-		ServicePrincipals        CollectorConfig `yaml:"servicePrincipals"`
-		Groups                   CollectorConfig `yaml:"groups"`
-		ConditionalAccessPolicies CollectorConfig `yaml:"conditionalAccessPolicies"`
-		DirectoryRoles           CollectorConfig `yaml:"directoryRoles"`
+		General                   CollectorConfig      `yaml:"general"`
+		Users                     UsersConfig          `yaml:"users"`
+		Devices                   CollectorConfig      `yaml:"devices"`
+		Applications              CollectorConfig      `yaml:"applications"`
+		ServicePrincipals         CollectorConfig      `yaml:"servicePrincipals"`
+		Groups                    CollectorConfig      `yaml:"groups"`
+		ConditionalAccessPolicies CollectorConfig      `yaml:"conditionalAccessPolicies"`
+		DirectoryRoles            CollectorConfig      `yaml:"directoryRoles"`
+		PIM                       ActivityConfig       `yaml:"pim"`
+		AuthMethods               CollectorConfig      `yaml:"authMethods"`
+		SignIns                   SignInsConfig        `yaml:"signIns"`
+		Autopilot                 CollectorConfig      `yaml:"autopilot"`
+		Consents                  ConsentsConfig       `yaml:"consents"`
+		AdministrativeUnits       CollectorConfig      `yaml:"administrativeUnits"`
+		TenantSettings            CollectorConfig      `yaml:"tenantSettings"`
+		Domains                   CollectorConfig      `yaml:"domains"`
+		HybridSync                CollectorConfig      `yaml:"hybridSync"`
+		DeletedItems              DeletedItemsConfig   `yaml:"deletedItems"`
+		SecurityAlerts            CollectorConfig      `yaml:"securityAlerts"`
+		AccessPackages            AccessPackagesConfig `yaml:"accessPackages"`
+		TermsOfUse                CollectorConfig      `yaml:"termsOfUse"`
+		Custom                    CustomConfig         `yaml:"custom"`
 	} `yaml:"collectors"`
 }
 
@@ -55,5 +1419,215 @@ func (c *Config) LoadConfigFile(path string) error {
 		return err
 	}
 
-	return yaml.Unmarshal(ymlBytes, c)
+	if err := yaml.Unmarshal(ymlBytes, c); err != nil {
+		return err
+	}
+	c.warnUnknownKeys(ymlBytes)
+
+	return c.validate()
+}
+
+// warnUnknownKeys logs the keys of the config file which are ignored, e.g.
+// misspelled options or options set on a collector which doesn't support them
+func (c *Config) warnUnknownKeys(ymlBytes []byte) {
+	decoder := yaml.NewDecoder(bytes.NewReader(ymlBytes))
+	decoder.KnownFields(true)
+
+	var typeErr *yaml.TypeError
+	if err := decoder.Decode(&Config{}); errors.As(err, &typeErr) {
+		for _, message := range typeErr.Errors {
+			if strings.Contains(message, "not found in type") {
+				c.Logger.Warnf("ignoring unknown config option, %s", message)
+			}
+		}
+	}
+}
+
+// Secrets returns the secret values of the configuration, e.g. tokens and
+// webhook URLs, which must not appear in logs
+func (c *Config) Secrets() []string {
+	secrets := []string{
+		c.ErrorReporting.Sentry.DSN,
+		c.Metrics.Influx.Token,
+		c.Graph.ChangeNotifications.ClientState,
+		c.Privacy.HashKey,
+		c.Web.DebugToken,
+		c.Sinks.Events.Webhook.URL,
+	}
+	for _, value := range c.Metrics.OTLP.Headers {
+		secrets = append(secrets, value)
+	}
+	for _, webhook := range c.Notifications.Webhooks {
+		secrets = append(secrets, webhook.URL)
+	}
+	for _, plugin := range c.Plugins {
+		for _, value := range plugin.Env {
+			secrets = append(secrets, value)
+		}
+	}
+	return secrets
+}
+
+// GetGraphUserAgent returns the configured User-Agent of Graph requests or
+// the default with the exporter version
+func (c *Config) GetGraphUserAgent(version string) string {
+	if c.Graph.UserAgent != "" {
+		return c.Graph.UserAgent
+	}
+	return "entra-exporter/" + version
+}
+
+// GetShutdownTimeout returns the time to wait for in-flight collections on
+// shutdown or its default
+func (c *Config) GetShutdownTimeout() time.Duration {
+	if c.Web.ShutdownTimeout > 0 {
+		return c.Web.ShutdownTimeout
+	}
+	return DefaultShutdownTimeout
+}
+
+// GetDurationBuckets returns the duration histogram buckets or their default
+func (c *Config) GetDurationBuckets() []float64 {
+	if len(c.Metrics.DurationBuckets) > 0 {
+		return c.Metrics.DurationBuckets
+	}
+	return DefaultDurationBuckets
+}
+
+// collectors returns all collector configurations by name
+func (c *Config) collectors() map[string]*CollectorConfig {
+	return map[string]*CollectorConfig{
+		"general":                   &c.Collector.General,
+		"users":                     &c.Collector.Users.CollectorConfig,
+		"devices":                   &c.Collector.Devices,
+		"applications":              &c.Collector.Applications,
+		"servicePrincipals":         &c.Collector.ServicePrincipals,
+		"groups":                    &c.Collector.Groups,
+		"conditionalAccessPolicies": &c.Collector.ConditionalAccessPolicies,
+		"directoryRoles":            &c.Collector.DirectoryRoles,
+		"pim":                       &c.Collector.PIM.CollectorConfig,
+		"authMethods":               &c.Collector.AuthMethods,
+		"signIns":                   &c.Collector.SignIns.CollectorConfig,
+		"autopilot":                 &c.Collector.Autopilot,
+		"consents":                  &c.Collector.Consents.CollectorConfig,
+		"administrativeUnits":       &c.Collector.AdministrativeUnits,
+		"tenantSettings":            &c.Collector.TenantSettings,
+		"domains":                   &c.Collector.Domains,
+		"hybridSync":                &c.Collector.HybridSync,
+		"deletedItems":              &c.Collector.DeletedItems.CollectorConfig,
+		"securityAlerts":            &c.Collector.SecurityAlerts,
+		"accessPackages":            &c.Collector.AccessPackages.CollectorConfig,
+		"termsOfUse":                &c.Collector.TermsOfUse,
+		"custom":                    &c.Collector.Custom.CollectorConfig,
+	}
+}
+
+// collectorOptions returns the configurations of the collectors with
+// options of their own by name
+func (c *Config) collectorOptions() map[string]collectorOptions {
+	return map[string]collectorOptions{
+		"users":          &c.Collector.Users,
+		"pim":            &c.Collector.PIM,
+		"signIns":        &c.Collector.SignIns,
+		"deletedItems":   &c.Collector.DeletedItems,
+		"accessPackages": &c.Collector.AccessPackages,
+		"custom":         &c.Collector.Custom,
+	}
+}
+
+// GetCollector returns the configuration of the named collector, or nil if
+// there is no such collector
+func (c *Config) GetCollector(name string) *CollectorConfig {
+	return c.collectors()[name]
+}
+
+// validate checks the loaded configuration
+func (c *Config) validate() error {
+	if err := c.compileTenantFilters(); err != nil {
+		return err
+	}
+	for name, collectorConfig := range c.collectors() {
+		if err := collectorConfig.validate(name); err != nil {
+			return err
+		}
+	}
+	for name, options := range c.collectorOptions() {
+		if err := options.validateOptions(name); err != nil {
+			return err
+		}
+	}
+	if err := c.Graph.ChangeNotifications.validate(); err != nil {
+		return err
+	}
+	if err := c.LeaderElection.validate(); err != nil {
+		return err
+	}
+	if err := c.Privacy.validate(); err != nil {
+		return err
+	}
+	plugins := map[string]bool{}
+	for _, plugin := range c.Plugins {
+		if err := plugin.validate(); err != nil {
+			return err
+		}
+		if plugins[plugin.Name] {
+			return fmt.Errorf("plugins: duplicate name %q", plugin.Name)
+		}
+		plugins[plugin.Name] = true
+	}
+	if err := c.Notifications.validate(); err != nil {
+		return err
+	}
+	if err := c.Sinks.Events.validate(); err != nil {
+		return err
+	}
+	if err := c.Sinks.Archive.validate(); err != nil {
+		return err
+	}
+	return c.Sinks.LogAnalytics.validate()
+}
+
+// compileTenantFilters compiles the tenant include and exclude regexes
+func (c *Config) compileTenantFilters() (err error) {
+	c.tenantInclude, c.tenantExclude = nil, nil
+	if c.Azure.TenantInclude != "" {
+		if c.tenantInclude, err = regexp.Compile("^(?:" + c.Azure.TenantInclude + ")$"); err != nil {
+			return fmt.Errorf("azure: invalid tenantInclude: %w", err)
+		}
+	}
+	if c.Azure.TenantExclude != "" {
+		if c.tenantExclude, err = regexp.Compile("^(?:" + c.Azure.TenantExclude + ")$"); err != nil {
+			return fmt.Errorf("azure: invalid tenantExclude: %w", err)
+		}
+	}
+	return nil
+}
+
+// SetTenantFilters replaces the configured tenant filters with the defined ones
+func (c *Config) SetTenantFilters(include, exclude string) error {
+	if include != "" {
+		c.Azure.TenantInclude = include
+	}
+	if exclude != "" {
+		c.Azure.TenantExclude = exclude
+	}
+	return c.compileTenantFilters()
+}
+
+// FilterTenants returns the tenants passing the include and exclude filters
+func (c *Config) FilterTenants(tenants []string) []string {
+	if c.tenantInclude == nil && c.tenantExclude == nil {
+		return tenants
+	}
+	filtered := make([]string, 0, len(tenants))
+	for _, tenantID := range tenants {
+		if c.tenantInclude != nil && !c.tenantInclude.MatchString(tenantID) {
+			continue
+		}
+		if c.tenantExclude != nil && c.tenantExclude.MatchString(tenantID) {
+			continue
+		}
+		filtered = append(filtered, tenantID)
+	}
+	return filtered
 }
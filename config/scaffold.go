@@ -0,0 +1,162 @@
+package config
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strings"
+)
+
+// configSource is parsed for the config structs and their field comments, so
+// the scaffolded config always matches what is loaded
+//
+//go:embed config.go
+var configSource []byte
+
+// scaffold writes the commented config from the struct definitions
+type scaffold struct {
+	buf   bytes.Buffer
+	types map[string]*ast.StructType
+	// Named type fields whose comment was already written
+	documented map[string]bool
+	// Options of list entries are written uncommented, the entry as a whole
+	// is commented out
+	entry bool
+}
+
+// Scaffold returns a config file with every option commented out and
+// documented by the comment of its struct field
+func Scaffold() ([]byte, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), "config.go", configSource, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config structs: %v", err)
+	}
+
+	s := &scaffold{
+		types:      map[string]*ast.StructType{},
+		documented: map[string]bool{},
+	}
+	ast.Inspect(file, func(node ast.Node) bool {
+		if spec, ok := node.(*ast.TypeSpec); ok {
+			if structType, ok := spec.Type.(*ast.StructType); ok {
+				s.types[spec.Name.Name] = structType
+			}
+		}
+		return true
+	})
+
+	root, exists := s.types["Config"]
+	if !exists {
+		return nil, fmt.Errorf("config struct not found")
+	}
+	s.buf.WriteString("# Entra ID exporter configuration, every option is commented out with its zero value\n")
+	s.writeStruct(root, "Config", 0)
+	return s.buf.Bytes(), nil
+}
+
+// writeStruct writes the fields of the struct, the fields of inlined
+// embedded structs are written as its own
+func (s *scaffold) writeStruct(structType *ast.StructType, typeName string, indent int) {
+	for _, field := range structType.Fields.List {
+		if field.Tag == nil {
+			continue
+		}
+		name := reflect.StructTag(strings.Trim(field.Tag.Value, "`")).Get("yaml")
+		if len(field.Names) == 0 {
+			if embedded, ok := field.Type.(*ast.Ident); ok && name == ",inline" {
+				if embeddedType, exists := s.types[embedded.Name]; exists {
+					s.writeStruct(embeddedType, embedded.Name, indent)
+				}
+			}
+			continue
+		}
+		if name == "" || name == "-" {
+			continue
+		}
+
+		doc := field.Doc.Text()
+
+		if indent == 0 && !s.entry {
+			s.buf.WriteString("\n")
+		}
+		// Comments of named types are written at their first occurrence only
+		key := typeName + "." + field.Names[0].Name
+		if typeName == "" || !s.documented[key] {
+			s.documented[key] = true
+			for _, line := range strings.Split(strings.TrimSpace(doc), "\n") {
+				if line != "" {
+					s.writeLine(indent, false, "# "+line)
+				}
+			}
+		}
+		s.writeField(field.Type, name, indent)
+	}
+}
+
+// writeField writes a field by its type, sections stay uncommented so
+// uncommenting an option is enough to set it
+func (s *scaffold) writeField(expr ast.Expr, name string, indent int) {
+	switch fieldType := expr.(type) {
+	case *ast.StructType:
+		s.writeLine(indent, false, name+":")
+		s.writeStruct(fieldType, "", indent+2)
+	case *ast.Ident:
+		if structType, exists := s.types[fieldType.Name]; exists {
+			s.writeLine(indent, false, name+":")
+			s.writeStruct(structType, fieldType.Name, indent+2)
+			return
+		}
+		s.writeLine(indent, true, name+": "+zeroValue(fieldType.Name))
+	case *ast.SelectorExpr:
+		// time.Duration is the only imported type
+		s.writeLine(indent, true, name+": 0s")
+	case *ast.ArrayType:
+		if elem, ok := fieldType.Elt.(*ast.Ident); ok {
+			if structType, exists := s.types[elem.Name]; exists {
+				// Lists of structs get a commented out example entry
+				entry := &scaffold{types: s.types, documented: s.documented, entry: true}
+				entry.writeStruct(structType, elem.Name, 0)
+				s.writeLine(indent, true, name+":")
+				item := "  - "
+				for _, line := range strings.Split(strings.TrimSuffix(entry.buf.String(), "\n"), "\n") {
+					if strings.HasPrefix(line, "#") {
+						s.writeLine(indent, true, "    "+line)
+						continue
+					}
+					s.writeLine(indent, true, item+line)
+					item = "    "
+				}
+				return
+			}
+		}
+		s.writeLine(indent, true, name+": []")
+	case *ast.MapType:
+		s.writeLine(indent, true, name+": {}")
+	}
+}
+
+// writeLine writes an indented line, commented out options keep their
+// indentation after the comment marker
+func (s *scaffold) writeLine(indent int, commented bool, line string) {
+	s.buf.WriteString(strings.Repeat(" ", indent))
+	if commented && !s.entry {
+		s.buf.WriteString("# ")
+	}
+	s.buf.WriteString(line + "\n")
+}
+
+// zeroValue returns the YAML zero value of a basic Go type
+func zeroValue(typeName string) string {
+	switch typeName {
+	case "string":
+		return `""`
+	case "bool":
+		return "false"
+	default:
+		return "0"
+	}
+}
@@ -0,0 +1,224 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/entra-exporter/collector"
+	"github.com/your-username/entra-exporter/config"
+)
+
+// Event types
+const (
+	Added   = "added"
+	Updated = "updated"
+	Deleted = "deleted"
+)
+
+// Event is a change of a directory object between two collection cycles
+type Event struct {
+	Type      string            `json:"type"`
+	Collector string            `json:"collector"`
+	TenantID  string            `json:"tenantId"`
+	ObjectID  string            `json:"objectId"`
+	Time      time.Time         `json:"time"`
+	Data      map[string]string `json:"data,omitempty"`
+	Previous  map[string]string `json:"previous,omitempty"`
+}
+
+// Publisher sends events to a sink
+type Publisher interface {
+	Publish(ctx context.Context, events []Event) error
+	Close() error
+}
+
+// NewPublisher creates the publisher of the configured sink type
+func NewPublisher(cfg config.EventsConfig) (Publisher, error) {
+	switch cfg.Type {
+	case config.EventsWebhook:
+		return newWebhookPublisher(cfg.Webhook), nil
+	case config.EventsNATS:
+		return newNATSPublisher(cfg.NATS)
+	case config.EventsKafka:
+		return newKafkaPublisher(cfg.Kafka), nil
+	default:
+		return nil, fmt.Errorf("unknown events sink type %q", cfg.Type)
+	}
+}
+
+// Stream emits add, update and delete events by comparing the inventory of
+// every collection cycle with the previous one
+type Stream struct {
+	logger    *logrus.Entry
+	publisher Publisher
+	exporters map[string]collector.InventoryExporter
+
+	// Rows of the previous cycle by object ID per tenant and collector,
+	// tenants without rows yet have no baseline
+	previous map[string]map[string]map[string][]string
+
+	// Names of the collectors with a completed collection cycle
+	pending chan string
+}
+
+// NewStream creates a stream of the changes of the configured collectors
+func NewStream(cfg config.EventsConfig, collectors []collector.Collector, logger *logrus.Entry) (*Stream, error) {
+	publisher, err := NewPublisher(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Stream{
+		logger:    logger,
+		publisher: publisher,
+		exporters: map[string]collector.InventoryExporter{},
+		previous:  map[string]map[string]map[string][]string{},
+		pending:   make(chan string, len(collectors)),
+	}
+	for _, c := range collectors {
+		exporter, ok := c.(collector.InventoryExporter)
+		if !ok {
+			continue
+		}
+		if len(cfg.Collectors) == 0 || slices.Contains(cfg.Collectors, exporter.Name()) {
+			s.exporters[exporter.Name()] = exporter
+		}
+	}
+
+	return s, nil
+}
+
+// CollectionCompleted queues the comparison of the collector's inventory, it
+// is registered as collection hook and doesn't block the collection
+func (s *Stream) CollectionCompleted(name string) {
+	if _, exists := s.exporters[name]; !exists {
+		return
+	}
+
+	select {
+	case s.pending <- name:
+	default:
+		s.logger.Warnf("Skipping %s change detection, previous events are still being published", name)
+	}
+}
+
+// Run publishes the changes of the queued collections until the context is cancelled
+func (s *Stream) Run(ctx context.Context) {
+	s.logger.Info("Publishing directory change events")
+	defer s.publisher.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case name := <-s.pending:
+			events := s.changes(s.exporters[name])
+			if len(events) == 0 {
+				continue
+			}
+
+			if err := s.publisher.Publish(ctx, events); err != nil {
+				s.logger.Errorf("Failed to publish %d %s change events: %v", len(events), name, err)
+				continue
+			}
+			s.logger.Debugf("Published %d %s change events", len(events), name)
+		}
+	}
+}
+
+// changes compares the current inventory of the collector with the previous
+// one per tenant. The first complete inventory of a tenant is its baseline
+// without events, tenants whose inventory is incomplete, e.g. because their
+// last collection failed or their cache expired, keep their previous rows
+// until a later cycle completes.
+func (s *Stream) changes(exporter collector.InventoryExporter) []Event {
+	header, rows := exporter.Inventory()
+
+	// The first columns of every inventory are the tenant and object ID
+	current := map[string]map[string][]string{}
+	for row := range rows {
+		if current[row[0]] == nil {
+			current[row[0]] = map[string][]string{}
+		}
+		current[row[0]][row[1]] = row
+	}
+
+	previous, exists := s.previous[exporter.Name()]
+	if !exists {
+		previous = map[string]map[string][]string{}
+		s.previous[exporter.Name()] = previous
+	}
+
+	// Tenants without any object left are compared too
+	tenants := map[string]bool{}
+	for tenantID := range current {
+		tenants[tenantID] = true
+	}
+	for tenantID := range previous {
+		tenants[tenantID] = true
+	}
+
+	now := time.Now()
+	newEvent := func(eventType, tenantID, objectID string) Event {
+		return Event{
+			Type:      eventType,
+			Collector: exporter.Name(),
+			TenantID:  tenantID,
+			ObjectID:  objectID,
+			Time:      now,
+		}
+	}
+
+	var events []Event
+	for tenantID := range tenants {
+		if !exporter.InventoryComplete(tenantID) {
+			continue
+		}
+
+		objects := current[tenantID]
+		if objects == nil {
+			objects = map[string][]string{}
+		}
+		previousObjects, baselined := previous[tenantID]
+		previous[tenantID] = objects
+		if !baselined {
+			continue
+		}
+
+		for objectID, row := range objects {
+			previousRow, existed := previousObjects[objectID]
+			switch {
+			case !existed:
+				event := newEvent(Added, tenantID, objectID)
+				event.Data = rowData(header, row)
+				events = append(events, event)
+			case !slices.Equal(row, previousRow):
+				event := newEvent(Updated, tenantID, objectID)
+				event.Data = rowData(header, row)
+				event.Previous = rowData(header, previousRow)
+				events = append(events, event)
+			}
+		}
+		for objectID, row := range previousObjects {
+			if _, exists := objects[objectID]; !exists {
+				event := newEvent(Deleted, tenantID, objectID)
+				event.Previous = rowData(header, row)
+				events = append(events, event)
+			}
+		}
+	}
+
+	return events
+}
+
+// rowData maps the columns of an inventory row to their values
+func rowData(header, row []string) map[string]string {
+	data := make(map[string]string, len(header))
+	for i, column := range header {
+		data[column] = row[i]
+	}
+	return data
+}
@@ -0,0 +1,131 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/segmentio/kafka-go"
+	"github.com/your-username/entra-exporter/config"
+)
+
+// webhookPublisher posts the events of a collection as JSON array
+type webhookPublisher struct {
+	url    string
+	client *http.Client
+}
+
+func newWebhookPublisher(cfg config.EventsWebhookConfig) *webhookPublisher {
+	return &webhookPublisher{
+		url:    cfg.URL,
+		client: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// Publish implements Publisher
+func (p *webhookPublisher) Publish(ctx context.Context, events []Event) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// Close implements Publisher
+func (p *webhookPublisher) Close() error {
+	return nil
+}
+
+// natsPublisher publishes every event to <subject>.<collector>.<type>
+type natsPublisher struct {
+	conn    *nats.Conn
+	subject string
+}
+
+func newNATSPublisher(cfg config.EventsNATSConfig) (*natsPublisher, error) {
+	conn, err := nats.Connect(cfg.URL, nats.Name("entra-exporter"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	return &natsPublisher{conn: conn, subject: cfg.GetSubject()}, nil
+}
+
+// Publish implements Publisher
+func (p *natsPublisher) Publish(ctx context.Context, events []Event) error {
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if err := p.conn.Publish(fmt.Sprintf("%s.%s.%s", p.subject, event.Collector, event.Type), data); err != nil {
+			return err
+		}
+	}
+	return p.conn.FlushWithContext(ctx)
+}
+
+// Close implements Publisher
+func (p *natsPublisher) Close() error {
+	return p.conn.Drain()
+}
+
+// kafkaPublisher writes every event to the topic, keyed by object ID so the
+// changes of an object stay ordered within a partition
+type kafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+func newKafkaPublisher(cfg config.EventsKafkaConfig) *kafkaPublisher {
+	return &kafkaPublisher{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(cfg.Brokers...),
+			Topic:        cfg.Topic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+		},
+	}
+}
+
+// Publish implements Publisher
+func (p *kafkaPublisher) Publish(ctx context.Context, events []Event) error {
+	messages := make([]kafka.Message, 0, len(events))
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, kafka.Message{
+			Key:   []byte(event.TenantID + "/" + event.ObjectID),
+			Value: data,
+		})
+	}
+	return p.writer.WriteMessages(ctx, messages...)
+}
+
+// Close implements Publisher
+func (p *kafkaPublisher) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,5 @@
+// Package inventorypb contains the protobuf definitions of the gRPC inventory
+// query API and the code generated from them
+package inventorypb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative inventory.proto
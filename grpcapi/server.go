@@ -0,0 +1,154 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/entra-exporter/collector"
+	"github.com/your-username/entra-exporter/grpcapi/inventorypb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server serves the gRPC inventory query API from the cached inventories, so
+// lookups don't need a Graph round trip
+type Server struct {
+	inventorypb.UnimplementedInventoryServer
+
+	logger    *logrus.Entry
+	server    *grpc.Server
+	exporters map[string]collector.InventoryExporter
+}
+
+// NewServer creates a server for the inventories of the collectors
+func NewServer(collectors []collector.Collector, logger *logrus.Entry) *Server {
+	s := &Server{
+		logger:    logger,
+		server:    grpc.NewServer(),
+		exporters: map[string]collector.InventoryExporter{},
+	}
+	for _, c := range collectors {
+		if exporter, ok := c.(collector.InventoryExporter); ok {
+			s.exporters[exporter.Name()] = exporter
+		}
+	}
+
+	inventorypb.RegisterInventoryServer(s.server, s)
+	return s
+}
+
+// ListenAndServe serves the API on the address until Stop is called
+func (s *Server) ListenAndServe(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return s.server.Serve(listener)
+}
+
+// Stop waits for the running calls and stops the server
+func (s *Server) Stop() {
+	s.server.GracefulStop()
+}
+
+// ListCollectors implements inventorypb.InventoryServer
+func (s *Server) ListCollectors(ctx context.Context, req *inventorypb.ListCollectorsRequest) (*inventorypb.ListCollectorsResponse, error) {
+	names := make([]string, 0, len(s.exporters))
+	for name := range s.exporters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	resp := &inventorypb.ListCollectorsResponse{}
+	for _, name := range names {
+		header, _ := s.exporters[name].Inventory()
+		resp.Collectors = append(resp.Collectors, &inventorypb.CollectorInfo{Name: name, Columns: header})
+	}
+	return resp, nil
+}
+
+// GetObject implements inventorypb.InventoryServer
+func (s *Server) GetObject(ctx context.Context, req *inventorypb.GetObjectRequest) (*inventorypb.Object, error) {
+	exporter, err := s.exporter(req.GetCollector())
+	if err != nil {
+		return nil, err
+	}
+	if req.GetObjectId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "object_id is required")
+	}
+
+	// The first columns of every inventory are the tenant and object ID
+	header, rows := exporter.Inventory()
+	for row := range rows {
+		if !strings.EqualFold(row[1], req.GetObjectId()) {
+			continue
+		}
+		if req.GetTenantId() != "" && !strings.EqualFold(row[0], req.GetTenantId()) {
+			continue
+		}
+		return newObject(exporter.Name(), header, row), nil
+	}
+	return nil, status.Errorf(codes.NotFound, "%s object %s not found", exporter.Name(), req.GetObjectId())
+}
+
+// FindObjects implements inventorypb.InventoryServer
+func (s *Server) FindObjects(req *inventorypb.FindObjectsRequest, stream inventorypb.Inventory_FindObjectsServer) error {
+	exporter, err := s.exporter(req.GetCollector())
+	if err != nil {
+		return err
+	}
+
+	header, rows, err := collector.FilterInventory(exporter, req.GetFilters())
+	if err != nil {
+		var filterErr *collector.InvalidFilterError
+		if errors.As(err, &filterErr) {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	sent := uint32(0)
+	for row := range rows {
+		if err := stream.Context().Err(); err != nil {
+			return status.FromContextError(err).Err()
+		}
+		if err := stream.Send(newObject(exporter.Name(), header, row)); err != nil {
+			return err
+		}
+
+		sent++
+		if req.GetLimit() > 0 && sent >= req.GetLimit() {
+			break
+		}
+	}
+	s.logger.Debugf("Sent %d %s objects for filters %v", sent, exporter.Name(), req.GetFilters())
+	return nil
+}
+
+// exporter returns the inventory of the collector or a NotFound error
+func (s *Server) exporter(name string) (collector.InventoryExporter, error) {
+	exporter, exists := s.exporters[name]
+	if !exists {
+		return nil, status.Errorf(codes.NotFound, "no inventory for collector %q", name)
+	}
+	return exporter, nil
+}
+
+// newObject maps an inventory row to its message
+func newObject(collectorName string, header, row []string) *inventorypb.Object {
+	object := &inventorypb.Object{
+		Collector:  collectorName,
+		TenantId:   row[0],
+		ObjectId:   row[1],
+		Attributes: make(map[string]string, len(header)),
+	}
+	for i, column := range header {
+		object.Attributes[column] = row[i]
+	}
+	return object
+}
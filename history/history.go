@@ -0,0 +1,174 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/entra-exporter/collector"
+	"github.com/your-username/entra-exporter/config"
+	_ "modernc.org/sqlite"
+)
+
+// schema creates the snapshot tables, objects hold the inventory columns as JSON
+const schema = `
+CREATE TABLE IF NOT EXISTS snapshots (
+	id        INTEGER PRIMARY KEY AUTOINCREMENT,
+	collector TEXT    NOT NULL,
+	taken_at  INTEGER NOT NULL,
+	objects   INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS snapshots_collector_taken_at ON snapshots (collector, taken_at);
+
+CREATE TABLE IF NOT EXISTS objects (
+	snapshot_id INTEGER NOT NULL REFERENCES snapshots (id) ON DELETE CASCADE,
+	tenant_id   TEXT    NOT NULL,
+	object_id   TEXT    NOT NULL,
+	data        TEXT    NOT NULL,
+	PRIMARY KEY (snapshot_id, tenant_id, object_id)
+) WITHOUT ROWID;
+`
+
+// Store records a snapshot of the inventory of every collection cycle in SQLite
+type Store struct {
+	logger    *logrus.Entry
+	config    config.SQLiteConfig
+	db        *sql.DB
+	exporters map[string]collector.InventoryExporter
+
+	// Names of the collectors with a completed collection cycle
+	pending chan string
+}
+
+// NewStore opens the database and creates the schema
+func NewStore(cfg config.SQLiteConfig, collectors []collector.Collector, logger *logrus.Entry) (*Store, error) {
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)", cfg.Path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", cfg.Path, err)
+	}
+	// SQLite allows a single writer
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+
+	s := &Store{
+		logger:    logger,
+		config:    cfg,
+		db:        db,
+		exporters: map[string]collector.InventoryExporter{},
+		pending:   make(chan string, len(collectors)),
+	}
+	for _, c := range collectors {
+		if exporter, ok := c.(collector.InventoryExporter); ok {
+			s.exporters[exporter.Name()] = exporter
+		}
+	}
+
+	return s, nil
+}
+
+// CollectionCompleted queues a snapshot of the collector, it is registered
+// as collection hook and doesn't block the collection
+func (s *Store) CollectionCompleted(name string) {
+	if _, exists := s.exporters[name]; !exists {
+		return
+	}
+
+	select {
+	case s.pending <- name:
+	default:
+		s.logger.Warnf("Skipping %s snapshot, previous snapshots are still being written", name)
+	}
+}
+
+// Run writes the queued snapshots until the context is cancelled
+func (s *Store) Run(ctx context.Context) {
+	s.logger.Infof("Recording inventory snapshots in %s", s.config.Path)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case name := <-s.pending:
+			start := time.Now()
+			objects, err := s.writeSnapshot(ctx, s.exporters[name])
+			if err != nil {
+				s.logger.Errorf("Failed to record %s snapshot: %v", name, err)
+				continue
+			}
+			s.logger.Debugf("Recorded %s snapshot with %d objects in %s", name, objects, time.Since(start))
+
+			if err := s.prune(ctx); err != nil {
+				s.logger.Errorf("Failed to prune snapshots: %v", err)
+			}
+		}
+	}
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// writeSnapshot stores the current inventory of the collector and returns
+// the number of objects
+func (s *Store) writeSnapshot(ctx context.Context, exporter collector.InventoryExporter) (int, error) {
+	header, rows := exporter.Inventory()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "INSERT INTO snapshots (collector, taken_at, objects) VALUES (?, ?, 0)", exporter.Name(), time.Now().Unix())
+	if err != nil {
+		return 0, err
+	}
+	snapshotID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	insert, err := tx.PrepareContext(ctx, "INSERT OR REPLACE INTO objects (snapshot_id, tenant_id, object_id, data) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return 0, err
+	}
+	defer insert.Close()
+
+	objects := 0
+	for row := range rows {
+		data := make(map[string]string, len(header))
+		for i, column := range header {
+			data[column] = row[i]
+		}
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			return 0, err
+		}
+
+		// The first columns of every inventory are the tenant and object ID
+		if _, err := insert.ExecContext(ctx, snapshotID, row[0], row[1], string(encoded)); err != nil {
+			return 0, err
+		}
+		objects++
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE snapshots SET objects = ? WHERE id = ?", objects, snapshotID); err != nil {
+		return 0, err
+	}
+	return objects, tx.Commit()
+}
+
+// prune deletes the snapshots older than the retention
+func (s *Store) prune(ctx context.Context) error {
+	cutoff := time.Now().Add(-s.config.GetRetention()).Unix()
+	_, err := s.db.ExecContext(ctx, "DELETE FROM snapshots WHERE taken_at < ?", cutoff)
+	return err
+}
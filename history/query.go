@@ -0,0 +1,231 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Snapshot is a recorded collection cycle
+type Snapshot struct {
+	ID        int64     `json:"id"`
+	Collector string    `json:"collector"`
+	TakenAt   time.Time `json:"takenAt"`
+	Objects   int       `json:"objects"`
+}
+
+// Object is an inventory object of a snapshot
+type Object struct {
+	TenantID string            `json:"tenantId"`
+	ObjectID string            `json:"objectId"`
+	Data     map[string]string `json:"data"`
+}
+
+// ChangedObject is an object whose columns differ between two snapshots
+type ChangedObject struct {
+	TenantID string            `json:"tenantId"`
+	ObjectID string            `json:"objectId"`
+	Before   map[string]string `json:"before"`
+	After    map[string]string `json:"after"`
+}
+
+// Diff are the changes of an inventory between two snapshots
+type Diff struct {
+	From    Snapshot        `json:"from"`
+	To      Snapshot        `json:"to"`
+	Added   []Object        `json:"added"`
+	Removed []Object        `json:"removed"`
+	Changed []ChangedObject `json:"changed"`
+}
+
+// errNoSnapshot is returned when no snapshot matches a query
+var errNoSnapshot = errors.New("no snapshot found")
+
+// Handler returns the HTTP handler of the query endpoints:
+//
+//	/history/snapshots?collector=users
+//	/history/diff?collector=users&since=168h
+func (s *Store) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /history/snapshots", func(w http.ResponseWriter, r *http.Request) {
+		snapshots, err := s.snapshots(r.Context(), r.URL.Query().Get("collector"))
+		if err != nil {
+			s.logger.Errorf("Failed to query snapshots: %v", err)
+			http.Error(w, "Failed to query snapshots", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, snapshots)
+	})
+
+	mux.HandleFunc("GET /history/diff", func(w http.ResponseWriter, r *http.Request) {
+		collectorName := r.URL.Query().Get("collector")
+		if collectorName == "" {
+			http.Error(w, "collector is required", http.StatusBadRequest)
+			return
+		}
+
+		since := 7 * 24 * time.Hour
+		if value := r.URL.Query().Get("since"); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				http.Error(w, "invalid since duration", http.StatusBadRequest)
+				return
+			}
+			since = parsed
+		}
+
+		diff, err := s.diff(r.Context(), collectorName, time.Now().Add(-since))
+		if errors.Is(err, errNoSnapshot) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			s.logger.Errorf("Failed to diff %s snapshots: %v", collectorName, err)
+			http.Error(w, "Failed to diff snapshots", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, diff)
+	})
+
+	return mux
+}
+
+// snapshots returns all snapshots, optionally of a single collector
+func (s *Store) snapshots(ctx context.Context, collectorName string) ([]Snapshot, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, collector, taken_at, objects FROM snapshots
+		WHERE ? = '' OR collector = ?
+		ORDER BY taken_at DESC, id DESC`, collectorName, collectorName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := []Snapshot{}
+	for rows.Next() {
+		snapshot, err := scanSnapshot(rows)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, rows.Err()
+}
+
+// diff compares the latest snapshot of the collector with the latest one
+// taken at or before the given time, or the oldest if there is none
+func (s *Store) diff(ctx context.Context, collectorName string, since time.Time) (*Diff, error) {
+	to, err := s.snapshotQuery(ctx, "SELECT id, collector, taken_at, objects FROM snapshots WHERE collector = ? ORDER BY taken_at DESC, id DESC LIMIT 1", collectorName)
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := s.snapshotQuery(ctx, "SELECT id, collector, taken_at, objects FROM snapshots WHERE collector = ? AND taken_at <= ? ORDER BY taken_at DESC, id DESC LIMIT 1", collectorName, since.Unix())
+	if errors.Is(err, errNoSnapshot) {
+		from, err = s.snapshotQuery(ctx, "SELECT id, collector, taken_at, objects FROM snapshots WHERE collector = ? ORDER BY taken_at, id LIMIT 1", collectorName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &Diff{From: from, To: to, Added: []Object{}, Removed: []Object{}, Changed: []ChangedObject{}}
+
+	// Objects only in one of the snapshots
+	onlyIn := `
+		SELECT a.tenant_id, a.object_id, a.data FROM objects a
+		WHERE a.snapshot_id = ? AND NOT EXISTS (
+			SELECT 1 FROM objects b
+			WHERE b.snapshot_id = ? AND b.tenant_id = a.tenant_id AND b.object_id = a.object_id
+		)
+		ORDER BY a.tenant_id, a.object_id`
+	if diff.Added, err = s.objects(ctx, onlyIn, to.ID, from.ID); err != nil {
+		return nil, err
+	}
+	if diff.Removed, err = s.objects(ctx, onlyIn, from.ID, to.ID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT a.tenant_id, a.object_id, a.data, b.data FROM objects a
+		JOIN objects b ON b.snapshot_id = ? AND b.tenant_id = a.tenant_id AND b.object_id = a.object_id
+		WHERE a.snapshot_id = ? AND a.data != b.data
+		ORDER BY a.tenant_id, a.object_id`, to.ID, from.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var changed ChangedObject
+		var before, after string
+		if err := rows.Scan(&changed.TenantID, &changed.ObjectID, &before, &after); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(before), &changed.Before); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(after), &changed.After); err != nil {
+			return nil, err
+		}
+		diff.Changed = append(diff.Changed, changed)
+	}
+	return diff, rows.Err()
+}
+
+// snapshotQuery returns the single snapshot selected by the query
+func (s *Store) snapshotQuery(ctx context.Context, query string, args ...interface{}) (Snapshot, error) {
+	snapshot, err := scanSnapshot(s.db.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return snapshot, errNoSnapshot
+	}
+	return snapshot, err
+}
+
+// objects returns the objects selected by the query
+func (s *Store) objects(ctx context.Context, query string, args ...interface{}) ([]Object, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	objects := []Object{}
+	for rows.Next() {
+		var object Object
+		var data string
+		if err := rows.Scan(&object.TenantID, &object.ObjectID, &data); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(data), &object.Data); err != nil {
+			return nil, err
+		}
+		objects = append(objects, object)
+	}
+	return objects, rows.Err()
+}
+
+// rowScanner is a single row of a query result, e.g. *sql.Row or *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanSnapshot scans a snapshot row
+func scanSnapshot(row rowScanner) (Snapshot, error) {
+	var snapshot Snapshot
+	var takenAt int64
+	if err := row.Scan(&snapshot.ID, &snapshot.Collector, &takenAt, &snapshot.Objects); err != nil {
+		return snapshot, err
+	}
+	snapshot.TakenAt = time.Unix(takenAt, 0).UTC()
+	return snapshot, nil
+}
+
+// writeJSON writes the value as JSON response
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,224 @@
+package influx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/entra-exporter/config"
+)
+
+// Writer periodically writes the metrics of a Prometheus gatherer in Influx
+// line protocol to an HTTP write endpoint or a file
+type Writer struct {
+	logger   *logrus.Entry
+	config   config.InfluxConfig
+	gatherer prometheus.Gatherer
+	client   *http.Client
+}
+
+// NewWriter creates a writer for the metrics of gatherer
+func NewWriter(cfg config.InfluxConfig, gatherer prometheus.Gatherer, logger *logrus.Entry) *Writer {
+	return &Writer{
+		logger:   logger,
+		config:   cfg,
+		gatherer: gatherer,
+		client:   &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// Run writes the metrics every interval until the context is cancelled
+func (w *Writer) Run(ctx context.Context) {
+	w.logger.Infof("Writing metrics in Influx line protocol every %s", w.config.GetInterval())
+
+	ticker := time.NewTicker(w.config.GetInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.Write(ctx); err != nil {
+				w.logger.Errorf("Failed to write metrics: %v", err)
+			}
+		}
+	}
+}
+
+// Write gathers and writes the metrics once
+func (w *Writer) Write(ctx context.Context) error {
+	families, err := w.gatherer.Gather()
+	if err != nil {
+		// Gather returns everything it could collect along with the error
+		w.logger.Warnf("Failed to gather some metrics: %v", err)
+	}
+
+	var lines bytes.Buffer
+	WriteLineProtocol(&lines, families, time.Now())
+
+	if w.config.URL != "" {
+		if err := w.push(ctx, lines.Bytes()); err != nil {
+			return err
+		}
+	}
+	if w.config.File != "" {
+		if err := writeFileAtomically(w.config.File, lines.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// push posts the lines to the write endpoint
+func (w *Writer) push(ctx context.Context, lines []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.config.URL, bytes.NewReader(lines))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if w.config.Token != "" {
+		req.Header.Set("Authorization", "Token "+w.config.Token)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// writeFileAtomically replaces the file via a temporary file in the same directory
+func writeFileAtomically(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// WriteLineProtocol writes the metric families as one line per series, using
+// the metric name as measurement and the labels as tags. Fields follow the
+// Telegraf Prometheus input: "counter" or "gauge" for single values, and
+// "count", "sum" plus one field per bucket bound or quantile for histograms
+// and summaries.
+func WriteLineProtocol(w io.Writer, families []*dto.MetricFamily, now time.Time) {
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			fields := map[string]float64{}
+
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				fields["counter"] = m.GetCounter().GetValue()
+			case dto.MetricType_GAUGE:
+				fields["gauge"] = m.GetGauge().GetValue()
+			case dto.MetricType_UNTYPED:
+				fields["value"] = m.GetUntyped().GetValue()
+			case dto.MetricType_HISTOGRAM:
+				fields["count"] = float64(m.GetHistogram().GetSampleCount())
+				fields["sum"] = m.GetHistogram().GetSampleSum()
+				for _, bucket := range m.GetHistogram().GetBucket() {
+					fields[strconv.FormatFloat(bucket.GetUpperBound(), 'g', -1, 64)] = float64(bucket.GetCumulativeCount())
+				}
+			case dto.MetricType_SUMMARY:
+				fields["count"] = float64(m.GetSummary().GetSampleCount())
+				fields["sum"] = m.GetSummary().GetSampleSum()
+				for _, quantile := range m.GetSummary().GetQuantile() {
+					fields[strconv.FormatFloat(quantile.GetQuantile(), 'g', -1, 64)] = quantile.GetValue()
+				}
+			default:
+				continue
+			}
+
+			timestamp := now
+			if m.TimestampMs != nil {
+				timestamp = time.UnixMilli(m.GetTimestampMs())
+			}
+			writeLine(w, family.GetName(), m.GetLabel(), fields, timestamp)
+		}
+	}
+}
+
+// writeLine writes a single line, NaN and infinite fields can't be
+// represented and are dropped
+func writeLine(w io.Writer, measurement string, labels []*dto.LabelPair, fields map[string]float64, timestamp time.Time) {
+	names := make([]string, 0, len(fields))
+	for name, value := range fields {
+		if !math.IsNaN(value) && !math.IsInf(value, 0) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+
+	var line strings.Builder
+	line.WriteString(measurementEscaper.Replace(measurement))
+
+	// Tags are sorted by key for best write performance
+	sorted := append([]*dto.LabelPair(nil), labels...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].GetName() < sorted[j].GetName() })
+	for _, label := range sorted {
+		// Empty tag values are not allowed
+		if label.GetValue() == "" {
+			continue
+		}
+		line.WriteByte(',')
+		line.WriteString(keyEscaper.Replace(label.GetName()))
+		line.WriteByte('=')
+		line.WriteString(keyEscaper.Replace(label.GetValue()))
+	}
+
+	for i, name := range names {
+		if i == 0 {
+			line.WriteByte(' ')
+		} else {
+			line.WriteByte(',')
+		}
+		line.WriteString(keyEscaper.Replace(name))
+		line.WriteByte('=')
+		line.WriteString(strconv.FormatFloat(fields[name], 'g', -1, 64))
+	}
+
+	line.WriteByte(' ')
+	line.WriteString(strconv.FormatInt(timestamp.UnixNano(), 10))
+	line.WriteByte('\n')
+
+	io.WriteString(w, line.String())
+}
+
+var (
+	// measurementEscaper escapes measurement names
+	measurementEscaper = strings.NewReplacer(`,`, `\,`, ` `, `\ `, "\n", `\n`)
+
+	// keyEscaper escapes tag keys, tag values and field keys
+	keyEscaper = strings.NewReplacer(`,`, `\,`, `=`, `\=`, ` `, `\ `, "\n", `\n`)
+)
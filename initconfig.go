@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+
+	"github.com/your-username/entra-exporter/config"
+)
+
+// initConfigCommand writes a config with every option commented out
+type initConfigCommand struct {
+	Force bool `long:"force" description:"Replace an existing config file"`
+}
+
+var initConfigOpts initConfigCommand
+
+// runInitConfig writes the scaffolded config to the --config path
+func runInitConfig() {
+	scaffold, err := config.Scaffold()
+	if err != nil {
+		logger.Fatalf("Failed to generate config: %v", err)
+	}
+
+	flag := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if !initConfigOpts.Force {
+		flag |= os.O_EXCL
+	}
+	file, err := os.OpenFile(opts.Config, flag, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			logger.Fatalf("Config file %s already exists, pass --force to replace it", opts.Config)
+		}
+		logger.Fatalf("Failed to create config file: %v", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(scaffold); err != nil {
+		logger.Fatalf("Failed to write config file: %v", err)
+	}
+	logger.Infof("Wrote config to %s", opts.Config)
+}
@@ -0,0 +1,108 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/entra-exporter/collector"
+	"github.com/your-username/entra-exporter/config"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// namespaceFile holds the namespace of the pod's service account
+const namespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// Elector runs the Lease based leader election, the collectors are on
+// standby until this replica becomes the leader
+type Elector struct {
+	config     config.LeaderElectionConfig
+	collectors []collector.Collector
+	logger     *logrus.Entry
+	lock       *resourcelock.LeaseLock
+}
+
+// NewElector creates an Elector using the in-cluster Kubernetes configuration
+func NewElector(config config.LeaderElectionConfig, collectors []collector.Collector, logger *logrus.Entry) (*Elector, error) {
+	restConfig, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load in-cluster config: %v", err)
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %v", err)
+	}
+
+	namespace := config.Namespace
+	if namespace == "" {
+		data, err := os.ReadFile(namespaceFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read pod namespace: %v", err)
+		}
+		namespace = strings.TrimSpace(string(data))
+	}
+
+	identity := config.Identity
+	if identity == "" {
+		if identity, err = os.Hostname(); err != nil {
+			return nil, fmt.Errorf("failed to get hostname: %v", err)
+		}
+	}
+
+	return &Elector{
+		config:     config,
+		collectors: collectors,
+		logger:     logger.WithField("identity", identity),
+		lock: &resourcelock.LeaseLock{
+			LeaseMeta: metav1.ObjectMeta{
+				Name:      config.LeaseName,
+				Namespace: namespace,
+			},
+			Client:     client.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+		},
+	}, nil
+}
+
+// Run takes part in the election until the context is cancelled, the lease
+// is released on shutdown so a standby replica takes over right away
+func (e *Elector) Run(ctx context.Context) {
+	collector.SetStandby(true)
+
+	// A leader losing its lease campaigns again as standby
+	for ctx.Err() == nil {
+		leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+			Lock:            e.lock,
+			LeaseDuration:   e.config.GetLeaseDuration(),
+			RenewDeadline:   e.config.GetRenewDeadline(),
+			RetryPeriod:     e.config.GetRetryPeriod(),
+			ReleaseOnCancel: true,
+			Name:            e.config.LeaseName,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: func(ctx context.Context) {
+					e.logger.Info("Became leader, starting collections")
+					collector.SetStandby(false)
+
+					// Don't wait for the schedule, the cache may be a whole
+					// scrapeTime old or never filled on this replica
+					for _, c := range e.collectors {
+						c.Refresh()
+					}
+				},
+				OnStoppedLeading: func() {
+					e.logger.Info("Lost leadership, collections are on standby")
+					collector.SetStandby(true)
+				},
+				OnNewLeader: func(identity string) {
+					e.logger.Infof("Current leader is %s", identity)
+				},
+			},
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package loganalytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/entra-exporter/collector"
+	"github.com/your-username/entra-exporter/config"
+)
+
+const (
+	// apiVersion is the Logs Ingestion API version
+	apiVersion = "2023-01-01"
+
+	// tokenScope is the scope of the Logs Ingestion API tokens
+	tokenScope = "https://monitor.azure.com/.default"
+
+	// maxBatchSize is the maximum body size of a single upload, the API
+	// rejects requests above 1 MB
+	maxBatchSize = 900 * 1024
+)
+
+// Sink pushes the cached inventories of the collectors to Log Analytics
+// custom tables via the Logs Ingestion API
+type Sink struct {
+	logger     *logrus.Entry
+	config     config.LogAnalyticsConfig
+	exporters  []collector.InventoryExporter
+	credential azcore.TokenCredential
+	client     *http.Client
+}
+
+// NewSink creates a sink for the collectors with a configured stream
+func NewSink(cfg config.LogAnalyticsConfig, collectors []collector.Collector, logger *logrus.Entry) (*Sink, error) {
+	credential, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure credential: %w", err)
+	}
+
+	s := &Sink{
+		logger:     logger,
+		config:     cfg,
+		credential: credential,
+		client:     &http.Client{Timeout: 60 * time.Second},
+	}
+
+	for _, c := range collectors {
+		exporter, ok := c.(collector.InventoryExporter)
+		if !ok {
+			continue
+		}
+		if _, exists := cfg.Streams[exporter.Name()]; exists {
+			s.exporters = append(s.exporters, exporter)
+		}
+	}
+	if len(s.exporters) == 0 {
+		return nil, fmt.Errorf("no enabled inventory collector has a stream configured")
+	}
+
+	return s, nil
+}
+
+// Run pushes the inventory snapshots every interval until the context is cancelled
+func (s *Sink) Run(ctx context.Context) {
+	s.logger.Infof("Pushing inventory snapshots to %s every %s", s.config.Endpoint, s.config.GetInterval())
+
+	ticker := time.NewTicker(s.config.GetInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Push(ctx)
+		}
+	}
+}
+
+// Push uploads a snapshot of every inventory to its stream
+func (s *Sink) Push(ctx context.Context) {
+	snapshotTime := time.Now().UTC().Format(time.RFC3339)
+
+	for _, exporter := range s.exporters {
+		stream := s.config.Streams[exporter.Name()]
+
+		records, err := s.pushInventory(ctx, exporter, stream, snapshotTime)
+		if err != nil {
+			s.logger.Errorf("Failed to push %s inventory to stream %s: %v", exporter.Name(), stream, err)
+			continue
+		}
+		s.logger.Debugf("Pushed %d %s records to stream %s", records, exporter.Name(), stream)
+	}
+}
+
+// pushInventory uploads the inventory in batches below the request size
+// limit and returns the number of uploaded records
+func (s *Sink) pushInventory(ctx context.Context, exporter collector.InventoryExporter, stream, snapshotTime string) (int, error) {
+	header, rows := exporter.Inventory()
+
+	var batch bytes.Buffer
+	records, batchRecords := 0, 0
+
+	flush := func() error {
+		if batchRecords == 0 {
+			return nil
+		}
+		batch.WriteByte(']')
+		if err := s.upload(ctx, stream, batch.Bytes()); err != nil {
+			return err
+		}
+		records += batchRecords
+		batch.Reset()
+		batchRecords = 0
+		return nil
+	}
+
+	for row := range rows {
+		record := make(map[string]string, len(header)+1)
+		record["TimeGenerated"] = snapshotTime
+		for i, column := range header {
+			record[column] = row[i]
+		}
+
+		encoded, err := json.Marshal(record)
+		if err != nil {
+			return records, err
+		}
+
+		if batchRecords > 0 && batch.Len()+len(encoded)+2 > maxBatchSize {
+			if err := flush(); err != nil {
+				return records, err
+			}
+		}
+		if batchRecords == 0 {
+			batch.WriteByte('[')
+		} else {
+			batch.WriteByte(',')
+		}
+		batch.Write(encoded)
+		batchRecords++
+	}
+
+	if err := flush(); err != nil {
+		return records, err
+	}
+	return records, nil
+}
+
+// upload sends a JSON array of records to the stream
+func (s *Sink) upload(ctx context.Context, stream string, body []byte) error {
+	token, err := s.credential.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{tokenScope}})
+	if err != nil {
+		return fmt.Errorf("failed to get token: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("%s/dataCollectionRules/%s/streams/%s?api-version=%s",
+		strings.TrimSuffix(s.config.Endpoint, "/"),
+		url.PathEscape(s.config.RuleID),
+		url.PathEscape(stream),
+		apiVersion,
+	)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
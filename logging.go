@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/coreos/go-systemd/v22/journal"
+	"github.com/sirupsen/logrus"
+)
+
+// journaldPriorities maps the log levels to journald priorities
+var journaldPriorities = map[logrus.Level]journal.Priority{
+	logrus.PanicLevel: journal.PriEmerg,
+	logrus.FatalLevel: journal.PriCrit,
+	logrus.ErrorLevel: journal.PriErr,
+	logrus.WarnLevel:  journal.PriWarning,
+	logrus.InfoLevel:  journal.PriInfo,
+	logrus.DebugLevel: journal.PriDebug,
+	logrus.TraceLevel: journal.PriDebug,
+}
+
+// journaldHook sends log entries to journald with their fields as journal
+// fields, e.g. the collector field as COLLECTOR
+type journaldHook struct{}
+
+// Levels implements logrus.Hook
+func (h *journaldHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook
+func (h *journaldHook) Fire(entry *logrus.Entry) error {
+	vars := make(map[string]string, len(entry.Data)+1)
+	vars["SYSLOG_IDENTIFIER"] = "entra-exporter"
+	for key, value := range entry.Data {
+		if name := journaldFieldName(key); name != "" {
+			vars[name] = fmt.Sprint(value)
+		}
+	}
+	return journal.Send(entry.Message, journaldPriorities[entry.Level], vars)
+}
+
+// journaldFieldName turns a log field name into a valid journal field name,
+// upper case with underscores between words
+func journaldFieldName(key string) string {
+	var name strings.Builder
+	for i, r := range key {
+		switch {
+		case unicode.IsUpper(r) && i > 0:
+			name.WriteRune('_')
+			name.WriteRune(r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			name.WriteRune(unicode.ToUpper(r))
+		default:
+			name.WriteRune('_')
+		}
+	}
+	// Leading underscores are reserved for trusted fields
+	return strings.TrimLeft(name.String(), "_")
+}
+
+// minSecretLength is the minimum length of scrubbed secret values, shorter
+// values would replace common words
+const minSecretLength = 6
+
+// redacted replaces scrubbed secrets in log entries
+const redacted = "[REDACTED]"
+
+// secretEnvVars are the environment variables holding Azure credentials
+var secretEnvVars = []string{"AZURE_CLIENT_SECRET", "AZURE_CLIENT_CERTIFICATE_PASSWORD", "AZURE_PASSWORD"}
+
+// secretPatterns match secrets whose values aren't known in advance: bearer
+// tokens, JWTs like Graph access tokens and client secrets in form bodies
+var secretPatterns = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`(?i)\b(bearer\s+)[A-Za-z0-9\-._~+/]+=*`), "${1}" + redacted},
+	{regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`), redacted},
+	{regexp.MustCompile(`(?i)\b(client_secret|client_assertion|access_token|refresh_token)=[^&\s"]+`), "${1}=" + redacted},
+}
+
+// scrubHook replaces known secret values and token patterns in the message
+// and fields of all log entries. It must be added before the hooks sending
+// entries elsewhere, hooks run in the order they were added.
+type scrubHook struct {
+	secretsLock sync.RWMutex
+	secrets     []string
+}
+
+// AddSecrets adds values to be scrubbed, empty and short values are ignored
+func (h *scrubHook) AddSecrets(secrets ...string) {
+	h.secretsLock.Lock()
+	defer h.secretsLock.Unlock()
+
+	for _, secret := range secrets {
+		if len(secret) >= minSecretLength {
+			h.secrets = append(h.secrets, secret)
+		}
+	}
+}
+
+// Levels implements logrus.Hook
+func (h *scrubHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook
+func (h *scrubHook) Fire(entry *logrus.Entry) error {
+	entry.Message = h.scrub(entry.Message)
+
+	// Errors and other values are formatted, they may wrap request dumps
+	for key, value := range entry.Data {
+		var text string
+		switch v := value.(type) {
+		case string:
+			text = v
+		case error, fmt.Stringer:
+			text = fmt.Sprint(v)
+		default:
+			continue
+		}
+		if scrubbed := h.scrub(text); scrubbed != text {
+			entry.Data[key] = scrubbed
+		}
+	}
+	return nil
+}
+
+// scrub replaces the secrets in the text
+func (h *scrubHook) scrub(text string) string {
+	h.secretsLock.RLock()
+	for _, secret := range h.secrets {
+		text = strings.ReplaceAll(text, secret, redacted)
+	}
+	h.secretsLock.RUnlock()
+
+	for _, secret := range secretPatterns {
+		text = secret.pattern.ReplaceAllString(text, secret.replacement)
+	}
+	return text
+}
@@ -2,20 +2,41 @@ package main
 
 import (
 	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	stdlog "log"
+	"log/syslog"
+	"math"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/coreos/go-systemd/v22/journal"
 	"github.com/jessevdk/go-flags"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
+	logrussyslog "github.com/sirupsen/logrus/hooks/syslog"
 	"github.com/your-username/entra-exporter/config"
+	"github.com/your-username/entra-exporter/archive"
+	"github.com/your-username/entra-exporter/changes"
 	"github.com/your-username/entra-exporter/collector"
+	"github.com/your-username/entra-exporter/events"
+	"github.com/your-username/entra-exporter/grpcapi"
+	"github.com/your-username/entra-exporter/history"
+	"github.com/your-username/entra-exporter/influx"
+	"github.com/your-username/entra-exporter/leader"
+	"github.com/your-username/entra-exporter/loganalytics"
+	"github.com/your-username/entra-exporter/notify"
+	"github.com/your-username/entra-exporter/otlp"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 const (
@@ -27,18 +48,40 @@ var (
 	argparser *flags.Parser
 	opts struct {
 		Config         string `short:"c" long:"config" description:"Path to config file" default:"config.yml"`
+		LogOutput      string `long:"log.output" description:"Log output target, syslog and journald keep the structured fields" choice:"stderr" choice:"syslog" choice:"journald" default:"stderr"`
 		LogFile        string `short:"l" long:"log.file" description:"Log output file"`
+		LogMaxSize     int    `long:"log.max-size" description:"Rotate the log file when it reaches this size in megabytes (default: never)"`
+		LogMaxAge      int    `long:"log.max-age" description:"Delete rotated log files older than this many days (default: keep)"`
+		LogMaxBackups  int    `long:"log.max-backups" description:"Number of rotated log files to keep (default: all)"`
+		LogRotate      time.Duration `long:"log.rotate-interval" description:"Also rotate the log file at this interval, e.g. 24h (default: never)"`
+		LogCompress    bool   `long:"log.compress" description:"Gzip rotated log files"`
 		LogFormat      string `short:"f" long:"log.format" description:"Log format" choice:"text" choice:"json" default:"text"`
 		LogLevel       string `short:"v" long:"log.level" description:"Log level" choice:"debug" choice:"info" choice:"warn" choice:"error" default:"info"`
 		LogDebug       bool   `long:"log.debug" description:"Enable debug logging"`
 		ListenAddress  string `short:"a" long:"web.listen-address" description:"Address to listen on for web interface and telemetry" default:":8080"`
+		GRPCAddress    string `long:"grpc.listen-address" description:"Address to listen on for the gRPC inventory query API (default: disabled)"`
+		Once           bool   `long:"once" description:"Collect once, write the metrics in exposition format and exit"`
+		OnceOutput     string `long:"once.output" description:"File the metrics are written to with --once (default: stdout)"`
+		TextfileDir    string `long:"textfile.directory" description:"Collect once and atomically write the metrics to a .prom file in this directory for the node_exporter textfile collector"`
+		TextfileName   string `long:"textfile.name" description:"Name of the .prom file written to --textfile.directory" default:"entra_exporter.prom"`
+		TenantInclude  string `long:"azure.tenant-include" description:"Only scrape tenants whose ID fully matches this regex, overrides azure.tenantInclude"`
+		TenantExclude  string `long:"azure.tenant-exclude" description:"Don't scrape tenants whose ID fully matches this regex, overrides azure.tenantExclude"`
 	}
 	logger = logrus.New()
+
+	// Scrubs secrets from all log entries, the config's are added once it's loaded
+	secretScrubber = &scrubHook{}
 )
 
 func main() {
 	initArgparser()
 
+	// Runs before loading the config, which it creates
+	if argparser.Active != nil && argparser.Active.Name == "init-config" {
+		runInitConfig()
+		return
+	}
+
 	logger.Infof("Starting Entra ID exporter v%s", Version)
 
 	// Check for required environment variables for Azure authentication
@@ -66,59 +109,136 @@ func main() {
 			logger.Fatalf("Failed to load config file: %v", err)
 		}
 	}
+	secretScrubber.AddSecrets(cfg.Secrets()...)
+	if err := cfg.SetTenantFilters(opts.TenantInclude, opts.TenantExclude); err != nil {
+		logger.Fatalf("Invalid tenant filter: %v", err)
+	}
+	collector.SetUserAgent(cfg.GetGraphUserAgent(Version))
+
+	if cfg.ErrorReporting.Sentry.IsEnabled() {
+		reporter, err := collector.NewSentryReporter(cfg.ErrorReporting.Sentry, Version)
+		if err != nil {
+			logger.Fatalf("Failed to set up error reporting: %v", err)
+		}
+		collector.SetErrorReporter(reporter)
+		defer reporter.Flush(5 * time.Second)
+		logger.Info("Reporting collector panics and persistent auth failures to Sentry")
+	}
+
+	if argparser.Active != nil && argparser.Active.Name == "bench" {
+		runBench(cfg)
+		return
+	}
+
+	if argparser.Active != nil && argparser.Active.Name == "collectors" {
+		runCollectors(cfg)
+		return
+	}
+
+	if argparser.Active != nil && argparser.Active.Name == "check-permissions" {
+		runCheckPermissions(cfg)
+		return
+	}
+
+	if opts.Once || opts.TextfileDir != "" {
+		runOnce(cfg)
+		return
+	}
 
 	registry := prometheus.NewRegistry()
 
+	// Background collections run until this context is cancelled on shutdown
+	collectCtx, stopCollectors := context.WithCancel(context.Background())
+	defer stopCollectors()
+
 	// Set up collectors
-	if cfg.Collector.General.IsEnabled() {
-		generalCollector := collector.NewGeneralCollector(cfg, logger.WithField("collector", "general"))
-		registry.MustRegister(generalCollector)
-		logger.Info("Enabled collector: general")
+	registry.MustRegister(collector.NewExporterCollector(cfg, logger.WithField("collector", "exporter")))
+	collectors := setupCollectors(collectCtx, cfg)
+	for _, c := range collectors {
+		registry.MustRegister(c)
 	}
 
-	if cfg.Collector.Users.IsEnabled() {
-		usersCollector := collector.NewUsersCollector(cfg, logger.WithField("collector", "users"))
-		registry.MustRegister(usersCollector)
-		logger.Info("Enabled collector: users")
+	// Only the elected leader of the replicas collects from Graph
+	if cfg.LeaderElection.IsEnabled() {
+		elector, err := leader.NewElector(cfg.LeaderElection, collectors, logger.WithField("component", "leader"))
+		if err != nil {
+			logger.Fatalf("Failed to set up leader election: %v", err)
+		}
+		go elector.Run(collectCtx)
 	}
 
-	if cfg.Collector.Devices.IsEnabled() {
-		devicesCollector := collector.NewDevicesCollector(cfg, logger.WithField("collector", "devices"))
-		registry.MustRegister(devicesCollector)
-		logger.Info("Enabled collector: devices")
+	// Push the metrics via OTLP in parallel to /metrics
+	var otlpExporter *otlp.Exporter
+	if cfg.Metrics.OTLP.IsEnabled() {
+		var err error
+		otlpExporter, err = otlp.NewExporter(collectCtx, cfg.Metrics.OTLP, registry, Version, logger.WithField("component", "otlp"))
+		if err != nil {
+			logger.Fatalf("Failed to set up OTLP export: %v", err)
+		}
+		go otlpExporter.Run(collectCtx)
 	}
 
-	/* Not yet implemented - will be added in future versions
-	if cfg.Collector.Applications.IsEnabled() {
-		applicationsCollector := collector.NewApplicationsCollector(cfg, logger.WithField("collector", "applications"))
-		registry.MustRegister(applicationsCollector)
-		logger.Info("Enabled collector: applications")
+	// Write the metrics in Influx line protocol
+	if cfg.Metrics.Influx.IsEnabled() {
+		go influx.NewWriter(cfg.Metrics.Influx, registry, logger.WithField("component", "influx")).Run(collectCtx)
 	}
 
-	if cfg.Collector.ServicePrincipals.IsEnabled() {
-		spCollector := collector.NewServicePrincipalsCollector(cfg, logger.WithField("collector", "servicePrincipals"))
-		registry.MustRegister(spCollector)
-		logger.Info("Enabled collector: servicePrincipals")
+	// Notify webhooks about conditions on the collected metrics
+	if cfg.Notifications.IsEnabled() {
+		go notify.NewEngine(cfg.Notifications, registry, logger.WithField("component", "notify")).Run(collectCtx)
 	}
 
-	if cfg.Collector.Groups.IsEnabled() {
-		groupsCollector := collector.NewGroupsCollector(cfg, logger.WithField("collector", "groups"))
-		registry.MustRegister(groupsCollector)
-		logger.Info("Enabled collector: groups")
+	// Push inventory snapshots to Log Analytics
+	if cfg.Sinks.LogAnalytics.IsEnabled() {
+		sink, err := loganalytics.NewSink(cfg.Sinks.LogAnalytics, collectors, logger.WithField("sink", "logAnalytics"))
+		if err != nil {
+			logger.Fatalf("Failed to set up Log Analytics sink: %v", err)
+		}
+		go sink.Run(collectCtx)
 	}
 
-	if cfg.Collector.ConditionalAccessPolicies.IsEnabled() {
-		capCollector := collector.NewConditionalAccessPoliciesCollector(cfg, logger.WithField("collector", "conditionalAccessPolicies"))
-		registry.MustRegister(capCollector)
-		logger.Info("Enabled collector: conditionalAccessPolicies")
+	// Record inventory snapshots for historical queries
+	if cfg.Sinks.SQLite.IsEnabled() {
+		store, err := history.NewStore(cfg.Sinks.SQLite, collectors, logger.WithField("sink", "sqlite"))
+		if err != nil {
+			logger.Fatalf("Failed to set up SQLite store: %v", err)
+		}
+		defer store.Close()
+
+		collector.AddCollectionHook(store.CollectionCompleted)
+		go store.Run(collectCtx)
+		http.Handle("/history/", store.Handler())
 	}
 
-	if cfg.Collector.DirectoryRoles.IsEnabled() {
-		rolesCollector := collector.NewDirectoryRolesCollector(cfg, logger.WithField("collector", "directoryRoles"))
-		registry.MustRegister(rolesCollector)
-		logger.Info("Enabled collector: directoryRoles")
+	// Publish directory changes between collection cycles
+	if cfg.Sinks.Events.IsEnabled() {
+		stream, err := events.NewStream(cfg.Sinks.Events, collectors, logger.WithField("sink", "events"))
+		if err != nil {
+			logger.Fatalf("Failed to set up change events: %v", err)
+		}
+		collector.AddCollectionHook(stream.CollectionCompleted)
+		go stream.Run(collectCtx)
+	}
+
+	// Upload inventory snapshots to object storage
+	if cfg.Sinks.Archive.IsEnabled() {
+		snapshotArchive, err := archive.NewArchive(collectCtx, cfg.Sinks.Archive, collectors, logger.WithField("sink", "archive"))
+		if err != nil {
+			logger.Fatalf("Failed to set up snapshot archive: %v", err)
+		}
+		collector.AddCollectionHook(snapshotArchive.CollectionCompleted)
+		go snapshotArchive.Run(collectCtx)
+	}
+
+	// Refresh collectors on Graph change notifications
+	if cfg.Graph.ChangeNotifications.IsEnabled() {
+		changeManager, err := changes.NewManager(cfg.Graph.ChangeNotifications, collectors, logger.WithField("component", "changes"))
+		if err != nil {
+			logger.Fatalf("Failed to set up change notifications: %v", err)
+		}
+		http.Handle(changeManager.Path(), changeManager)
+		go changeManager.Run(collectCtx)
 	}
-	*/
 
 	// Create HTTP server and metrics handler
 	handler := promhttp.HandlerFor(
@@ -149,6 +269,8 @@ func main() {
 		<body>
 		<h1>Entra ID Exporter</h1>
 		<p><a href="/metrics">Metrics</a></p>
+		<p><a href="/debug/collectors">Collector status</a></p>
+		<p><a href="/debug/tenants">Tenant report</a></p>
 		</body>
 		</html>
 		`))
@@ -158,6 +280,111 @@ func main() {
 		w.Write([]byte("OK"))
 	})
 	
+	// Runtime status of all collectors, including their last errors
+	http.HandleFunc("/debug/collectors", func(w http.ResponseWriter, r *http.Request) {
+		statuses := make([]collector.CollectorStatus, 0, len(collectors))
+		for _, c := range collectors {
+			statuses = append(statuses, c.Status())
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(statuses); err != nil {
+			logger.Errorf("Failed to encode collector status: %v", err)
+		}
+	})
+
+	// Suspend and resume the background collections, cached metrics are still
+	// served. Only available with a debug token, anyone reaching the port
+	// could stop the collections otherwise.
+	if cfg.Web.DebugToken != "" {
+		http.HandleFunc("POST /-/pause", func(w http.ResponseWriter, r *http.Request) {
+			collector.SetPaused(true)
+			logger.Warn("Collections paused via /-/pause")
+			w.Write([]byte("Paused"))
+		})
+		http.HandleFunc("POST /-/resume", func(w http.ResponseWriter, r *http.Request) {
+			collector.SetPaused(false)
+			logger.Info("Collections resumed via /-/resume")
+			w.Write([]byte("Resumed"))
+		})
+	} else {
+		logger.Warnf("web.debugToken is not set: %s are served without authentication and /-/pause is disabled", strings.Join(protectedPaths, ", "))
+	}
+
+	// Per tenant summary of the collections and Graph usage
+	http.HandleFunc("/debug/tenants", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(collector.TenantReports()); err != nil {
+			logger.Errorf("Failed to encode tenant report: %v", err)
+		}
+	})
+
+	// Point in time snapshots of the cached inventories
+	http.HandleFunc("/export/{file}", func(w http.ResponseWriter, r *http.Request) {
+		name, ok := strings.CutSuffix(r.PathValue("file"), ".csv")
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		for _, c := range collectors {
+			exporter, ok := c.(collector.InventoryExporter)
+			if !ok || exporter.Name() != name {
+				continue
+			}
+
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("%s-%s.csv", name, time.Now().UTC().Format("20060102T150405Z"))))
+			if err := collector.WriteInventoryCSV(w, exporter); err != nil {
+				logger.Errorf("Failed to export %s inventory: %v", name, err)
+			}
+			return
+		}
+		http.NotFound(w, r)
+	})
+
+	// Cached inventories as JSON, NDJSON or CSV depending on the Accept header,
+	// query parameters filter by column, e.g. ?accountEnabled=false
+	http.HandleFunc("/inventory/{collector}", func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("collector")
+
+		var exporter collector.InventoryExporter
+		for _, c := range collectors {
+			if e, ok := c.(collector.InventoryExporter); ok && e.Name() == name {
+				exporter = e
+			}
+		}
+		if exporter == nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		filters := map[string]string{}
+		for key, values := range r.URL.Query() {
+			filters[key] = values[0]
+		}
+
+		format, contentType := collector.InventoryJSON, "application/json"
+		accept := r.Header.Get("Accept")
+		switch {
+		case strings.Contains(accept, "text/csv"):
+			format, contentType = collector.InventoryCSV, "text/csv; charset=utf-8"
+		case strings.Contains(accept, "ndjson"):
+			format, contentType = collector.InventoryNDJSON, "application/x-ndjson"
+		}
+
+		// Invalid filters are reported before anything is written
+		w.Header().Set("Content-Type", contentType)
+		if err := collector.WriteInventory(w, exporter, format, filters); err != nil {
+			var filterErr *collector.InvalidFilterError
+			if errors.As(err, &filterErr) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			logger.Errorf("Failed to write %s inventory: %v", name, err)
+		}
+	})
+
 	// Add a debug endpoint to check environment variables
 	if logger.Level == logrus.DebugLevel {
 		http.HandleFunc("/debug/env", func(w http.ResponseWriter, r *http.Request) {
@@ -171,7 +398,7 @@ func main() {
 	// Set up graceful shutdown
 	server := &http.Server{
 		Addr:    opts.ListenAddress,
-		Handler: http.DefaultServeMux,
+		Handler: protectEndpoints(cfg.Web.DebugToken, http.DefaultServeMux),
 	}
 
 	// Make a channel to listen for OS signals for graceful shutdown
@@ -186,8 +413,41 @@ func main() {
 		}
 	}()
 
+	// Serve lookups of cached objects to sidecar services
+	var grpcServer *grpcapi.Server
+	if opts.GRPCAddress != "" {
+		grpcServer = grpcapi.NewServer(collectors, logger.WithField("component", "grpc"))
+		go func() {
+			logger.Infof("Starting gRPC server on %s", opts.GRPCAddress)
+			if err := grpcServer.ListenAndServe(opts.GRPCAddress); err != nil {
+				logger.Errorf("Error starting gRPC server: %v", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
+	// Dump the internal state to the log on SIGUSR1
+	dump := make(chan os.Signal, 1)
+	signal.Notify(dump, syscall.SIGUSR1)
+	go func() {
+		for range dump {
+			collector.DumpState(logger.WithField("component", "state"))
+		}
+	}()
+
 	// Block until we receive a termination signal
 	<-done
+	logger.Info("Stopping collectors...")
+	stopCollectors()
+
+	// Cancelled collections return after their current Graph request, keep
+	// serving the cached results meanwhile
+	waitCtx, cancelWait := context.WithTimeout(context.Background(), cfg.GetShutdownTimeout())
+	if !collector.WaitCollections(waitCtx) {
+		logger.Warnf("Collections still running after %s, shutting down anyway", cfg.GetShutdownTimeout())
+	}
+	cancelWait()
+
 	logger.Info("Shutting down server...")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -197,9 +457,168 @@ func main() {
 		logger.Errorf("Server shutdown failed: %v", err)
 	}
 
+	if grpcServer != nil {
+		grpcServer.Stop()
+	}
+
+	if otlpExporter != nil {
+		if err := otlpExporter.Shutdown(ctx); err != nil {
+			logger.Errorf("OTLP exporter shutdown failed: %v", err)
+		}
+	}
+
 	logger.Info("Server gracefully stopped")
 }
 
+// setupCollectors creates all collectors enabled in the config
+func setupCollectors(ctx context.Context, cfg *config.Config) []collector.Collector {
+	var collectors []collector.Collector
+
+	if cfg.Collector.General.IsEnabled() {
+		generalCollector := collector.NewGeneralCollector(ctx, cfg, logger.WithField("collector", "general"))
+		collectors = append(collectors, generalCollector)
+		logger.Info("Enabled collector: general")
+	}
+
+	if cfg.Collector.Users.IsEnabled() {
+		usersCollector := collector.NewUsersCollector(ctx, cfg, logger.WithField("collector", "users"))
+		collectors = append(collectors, usersCollector)
+		logger.Info("Enabled collector: users")
+	}
+
+	if cfg.Collector.Devices.IsEnabled() {
+		devicesCollector := collector.NewDevicesCollector(ctx, cfg, logger.WithField("collector", "devices"))
+		collectors = append(collectors, devicesCollector)
+		logger.Info("Enabled collector: devices")
+	}
+
+	if cfg.Collector.PIM.IsEnabled() {
+		pimCollector := collector.NewPIMCollector(ctx, cfg, logger.WithField("collector", "pim"))
+		collectors = append(collectors, pimCollector)
+		logger.Info("Enabled collector: pim")
+	}
+
+	if cfg.Collector.AuthMethods.IsEnabled() {
+		authMethodsCollector := collector.NewAuthMethodsCollector(ctx, cfg, logger.WithField("collector", "authMethods"))
+		collectors = append(collectors, authMethodsCollector)
+		logger.Info("Enabled collector: authMethods")
+	}
+
+	if cfg.Collector.SignIns.IsEnabled() {
+		signInsCollector := collector.NewSignInsCollector(ctx, cfg, logger.WithField("collector", "signIns"))
+		collectors = append(collectors, signInsCollector)
+		logger.Info("Enabled collector: signIns")
+	}
+
+	if cfg.Collector.Autopilot.IsEnabled() {
+		autopilotCollector := collector.NewAutopilotCollector(ctx, cfg, logger.WithField("collector", "autopilot"))
+		collectors = append(collectors, autopilotCollector)
+		logger.Info("Enabled collector: autopilot")
+	}
+
+	if cfg.Collector.Consents.IsEnabled() {
+		consentsCollector := collector.NewConsentsCollector(ctx, cfg, logger.WithField("collector", "consents"))
+		collectors = append(collectors, consentsCollector)
+		logger.Info("Enabled collector: consents")
+	}
+
+	if cfg.Collector.AdministrativeUnits.IsEnabled() {
+		administrativeUnitsCollector := collector.NewAdministrativeUnitsCollector(ctx, cfg, logger.WithField("collector", "administrativeUnits"))
+		collectors = append(collectors, administrativeUnitsCollector)
+		logger.Info("Enabled collector: administrativeUnits")
+	}
+
+	if cfg.Collector.TenantSettings.IsEnabled() {
+		tenantSettingsCollector := collector.NewTenantSettingsCollector(ctx, cfg, logger.WithField("collector", "tenantSettings"))
+		collectors = append(collectors, tenantSettingsCollector)
+		logger.Info("Enabled collector: tenantSettings")
+	}
+
+	if cfg.Collector.Domains.IsEnabled() {
+		domainsCollector := collector.NewDomainsCollector(ctx, cfg, logger.WithField("collector", "domains"))
+		collectors = append(collectors, domainsCollector)
+		logger.Info("Enabled collector: domains")
+	}
+
+	if cfg.Collector.HybridSync.IsEnabled() {
+		hybridSyncCollector := collector.NewHybridSyncCollector(ctx, cfg, logger.WithField("collector", "hybridSync"))
+		collectors = append(collectors, hybridSyncCollector)
+		logger.Info("Enabled collector: hybridSync")
+	}
+
+	if cfg.Collector.DeletedItems.IsEnabled() {
+		deletedItemsCollector := collector.NewDeletedItemsCollector(ctx, cfg, logger.WithField("collector", "deletedItems"))
+		collectors = append(collectors, deletedItemsCollector)
+		logger.Info("Enabled collector: deletedItems")
+	}
+
+	if cfg.Collector.SecurityAlerts.IsEnabled() {
+		securityAlertsCollector := collector.NewSecurityAlertsCollector(ctx, cfg, logger.WithField("collector", "securityAlerts"))
+		collectors = append(collectors, securityAlertsCollector)
+		logger.Info("Enabled collector: securityAlerts")
+	}
+
+	if cfg.Collector.AccessPackages.IsEnabled() {
+		accessPackagesCollector := collector.NewAccessPackagesCollector(ctx, cfg, logger.WithField("collector", "accessPackages"))
+		collectors = append(collectors, accessPackagesCollector)
+		logger.Info("Enabled collector: accessPackages")
+	}
+
+	if cfg.Collector.TermsOfUse.IsEnabled() {
+		termsOfUseCollector := collector.NewTermsOfUseCollector(ctx, cfg, logger.WithField("collector", "termsOfUse"))
+		collectors = append(collectors, termsOfUseCollector)
+		logger.Info("Enabled collector: termsOfUse")
+	}
+
+	if cfg.Collector.Custom.IsEnabled() {
+		customCollector := collector.NewCustomCollector(ctx, cfg, logger.WithField("collector", "custom"))
+		collectors = append(collectors, customCollector)
+		logger.Info("Enabled collector: custom")
+	}
+
+	for _, plugin := range cfg.Plugins {
+		if plugin.IsEnabled() {
+			pluginCollector := collector.NewPluginCollector(ctx, plugin, cfg, logger.WithField("collector", "plugin_"+plugin.Name))
+			collectors = append(collectors, pluginCollector)
+			logger.Infof("Enabled plugin: %s", plugin.Name)
+		}
+	}
+
+	/* Not yet implemented - will be added in future versions
+	if cfg.Collector.Applications.IsEnabled() {
+		applicationsCollector := collector.NewApplicationsCollector(ctx, cfg, logger.WithField("collector", "applications"))
+		collectors = append(collectors, applicationsCollector)
+		logger.Info("Enabled collector: applications")
+	}
+
+	if cfg.Collector.ServicePrincipals.IsEnabled() {
+		spCollector := collector.NewServicePrincipalsCollector(ctx, cfg, logger.WithField("collector", "servicePrincipals"))
+		collectors = append(collectors, spCollector)
+		logger.Info("Enabled collector: servicePrincipals")
+	}
+
+	if cfg.Collector.Groups.IsEnabled() {
+		groupsCollector := collector.NewGroupsCollector(ctx, cfg, logger.WithField("collector", "groups"))
+		collectors = append(collectors, groupsCollector)
+		logger.Info("Enabled collector: groups")
+	}
+
+	if cfg.Collector.ConditionalAccessPolicies.IsEnabled() {
+		capCollector := collector.NewConditionalAccessPoliciesCollector(ctx, cfg, logger.WithField("collector", "conditionalAccessPolicies"))
+		collectors = append(collectors, capCollector)
+		logger.Info("Enabled collector: conditionalAccessPolicies")
+	}
+
+	if cfg.Collector.DirectoryRoles.IsEnabled() {
+		rolesCollector := collector.NewDirectoryRolesCollector(ctx, cfg, logger.WithField("collector", "directoryRoles"))
+		collectors = append(collectors, rolesCollector)
+		logger.Info("Enabled collector: directoryRoles")
+	}
+	*/
+
+	return collectors
+}
+
 func initArgparser() {
 	// Parse environment variables
 	if os.Getenv("LOG_DEBUG") == "true" {
@@ -208,6 +627,24 @@ func initArgparser() {
 
 	// Parse command line arguments
 	argparser = flags.NewParser(&opts, flags.Default)
+	argparser.SubcommandsOptional = true
+	if _, err := argparser.AddCommand("bench", "Benchmark collectors", "Runs each enabled collector once against the configured tenants and reports duration, pages fetched, objects and peak memory", &benchCommand{}); err != nil {
+		fmt.Printf("Error adding bench command: %s\n", err)
+		os.Exit(1)
+	}
+	if _, err := argparser.AddCommand("collectors", "List collectors", "Lists all known collectors, whether the config enables them, their scrape time and the Graph permissions they need", &collectorsCommand{}); err != nil {
+		fmt.Printf("Error adding collectors command: %s\n", err)
+		os.Exit(1)
+	}
+	if _, err := argparser.AddCommand("check-permissions", "Check Graph permissions", "Authenticates against every configured tenant, checks the token roles and probes Graph for each permission the enabled collectors need", &checkPermissionsCommand{}); err != nil {
+		fmt.Printf("Error adding check-permissions command: %s\n", err)
+		os.Exit(1)
+	}
+	if _, err := argparser.AddCommand("init-config", "Write an example config", "Writes a config file with every option commented out and documented to the --config path", &initConfigOpts); err != nil {
+		fmt.Printf("Error adding init-config command: %s\n", err)
+		os.Exit(1)
+	}
+
 	if _, err := argparser.Parse(); err != nil {
 		if flagsErr, ok := err.(*flags.Error); ok && flagsErr.Type == flags.ErrHelp {
 			os.Exit(0)
@@ -243,15 +680,84 @@ func initArgparser() {
 		})
 	}
 
-	// Set log output
-	if opts.LogFile != "" {
-		file, err := os.OpenFile(opts.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	// Added first so no other hook sees the secrets
+	for _, name := range secretEnvVars {
+		secretScrubber.AddSecrets(os.Getenv(name))
+	}
+	logger.AddHook(secretScrubber)
+
+	// Send to the local syslog daemon or journald instead of stderr
+	switch opts.LogOutput {
+	case "syslog":
+		hook, err := logrussyslog.NewSyslogHook("", "", syslog.LOG_INFO|syslog.LOG_DAEMON, "entra-exporter")
 		if err != nil {
-			fmt.Printf("Error opening log file: %s\n", err)
+			fmt.Printf("Error connecting to syslog: %s\n", err)
 			os.Exit(1)
 		}
+		logger.AddHook(hook)
+		logger.SetOutput(io.Discard)
+	case "journald":
+		if !journal.Enabled() {
+			fmt.Println("Error connecting to journald: journal socket not available")
+			os.Exit(1)
+		}
+		logger.AddHook(&journaldHook{})
+		logger.SetOutput(io.Discard)
+	}
+
+	// Set log output, rotated files are named after the log file plus the rotation time
+	if opts.LogFile != "" {
+		file := &lumberjack.Logger{
+			Filename:   opts.LogFile,
+			MaxSize:    opts.LogMaxSize,
+			MaxAge:     opts.LogMaxAge,
+			MaxBackups: opts.LogMaxBackups,
+			Compress:   opts.LogCompress,
+		}
+		// Without a size lumberjack would rotate at 100 MB
+		if opts.LogMaxSize <= 0 {
+			file.MaxSize = math.MaxInt32
+		}
 		logger.SetOutput(file)
+
+		if opts.LogRotate > 0 {
+			go func() {
+				for range time.Tick(opts.LogRotate) {
+					if err := file.Rotate(); err != nil {
+						fmt.Fprintf(os.Stderr, "Error rotating log file: %s\n", err)
+					}
+				}
+			}()
+		}
+	}
+}
+
+// protectedPaths are the prefixes of the endpoints requiring the debug token,
+// they expose deployment details, change the exporter's state or serve the
+// cached inventories with user principal names and device names
+var protectedPaths = []string{"/debug/", "/-/", "/inventory/", "/export/", "/history/"}
+
+// protectEndpoints requires the debug token as bearer token for the
+// protectedPaths. Without a token all requests pass.
+func protectEndpoints(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
 	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		protected := slices.ContainsFunc(protectedPaths, func(prefix string) bool {
+			return strings.HasPrefix(r.URL.Path, prefix)
+		})
+		if protected {
+			provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="entra-exporter"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
 }
 
 // maskValue masks part of a string value for logging
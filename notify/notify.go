@@ -0,0 +1,272 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/entra-exporter/config"
+)
+
+// Alert is a series matching a rule
+type Alert struct {
+	Rule      string            `json:"rule"`
+	Metric    string            `json:"metric"`
+	Labels    map[string]string `json:"labels"`
+	Value     float64           `json:"value"`
+	Condition string            `json:"condition"`
+	Threshold float64           `json:"threshold"`
+	Time      time.Time         `json:"time"`
+}
+
+// String returns a human readable description of the alert
+func (a Alert) String() string {
+	names := make([]string, 0, len(a.Labels))
+	for name := range a.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	labels := make([]string, 0, len(names))
+	for _, name := range names {
+		labels = append(labels, fmt.Sprintf("%s=%q", name, a.Labels[name]))
+	}
+	series := fmt.Sprintf("%s{%s}", a.Metric, strings.Join(labels, ","))
+
+	if a.Condition == config.ConditionNew {
+		return fmt.Sprintf("[%s] new series %s", a.Rule, series)
+	}
+	return fmt.Sprintf("[%s] %s = %g (%s %g)", a.Rule, series, a.Value, a.Condition, a.Threshold)
+}
+
+// Engine evaluates the notification rules on the gathered metrics and sends
+// webhooks when a series starts matching a rule
+type Engine struct {
+	logger   *logrus.Entry
+	config   config.NotificationsConfig
+	gatherer prometheus.Gatherer
+	client   *http.Client
+
+	// Series matching each rule and when they were last notified
+	firing map[string]map[string]time.Time
+	// Series seen by each "new" rule, nil until the first evaluation
+	seen map[string]map[string]bool
+}
+
+// NewEngine creates a rules engine evaluating the metrics of gatherer
+func NewEngine(cfg config.NotificationsConfig, gatherer prometheus.Gatherer, logger *logrus.Entry) *Engine {
+	return &Engine{
+		logger:   logger,
+		config:   cfg,
+		gatherer: gatherer,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		firing:   map[string]map[string]time.Time{},
+		seen:     map[string]map[string]bool{},
+	}
+}
+
+// Run evaluates the rules every interval until the context is cancelled
+func (e *Engine) Run(ctx context.Context) {
+	e.logger.Infof("Evaluating %d notification rules every %s", len(e.config.Rules), e.config.GetInterval())
+
+	ticker := time.NewTicker(e.config.GetInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.Evaluate(ctx)
+		}
+	}
+}
+
+// Evaluate gathers the metrics once and notifies about new alerts
+func (e *Engine) Evaluate(ctx context.Context) {
+	families, err := e.gatherer.Gather()
+	if err != nil {
+		// Gather returns everything it could collect along with the error
+		e.logger.Warnf("Failed to gather some metrics: %v", err)
+	}
+
+	byName := make(map[string]*dto.MetricFamily, len(families))
+	for _, family := range families {
+		byName[family.GetName()] = family
+	}
+
+	now := time.Now()
+	for _, rule := range e.config.Rules {
+		for _, alert := range e.evaluateRule(rule, byName[rule.Metric], now) {
+			e.logger.Infof("Notification: %s", alert)
+			for _, webhook := range e.config.WebhooksOf(rule) {
+				if err := e.send(ctx, webhook, alert); err != nil {
+					e.logger.Errorf("Failed to send notification for rule %s to webhook %s: %v", rule.Name, webhook.Name, err)
+				}
+			}
+		}
+	}
+}
+
+// evaluateRule returns the alerts of series which started matching the rule
+// or whose repeat interval passed
+func (e *Engine) evaluateRule(rule config.NotificationRule, family *dto.MetricFamily, now time.Time) []Alert {
+	var alerts []Alert
+
+	matching := map[string]bool{}
+	if family != nil {
+		for _, m := range family.GetMetric() {
+			labels := metricLabels(m)
+			if !matchLabels(rule.Labels, labels) {
+				continue
+			}
+
+			value, ok := metricValue(family.GetType(), m)
+			if !ok || !rule.Matches(value) {
+				continue
+			}
+
+			key := seriesKey(labels)
+			matching[key] = true
+			alert := Alert{
+				Rule:      rule.Name,
+				Metric:    rule.Metric,
+				Labels:    labels,
+				Value:     value,
+				Condition: rule.Condition,
+				Threshold: rule.Value,
+				Time:      now,
+			}
+
+			if rule.Condition == config.ConditionNew {
+				if e.seen[rule.Name] != nil && !e.seen[rule.Name][key] {
+					alerts = append(alerts, alert)
+				}
+				continue
+			}
+
+			if e.firing[rule.Name] == nil {
+				e.firing[rule.Name] = map[string]time.Time{}
+			}
+			lastNotified, firing := e.firing[rule.Name][key]
+			if !firing || (rule.RepeatInterval > 0 && now.Sub(lastNotified) >= rule.RepeatInterval) {
+				alerts = append(alerts, alert)
+				e.firing[rule.Name][key] = now
+			}
+		}
+	}
+
+	// The baseline is taken once the metric exists, collectors may not have
+	// finished their first collection at the first evaluation
+	if rule.Condition == config.ConditionNew && family != nil {
+		if e.seen[rule.Name] == nil {
+			e.seen[rule.Name] = map[string]bool{}
+		}
+		for key := range matching {
+			e.seen[rule.Name][key] = true
+		}
+	}
+
+	// Resolved series notify again when they match the next time
+	for key := range e.firing[rule.Name] {
+		if !matching[key] {
+			delete(e.firing[rule.Name], key)
+		}
+	}
+
+	return alerts
+}
+
+// send posts the alert to the webhook in the payload format of its type
+func (e *Engine) send(ctx context.Context, webhook config.Webhook, alert Alert) error {
+	var payload interface{}
+	switch webhook.Type {
+	case config.WebhookSlack, config.WebhookTeams:
+		payload = map[string]string{"text": alert.String()}
+	default:
+		payload = alert
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// metricLabels returns the labels of a metric as map
+func metricLabels(m *dto.Metric) map[string]string {
+	labels := make(map[string]string, len(m.GetLabel()))
+	for _, label := range m.GetLabel() {
+		labels[label.GetName()] = label.GetValue()
+	}
+	return labels
+}
+
+// matchLabels returns if all expected labels have the expected value
+func matchLabels(expected, labels map[string]string) bool {
+	for name, value := range expected {
+		if labels[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// metricValue returns the value of gauges, counters and untyped metrics
+func metricValue(metricType dto.MetricType, m *dto.Metric) (float64, bool) {
+	switch metricType {
+	case dto.MetricType_GAUGE:
+		return m.GetGauge().GetValue(), true
+	case dto.MetricType_COUNTER:
+		return m.GetCounter().GetValue(), true
+	case dto.MetricType_UNTYPED:
+		return m.GetUntyped().GetValue(), true
+	default:
+		return 0, false
+	}
+}
+
+// seriesKey identifies a series by its sorted labels
+func seriesKey(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var key strings.Builder
+	for _, name := range names {
+		key.WriteString(name)
+		key.WriteByte('=')
+		key.WriteString(labels[name])
+		key.WriteByte(0)
+	}
+	return key.String()
+}
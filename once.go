@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	"github.com/your-username/entra-exporter/collector"
+	"github.com/your-username/entra-exporter/config"
+)
+
+// runOnce runs each enabled collector once and writes the resulting metrics
+// in exposition format to stdout, the file set by --once.output or the
+// textfile collector directory set by --textfile.directory. It exits with
+// status 1 after writing the metrics when a collector failed for a tenant
+func runOnce(cfg *config.Config) {
+	output := opts.OnceOutput
+	if opts.TextfileDir != "" {
+		// The textfile collector only reads files ending in .prom
+		if !strings.HasSuffix(opts.TextfileName, ".prom") {
+			logger.Fatalf("Textfile name %q must end in .prom", opts.TextfileName)
+		}
+		output = filepath.Join(opts.TextfileDir, opts.TextfileName)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg.Oneshot = true
+	collectors := setupCollectors(ctx, cfg)
+	if len(collectors) == 0 {
+		logger.Fatal("No collectors enabled in config")
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector.NewExporterCollector(cfg, logger.WithField("collector", "exporter")))
+	for _, c := range collectors {
+		registry.MustRegister(c)
+	}
+
+	var failed []string
+	for _, c := range collectors {
+		logger.Infof("Running collector %s", c.Name())
+		c.RunOnce()
+
+		if ctx.Err() != nil {
+			logger.Fatalf("Collection interrupted: %v", ctx.Err())
+		}
+
+		// Every collector runs once, so any recorded error is from this run
+		for _, tenant := range c.Status().Tenants {
+			if tenant.LastError != nil {
+				logger.Errorf("Collector %s failed for tenant %s: %s", c.Name(), tenant.TenantID, tenant.LastError.Message)
+				if !slices.Contains(failed, c.Name()) {
+					failed = append(failed, c.Name())
+				}
+			}
+		}
+	}
+
+	// The metrics of the successful collectors are written anyway
+	defer func() {
+		if len(failed) > 0 {
+			logger.Errorf("Failed collectors: %s", strings.Join(failed, ", "))
+			os.Exit(1)
+		}
+	}()
+
+	if output != "" {
+		// Written to a temporary file and renamed, so readers never see partial output
+		if err := prometheus.WriteToTextfile(output, registry); err != nil {
+			logger.Fatalf("Failed to write metrics to %s: %v", output, err)
+		}
+		logger.Infof("Wrote metrics to %s", output)
+		return
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		logger.Errorf("Failed to gather some metrics: %v", err)
+	}
+
+	encoder := expfmt.NewEncoder(os.Stdout, expfmt.NewFormat(expfmt.TypeTextPlain))
+	for _, family := range families {
+		if err := encoder.Encode(family); err != nil {
+			logger.Fatalf("Failed to write metrics: %v", err)
+		}
+	}
+}
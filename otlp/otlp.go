@@ -0,0 +1,222 @@
+package otlp
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/entra-exporter/config"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// scopeName is the instrumentation scope of the exported metrics
+const scopeName = "github.com/your-username/entra-exporter"
+
+// Exporter periodically pushes the metrics of a Prometheus gatherer to an
+// OTLP endpoint
+type Exporter struct {
+	logger   *logrus.Entry
+	config   config.OTLPConfig
+	gatherer prometheus.Gatherer
+	exporter *otlpmetrichttp.Exporter
+	resource *resource.Resource
+	started  time.Time
+}
+
+// NewExporter creates an exporter pushing the metrics of gatherer to the configured endpoint
+func NewExporter(ctx context.Context, cfg config.OTLPConfig, gatherer prometheus.Gatherer, version string, logger *logrus.Entry) (*Exporter, error) {
+	options := []otlpmetrichttp.Option{
+		otlpmetrichttp.WithEndpointURL(cfg.Endpoint),
+	}
+	if len(cfg.Headers) > 0 {
+		options = append(options, otlpmetrichttp.WithHeaders(cfg.Headers))
+	}
+
+	exporter, err := otlpmetrichttp.New(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	return &Exporter{
+		logger:   logger,
+		config:   cfg,
+		gatherer: gatherer,
+		exporter: exporter,
+		resource: resource.NewSchemaless(
+			attribute.String("service.name", "entra-exporter"),
+			attribute.String("service.version", version),
+		),
+		started: time.Now(),
+	}, nil
+}
+
+// Run pushes the metrics every interval until the context is cancelled
+func (e *Exporter) Run(ctx context.Context) {
+	e.logger.Infof("Exporting metrics to %s every %s", e.config.Endpoint, e.config.GetInterval())
+
+	ticker := time.NewTicker(e.config.GetInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := e.Export(ctx); err != nil {
+				e.logger.Errorf("Failed to export metrics: %v", err)
+			}
+		}
+	}
+}
+
+// Export gathers and pushes the metrics once
+func (e *Exporter) Export(ctx context.Context) error {
+	families, err := e.gatherer.Gather()
+	if err != nil {
+		// Gather returns everything it could collect along with the error
+		e.logger.Warnf("Failed to gather some metrics: %v", err)
+	}
+
+	metrics := metricdata.ResourceMetrics{
+		Resource: e.resource,
+		ScopeMetrics: []metricdata.ScopeMetrics{{
+			Scope:   instrumentation.Scope{Name: scopeName},
+			Metrics: convertFamilies(families, e.started, time.Now()),
+		}},
+	}
+	return e.exporter.Export(ctx, &metrics)
+}
+
+// Shutdown flushes and closes the exporter
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	return e.exporter.Shutdown(ctx)
+}
+
+// convertFamilies converts Prometheus metric families into OTLP metrics,
+// cumulative values start at the exporter start time
+func convertFamilies(families []*dto.MetricFamily, start, now time.Time) []metricdata.Metrics {
+	result := make([]metricdata.Metrics, 0, len(families))
+
+	for _, family := range families {
+		metric := metricdata.Metrics{
+			Name:        family.GetName(),
+			Description: family.GetHelp(),
+		}
+
+		switch family.GetType() {
+		case dto.MetricType_COUNTER:
+			sum := metricdata.Sum[float64]{
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+			}
+			for _, m := range family.GetMetric() {
+				sum.DataPoints = append(sum.DataPoints, metricdata.DataPoint[float64]{
+					Attributes: attributes(m),
+					StartTime:  start,
+					Time:       now,
+					Value:      m.GetCounter().GetValue(),
+				})
+			}
+			metric.Data = sum
+
+		case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+			gauge := metricdata.Gauge[float64]{}
+			for _, m := range family.GetMetric() {
+				value := m.GetGauge().GetValue()
+				if family.GetType() == dto.MetricType_UNTYPED {
+					value = m.GetUntyped().GetValue()
+				}
+				gauge.DataPoints = append(gauge.DataPoints, metricdata.DataPoint[float64]{
+					Attributes: attributes(m),
+					Time:       now,
+					Value:      value,
+				})
+			}
+			metric.Data = gauge
+
+		case dto.MetricType_HISTOGRAM:
+			histogram := metricdata.Histogram[float64]{
+				Temporality: metricdata.CumulativeTemporality,
+			}
+			for _, m := range family.GetMetric() {
+				histogram.DataPoints = append(histogram.DataPoints, histogramDataPoint(m, start, now))
+			}
+			metric.Data = histogram
+
+		case dto.MetricType_SUMMARY:
+			summary := metricdata.Summary{}
+			for _, m := range family.GetMetric() {
+				point := metricdata.SummaryDataPoint{
+					Attributes: attributes(m),
+					StartTime:  start,
+					Time:       now,
+					Count:      m.GetSummary().GetSampleCount(),
+					Sum:        m.GetSummary().GetSampleSum(),
+				}
+				for _, quantile := range m.GetSummary().GetQuantile() {
+					// Quantiles of summaries without observations are NaN
+					if math.IsNaN(quantile.GetValue()) {
+						continue
+					}
+					point.QuantileValues = append(point.QuantileValues, metricdata.QuantileValue{
+						Quantile: quantile.GetQuantile(),
+						Value:    quantile.GetValue(),
+					})
+				}
+				summary.DataPoints = append(summary.DataPoints, point)
+			}
+			metric.Data = summary
+
+		default:
+			continue
+		}
+
+		result = append(result, metric)
+	}
+
+	return result
+}
+
+// histogramDataPoint converts a Prometheus histogram, whose buckets are
+// cumulative, into an OTLP data point with a count per bucket
+func histogramDataPoint(m *dto.Metric, start, now time.Time) metricdata.HistogramDataPoint[float64] {
+	histogram := m.GetHistogram()
+	point := metricdata.HistogramDataPoint[float64]{
+		Attributes: attributes(m),
+		StartTime:  start,
+		Time:       now,
+		Count:      histogram.GetSampleCount(),
+		Sum:        histogram.GetSampleSum(),
+	}
+
+	var previous uint64
+	for _, bucket := range histogram.GetBucket() {
+		// The +Inf bucket is implied in OTLP
+		if math.IsInf(bucket.GetUpperBound(), 1) {
+			continue
+		}
+		point.Bounds = append(point.Bounds, bucket.GetUpperBound())
+		point.BucketCounts = append(point.BucketCounts, bucket.GetCumulativeCount()-previous)
+		previous = bucket.GetCumulativeCount()
+	}
+	point.BucketCounts = append(point.BucketCounts, histogram.GetSampleCount()-previous)
+
+	return point
+}
+
+// attributes converts the labels of a Prometheus metric into OTLP attributes
+func attributes(m *dto.Metric) attribute.Set {
+	kvs := make([]attribute.KeyValue, 0, len(m.GetLabel()))
+	for _, label := range m.GetLabel() {
+		kvs = append(kvs, attribute.String(label.GetName(), label.GetValue()))
+	}
+	return attribute.NewSet(kvs...)
+}